@@ -0,0 +1,463 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/sethvargo/go-envconfig"
+
+	"github.com/glizzus/trf/internal/auth"
+	"github.com/glizzus/trf/internal/domain"
+	"github.com/glizzus/trf/internal/ingest"
+	"github.com/glizzus/trf/internal/logging"
+	"github.com/glizzus/trf/internal/repo"
+	"github.com/glizzus/trf/internal/schedule"
+	"github.com/glizzus/trf/internal/scraping"
+	"github.com/glizzus/trf/internal/spoofing"
+	"github.com/glizzus/trf/internal/tracing"
+	"github.com/glizzus/trf/migrations"
+)
+
+// PostgresConfig configures the PostgreSQL database. Host, User, Password and DB are required when it is used.
+type PostgresConfig struct {
+	Host     string         `env:"HOST"`
+	Port     int            `env:"PORT,default=5432"`
+	User     string         `env:"USER"`
+	Password logging.Secret `env:"PASSWORD"`
+	DB       string         `env:"DB"`
+}
+
+func (c *PostgresConfig) DSN() string {
+	return fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=disable", c.User, string(c.Password), c.Host, c.Port, c.DB)
+}
+
+// SQLiteConfig configures the SQLite database.
+type SQLiteConfig struct {
+	// Path is the database file. It is created, with the schema, if it doesn't exist.
+	Path string `env:"PATH,default=ministry.db"`
+}
+
+type SpooferConfig struct {
+	Type string `env:"TYPE"`
+
+	OpenAIKey logging.Secret `env:"OPENAI_KEY"`
+}
+
+// ScraperConfig configures how we scrape Snopes.
+type ScraperConfig struct {
+	// ArchiveDir is where raw pages are archived. Archiving is disabled if it is empty.
+	ArchiveDir string `env:"ARCHIVE_DIR"`
+
+	// SelectorsFile overrides the selectors compiled into the binary. It is reloaded when it changes.
+	SelectorsFile           string        `env:"SELECTORS_FILE"`
+	SelectorsReloadInterval time.Duration `env:"SELECTORS_RELOAD_INTERVAL,default=10s"`
+
+	Timeout         time.Duration  `env:"TIMEOUT,default=20s"`
+	MaxRetries      int            `env:"MAX_RETRIES,default=3"`
+	RetryDelay      time.Duration  `env:"RETRY_DELAY,default=1s"`
+	UserAgent       string         `env:"USER_AGENT,default=TotallyRealFacts/1.0 (+https://github.com/glizzus/trf)"`
+	Proxy           logging.Secret `env:"PROXY"`
+	PolitenessDelay time.Duration  `env:"POLITENESS_DELAY,default=2s"`
+	IgnoreRobots    bool           `env:"IGNORE_ROBOTS,default=false"`
+
+	// We alert when at least AlertThreshold of the last AlertWindow extractions of a field failed.
+	AlertWindow    int     `env:"ALERT_WINDOW,default=20"`
+	AlertThreshold float64 `env:"ALERT_THRESHOLD,default=0.5"`
+}
+
+// IngestConfig configures each ingest run. A zero timeout means no deadline.
+type IngestConfig struct {
+	RunTimeout    time.Duration `env:"RUN_TIMEOUT,default=30m"`
+	ScrapeTimeout time.Duration `env:"SCRAPE_TIMEOUT,default=30s"`
+	SpoofTimeout  time.Duration `env:"SPOOF_TIMEOUT,default=3m"`
+	RepoTimeout   time.Duration `env:"REPO_TIMEOUT,default=10s"`
+
+	RecheckWindow         time.Duration `env:"RECHECK_WINDOW,default=168h"`
+	RecheckLimit          int           `env:"RECHECK_LIMIT,default=10"`
+	RespoofOnRatingChange bool          `env:"RESPOOF_ON_RATING_CHANGE,default=false"`
+
+	// Inversion names the strategy that chooses the rating of each spoof.
+	Inversion string `env:"INVERSION,default=table"`
+	// InversionByKind overrides Inversion for ratings of a kind, like "origin:absurd,status:keep-neutral".
+	InversionByKind map[string]string `env:"INVERSION_BY_KIND"`
+
+	// AutoPublish skips review, and publishes spoofs as soon as they are made.
+	AutoPublish bool `env:"AUTO_PUBLISH,default=false"`
+
+	// MaxQueuedJobs is the most articles requested from /admin or the API that can wait to be spoofed.
+	MaxQueuedJobs int `env:"MAX_QUEUED_JOBS,default=20"`
+}
+
+// ScheduleConfig configures when the scheduled jobs run. Schedules are cron expressions like "0 * * * *",
+// or descriptors like "@hourly" or "@every 30m". An empty schedule disables the job.
+type ScheduleConfig struct {
+	// Ingest scrapes the latest fact checks, and spoofs the new ones.
+	Ingest string `env:"INGEST,default=@hourly"`
+	// IngestOnStart also ingests as soon as we start, instead of waiting for the first scheduled time.
+	IngestOnStart bool `env:"INGEST_ON_START,default=true"`
+	// Recheck rescrapes recent articles to pick up revisions.
+	Recheck string `env:"RECHECK,default=30 * * * *"`
+	// Cleanup deletes expired sessions and old jobs.
+	Cleanup string `env:"CLEANUP,default=@daily"`
+
+	// Jitter delays each run by a random duration up to this long.
+	Jitter time.Duration `env:"JITTER,default=2m"`
+
+	// LeaderElection makes only one of the instances that share the database run the jobs.
+	LeaderElection bool `env:"LEADER_ELECTION,default=true"`
+	// LeaderCheckInterval is how often the leader checks that it still leads, and the others try to take over.
+	LeaderCheckInterval time.Duration `env:"LEADER_CHECK_INTERVAL,default=15s"`
+}
+
+// AuthConfig configures how users sign in to the admin area.
+type AuthConfig struct {
+	// SessionTTL is how long users stay signed in.
+	SessionTTL time.Duration `env:"SESSION_TTL,default=12h"`
+	// SecureCookies only sends the session cookie over HTTPS. Turn it off to sign in over plain HTTP.
+	SecureCookies bool `env:"SECURE_COOKIES,default=true"`
+}
+
+// HealthConfig configures the readiness checks behind /readyz.
+type HealthConfig struct {
+	// CheckTimeout is how long each check may take before it counts as failed.
+	CheckTimeout time.Duration `env:"CHECK_TIMEOUT,default=2s"`
+	// SpooferCheckInterval is how often the spoofer is checked. Checking OpenAI on every probe would be wasteful.
+	SpooferCheckInterval time.Duration `env:"SPOOFER_CHECK_INTERVAL,default=1m"`
+	// MaxIngestAge is how long ago the last successful ingest may have been. Zero disables the check.
+	MaxIngestAge time.Duration `env:"MAX_INGEST_AGE,default=3h"`
+}
+
+// HealthcheckConfig configures the healthcheck command.
+type HealthcheckConfig struct {
+	// Addr is the base URL of the server to check.
+	Addr string `env:"ADDR,default=http://localhost:80"`
+	// Probe is "livez" or "readyz".
+	Probe   string        `env:"PROBE,default=livez"`
+	Timeout time.Duration `env:"TIMEOUT,default=4s"`
+}
+
+// TracingConfig configures where spans are sent.
+type TracingConfig struct {
+	// Exporter is "none", "otlp" for a collector, or "stdout".
+	Exporter string `env:"EXPORTER,default=none"`
+	// Endpoint is the host and port of the collector. If it is empty, the OTEL_EXPORTER_OTLP_* variables apply.
+	Endpoint    string  `env:"ENDPOINT"`
+	Insecure    bool    `env:"INSECURE,default=false"`
+	SampleRatio float64 `env:"SAMPLE_RATIO,default=1"`
+}
+
+// LogConfig configures how we log.
+type LogConfig struct {
+	// Level is "debug", "info", "warn" or "error".
+	Level string `env:"LEVEL,default=info"`
+	// Format is "text" or "json".
+	Format string `env:"FORMAT,default=text"`
+}
+
+// Config is the configuration of the server. It can be logged whole, because secrets are redacted.
+type Config struct {
+	Log      LogConfig      `env:", prefix=LOG_"`
+	Spoofer  SpooferConfig  `env:", prefix=SPOOFER_"`
+	Postgres PostgresConfig `env:", prefix=POSTGRES_"`
+	SQLite   SQLiteConfig   `env:", prefix=SQLITE_"`
+	Scraper  ScraperConfig  `env:", prefix=SCRAPER_"`
+	Ingest   IngestConfig   `env:", prefix=INGEST_"`
+	Tracing  TracingConfig  `env:", prefix=TRACING_"`
+	Health   HealthConfig   `env:", prefix=HEALTH_"`
+	Auth     AuthConfig     `env:", prefix=AUTH_"`
+	Schedule ScheduleConfig `env:", prefix=SCHEDULE_"`
+
+	// Database is "postgres", or "sqlite" to keep everything in a file and run with no database server.
+	Database string `env:"DATABASE,default=postgres"`
+
+	// RatingsFile adds ratings, aliases and opposites to the ones compiled into the binary.
+	RatingsFile string `env:"RATINGS_FILE"`
+
+	// MetricsAddr is where /metrics is served. Metrics are disabled if it is empty.
+	MetricsAddr string `env:"METRICS_ADDR,default=:9090"`
+
+	// ShutdownTimeout is how long we wait for in-flight requests and the article
+	// being ingested to finish after a signal. Keep it below Docker's stop timeout.
+	ShutdownTimeout time.Duration `env:"SHUTDOWN_TIMEOUT,default=25s"`
+}
+
+// processEnv fills target from the environment variables starting with prefix.
+func processEnv(prefix string, target any) error {
+	return envconfig.ProcessWith(context.Background(), &envconfig.Config{
+		Lookuper: envconfig.PrefixLookuper(prefix, envconfig.OsLookuper()),
+		Target:   target,
+	})
+}
+
+func getConfig() Config {
+	var cfg Config
+	if err := processEnv("MINISTRY_", &cfg); err != nil {
+		fatal("failed to process config", "error", err)
+	}
+	return cfg
+}
+
+// getLogConfig is separate from getConfig, because logging is set up before any command runs,
+// and some commands don't need the rest of the config.
+func getLogConfig() LogConfig {
+	var cfg LogConfig
+	if err := processEnv("MINISTRY_LOG_", &cfg); err != nil {
+		fatal("failed to process log config", "error", err)
+	}
+	return cfg
+}
+
+// getHealthcheckConfig is separate from getConfig, so that the healthcheck command
+// doesn't need the settings of the server it is checking.
+func getHealthcheckConfig() HealthcheckConfig {
+	var cfg HealthcheckConfig
+	if err := processEnv("MINISTRY_HEALTHCHECK_", &cfg); err != nil {
+		fatal("failed to process healthcheck config", "error", err)
+	}
+	return cfg
+}
+
+// setupTracing sends spans where cfg says, and returns a function that flushes them on exit.
+func setupTracing(ctx context.Context, cfg *TracingConfig) func() {
+	shutdown, err := tracing.Setup(ctx, tracing.Options{
+		Exporter:    cfg.Exporter,
+		Endpoint:    cfg.Endpoint,
+		Insecure:    cfg.Insecure,
+		SampleRatio: cfg.SampleRatio,
+		ServiceName: "ministry",
+	})
+	if err != nil {
+		fatal("failed to set up tracing", "error", err)
+	}
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdown(ctx); err != nil {
+			slog.Error("failed to flush spans", "error", err)
+		}
+	}
+}
+
+func getSpoofer(cfg *SpooferConfig) spoofing.Spoofer {
+	switch cfg.Type {
+	case "openai":
+		if cfg.OpenAIKey == "" {
+			fatal("missing OpenAI key")
+		}
+		return spoofing.WithTracing(spoofing.NewOpenAI(string(cfg.OpenAIKey)))
+	case "mock":
+		return spoofing.WithTracing(&spoofing.MockSpoofer{})
+	default:
+		fatal("unknown spoofer type", "type", cfg.Type)
+		return nil // unreachable
+	}
+}
+
+func getAuth(cfg *AuthConfig, users repo.UserRepo) *auth.Service {
+	return auth.NewService(users, auth.Options{SessionTTL: cfg.SessionTTL})
+}
+
+// loadRatings makes the rating map file named by cfg the one every rating uses.
+// Without a file, the defaults are used.
+func loadRatings(cfg *Config) {
+	if cfg.RatingsFile == "" {
+		return
+	}
+	data, err := os.ReadFile(cfg.RatingsFile)
+	if err != nil {
+		fatal("failed to read ratings file", "error", err)
+	}
+	ratings, err := domain.ParseRatingMap(data)
+	if err != nil {
+		fatal("invalid ratings file", "file", cfg.RatingsFile, "error", err)
+	}
+	domain.SetRatingMap(ratings)
+}
+
+// getWorker returns an ingest worker configured by cfg.
+func getWorker(cfg *IngestConfig, scraper scraping.Scraper, repo repo.Repo, spoofer spoofing.Spoofer) *ingest.Worker {
+	inversion, inversionByKind := getInversions(cfg)
+	return ingest.New(scraper, repo, spoofer, ingest.Options{
+		RunTimeout:    cfg.RunTimeout,
+		ScrapeTimeout: cfg.ScrapeTimeout,
+		SpoofTimeout:  cfg.SpoofTimeout,
+		RepoTimeout:   cfg.RepoTimeout,
+
+		RecheckWindow:         cfg.RecheckWindow,
+		RecheckLimit:          cfg.RecheckLimit,
+		RespoofOnRatingChange: cfg.RespoofOnRatingChange,
+
+		Inversion:       inversion,
+		InversionByKind: inversionByKind,
+
+		AutoPublish:   cfg.AutoPublish,
+		MaxQueuedJobs: cfg.MaxQueuedJobs,
+	})
+}
+
+// getScheduler returns a scheduler that runs the jobs configured by cfg while leader says we lead.
+// If leader is nil, the jobs always run.
+func getScheduler(cfg *ScheduleConfig, leader schedule.Leader, worker *ingest.Worker, authService *auth.Service) *schedule.Scheduler {
+	scheduler := schedule.New(leader)
+	add := func(name, spec string, runOnStart bool, run func(context.Context) error) {
+		if spec == "" {
+			slog.Info("scheduled job is disabled", "job", name)
+			return
+		}
+		s, err := schedule.Parse(spec)
+		if err != nil {
+			fatal("invalid schedule", "job", name, "error", err)
+		}
+		scheduler.Add(schedule.Job{
+			Name:       name,
+			Schedule:   s,
+			Jitter:     cfg.Jitter,
+			RunOnStart: runOnStart,
+			Run:        run,
+		})
+	}
+	add("ingest", cfg.Ingest, cfg.IngestOnStart, worker.Ingest)
+	add("recheck", cfg.Recheck, false, worker.Recheck)
+	add("cleanup", cfg.Cleanup, false, func(ctx context.Context) error {
+		return errors.Join(authService.DeleteExpiredSessions(ctx), worker.DeleteOldJobs(ctx))
+	})
+	return scheduler
+}
+
+// getInversions returns the inversion for every spoof, and the ones that override it by rating kind.
+func getInversions(cfg *IngestConfig) (domain.Inversion, map[domain.RatingKind]domain.Inversion) {
+	inversion, err := domain.ParseInversion(cfg.Inversion)
+	if err != nil {
+		fatal("invalid inversion", "error", err)
+	}
+
+	byKind := make(map[domain.RatingKind]domain.Inversion, len(cfg.InversionByKind))
+	for name, inversionName := range cfg.InversionByKind {
+		kind, err := domain.ParseRatingKind(name)
+		if err != nil {
+			fatal("invalid inversion by kind", "error", err)
+		}
+		inversion, err := domain.ParseInversion(inversionName)
+		if err != nil {
+			fatal("invalid inversion", "kind", kind, "error", err)
+		}
+		byKind[kind] = inversion
+	}
+	return inversion, byKind
+}
+
+// getScraper creates the scraper described by cfg, which finds fields with the given selectors.
+func getScraper(cfg *ScraperConfig, selectors *scraping.SelectorStore) *scraping.GoqueryScraper {
+	return scraping.NewGoquery(scraping.GoqueryOptions{
+		Client:  getScraperClient(cfg),
+		Archive: getArchive(cfg),
+		Health: scraping.NewHealth(scraping.HealthOptions{
+			Window:    cfg.AlertWindow,
+			Threshold: cfg.AlertThreshold,
+		}),
+		Selectors: selectors,
+	})
+}
+
+// getSelectors loads the selectors file named by cfg, or the defaults if there is none.
+func getSelectors(cfg *ScraperConfig) *scraping.SelectorStore {
+	store, err := scraping.LoadSelectors(cfg.SelectorsFile)
+	if err != nil {
+		fatal("failed to load selectors", "error", err)
+	}
+	return store
+}
+
+func getScraperClient(cfg *ScraperConfig) *scraping.Client {
+	client, err := scraping.NewClient(scraping.ClientOptions{
+		Timeout:         cfg.Timeout,
+		MaxRetries:      cfg.MaxRetries,
+		RetryDelay:      cfg.RetryDelay,
+		UserAgent:       cfg.UserAgent,
+		Proxy:           string(cfg.Proxy),
+		PolitenessDelay: cfg.PolitenessDelay,
+		IgnoreRobots:    cfg.IgnoreRobots,
+	})
+	if err != nil {
+		fatal("failed to create scraper client", "error", err)
+	}
+	return client
+}
+
+// getArchive returns the archive described by cfg, or nil if archiving is disabled.
+// We return the interface so that a nil archive is a nil interface.
+func getArchive(cfg *ScraperConfig) scraping.Archive {
+	if cfg.ArchiveDir == "" {
+		return nil
+	}
+	archive, err := scraping.NewDirArchive(cfg.ArchiveDir)
+	if err != nil {
+		fatal("failed to open archive", "error", err)
+	}
+	return archive
+}
+
+// database is what the commands need from the database, whichever one cfg.Database chooses.
+type database interface {
+	repo.Repo
+	repo.UserRepo
+	repo.Locker
+	Ping(ctx context.Context) error
+	MigrationVersion(ctx context.Context) (version uint, dirty bool, err error)
+}
+
+// openRepo opens the database chosen by cfg, and returns it with the schema version the code expects
+// and a function that closes it. A SQLite database is migrated to that version as it is opened.
+func openRepo(ctx context.Context, cfg *Config) (database, uint, func()) {
+	switch cfg.Database {
+	case "postgres":
+		db := openDB(ctx, &cfg.Postgres)
+		return repo.NewPostgres(db), migrations.Latest(), func() { db.Close() }
+	case "sqlite":
+		db, err := repo.OpenSQLite(cfg.SQLite.Path)
+		if err != nil {
+			fatal("failed to open database", "error", err)
+		}
+		sqlite := repo.NewSQLite(db)
+		if err := sqlite.Migrate(ctx, migrations.SQLite(), migrations.Version); err != nil {
+			fatal("failed to migrate database", "path", cfg.SQLite.Path, "error", err)
+		}
+		return sqlite, migrations.LatestSQLite(), func() { db.Close() }
+	default:
+		fatal("unknown database", "database", cfg.Database)
+		return nil, 0, nil // unreachable
+	}
+}
+
+// openDB opens the PostgreSQL database described by cfg, and waits for it to be reachable.
+func openDB(ctx context.Context, cfg *PostgresConfig) *sql.DB {
+	if cfg.Host == "" || cfg.User == "" || cfg.Password == "" || cfg.DB == "" {
+		fatal("missing PostgreSQL config, set MINISTRY_POSTGRES_HOST, _USER, _PASSWORD and _DB")
+	}
+
+	db, err := sql.Open("postgres", cfg.DSN())
+	if err != nil {
+		fatal("failed to open database", "error", err)
+	}
+
+	tries := 0
+	for {
+		if err := db.PingContext(ctx); err != nil {
+			tries++
+			if tries > 5 || ctx.Err() != nil {
+				fatal("failed to ping database", "error", err)
+			}
+			slog.Warn("failed to ping database", "error", err)
+			time.Sleep(5 * time.Second)
+			continue
+		}
+		break
+	}
+
+	return db
+}
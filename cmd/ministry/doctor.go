@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/glizzus/trf/internal/scraping"
+)
+
+// scrapeDoctor scrapes a single Snopes page, and prints what each selector found.
+// This is the first thing to run when Snopes changes its markup and the scraper starts failing.
+//
+// The page is fetched fresh, and nothing is archived or saved.
+func scrapeDoctor(args []string) {
+	if len(args) != 1 {
+		fatal("usage: ministry scrape-doctor <url or slug>")
+	}
+	target := args[0]
+	if !strings.Contains(target, "://") {
+		target = "https://www.snopes.com/fact-check/" + target
+	}
+
+	cfg := getConfig()
+	loadRatings(&cfg)
+	client := getScraperClient(&cfg.Scraper)
+	sel := getSelectors(&cfg.Scraper).Get()
+
+	ctx := context.Background()
+	res, err := client.Get(ctx, target, nil)
+	if err != nil {
+		fatal("failed to fetch page", "url", target, "error", err)
+	}
+	defer res.Body.Close()
+
+	var result any
+	var extractions []scraping.Extraction
+	if slug, ok := scraping.SlugFromURL(target); ok {
+		result, extractions, err = scraping.DiagnoseArticle(ctx, res.Body, slug, sel)
+	} else {
+		result, extractions, err = scraping.DiagnoseLatestFactChecks(ctx, res.Body, sel)
+	}
+
+	printExtractions(os.Stdout, extractions)
+
+	fmt.Println()
+	out, _ := json.MarshalIndent(result, "", "  ")
+	fmt.Println(string(out))
+
+	healthy := true
+	for _, e := range extractions {
+		if !e.Matched && !e.Optional {
+			healthy = false
+		}
+	}
+	if err != nil {
+		fmt.Printf("\nerrors:\n%v\n", err)
+		healthy = false
+	}
+	if !healthy {
+		os.Exit(1)
+	}
+}
+
+func printExtractions(w io.Writer, extractions []scraping.Extraction) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	defer tw.Flush()
+
+	fmt.Fprintln(tw, "FIELD\tSELECTOR\tRESULT\tVALUE")
+	for _, e := range extractions {
+		result := "ok"
+		switch {
+		case e.Fallback:
+			result = "ok (fallback)"
+		case !e.Matched && e.Optional:
+			result = "missing (optional)"
+		case !e.Matched:
+			result = "MISSED"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", e.Field, e.Selector, result, e.Value)
+	}
+}
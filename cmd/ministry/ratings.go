@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+)
+
+// ratings prints every rating our articles have, what we map it to, and how many articles have it.
+// Ratings with no mapping are listed first, so that they can be added to the ratings file.
+func ratings() {
+	cfg := getConfig()
+	loadRatings(&cfg)
+
+	ctx := context.Background()
+	db, _, closeDB := openRepo(ctx, &cfg)
+	defer closeDB()
+
+	counts, err := db.GetRatingCounts(ctx)
+	if err != nil {
+		fatal("failed to count ratings", "error", err)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "RATING\tCATEGORY\tOPPOSITE\tARTICLES\tLATEST")
+
+	var unmapped int
+	for _, mapped := range []bool{false, true} {
+		for _, count := range counts {
+			if count.Rating.Known() != mapped {
+				continue
+			}
+
+			category := count.Rating.Category().String()
+			if !mapped {
+				unmapped++
+				category += " (fallback)"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s (%s)\n",
+				count.Rating,
+				category,
+				count.Rating.Opposite(),
+				count.Articles,
+				count.LatestSlug,
+				count.LatestDate.Format("2006-01-02"),
+			)
+		}
+	}
+	tw.Flush()
+
+	if unmapped > 0 {
+		fmt.Printf("\n%d ratings have no mapping. Add them to MINISTRY_RATINGS_FILE as aliases or with an opposite.\n", unmapped)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/glizzus/trf/internal/repo"
+	"github.com/glizzus/trf/internal/scraping"
+)
+
+// reparse rebuilds the articles table from the archived pages.
+// This is useful after fixing a bug in the scraper, because it doesn't touch the network.
+// Spoofs are left alone.
+func reparse() {
+	cfg := getConfig()
+	loadRatings(&cfg)
+	if cfg.Scraper.ArchiveDir == "" {
+		fatal("reparse needs MINISTRY_SCRAPER_ARCHIVE_DIR to be set")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	articles, _, closeDB := openRepo(ctx, &cfg)
+	defer closeDB()
+
+	archive := getArchive(&cfg.Scraper)
+	sel := getSelectors(&cfg.Scraper).Get()
+
+	urls, err := archive.URLs(ctx)
+	if err != nil {
+		fatal("failed to list archived pages", "error", err)
+	}
+
+	var updated, created, unchanged, failed int
+	for _, url := range urls {
+		if ctx.Err() != nil {
+			break
+		}
+
+		slug, ok := scraping.SlugFromURL(url)
+		if !ok {
+			continue
+		}
+
+		page, err := archive.Latest(ctx, url)
+		if err != nil {
+			slog.Error("failed to read archived page", "url", url, "error", err)
+			failed++
+			continue
+		}
+
+		article, err := scraping.ParseArticle(ctx, bytes.NewReader(page.Body), slug, sel)
+		if err != nil {
+			slog.Error("failed to parse archived article", "slug", slug, "error", err)
+			failed++
+			continue
+		}
+
+		existing, err := articles.GetArticle(ctx, slug)
+		switch {
+		case errors.Is(err, repo.ErrNotFound):
+			// The scraper must have failed on this article the first time around.
+			err = articles.SaveArticle(ctx, article)
+			if err == nil {
+				created++
+			}
+		case err != nil:
+			// Reported below, like the errors from saving.
+		case existing.Hash() == article.Hash():
+			unchanged++
+		default:
+			err = articles.UpdateArticle(ctx, article)
+			if err == nil {
+				updated++
+			}
+		}
+		if err != nil {
+			slog.Error("failed to save reparsed article", "slug", slug, "error", err)
+			failed++
+		}
+	}
+
+	slog.Info("reparsed archived articles",
+		"updated", updated,
+		"created", created,
+		"unchanged", unchanged,
+		"failed", failed,
+	)
+	if ctx.Err() != nil {
+		fatal("reparse interrupted", "error", ctx.Err())
+	}
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
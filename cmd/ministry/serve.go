@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/glizzus/trf/internal/health"
+	"github.com/glizzus/trf/internal/ingest"
+	"github.com/glizzus/trf/internal/metrics"
+	"github.com/glizzus/trf/internal/repo"
+	"github.com/glizzus/trf/internal/schedule"
+	"github.com/glizzus/trf/internal/spoofing"
+	"github.com/glizzus/trf/internal/web"
+)
+
+// serve runs the web server, the ingest worker and the scheduled jobs until we get a signal.
+func serve() {
+	cfg := getConfig()
+	slog.Info("starting Ministry", "config", cfg)
+	loadRatings(&cfg)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTracing := setupTracing(ctx, &cfg.Tracing)
+	defer shutdownTracing()
+
+	db, latestMigration, closeDB := openRepo(ctx, &cfg)
+	defer closeDB()
+
+	repo := repo.Instrument(db)
+	spoofer := getSpoofer(&cfg.Spoofer)
+	selectors := getSelectors(&cfg.Scraper)
+	go selectors.Watch(ctx, cfg.Scraper.SelectorsReloadInterval)
+	scraper := getScraper(&cfg.Scraper, selectors)
+
+	authService := getAuth(&cfg.Auth, db)
+
+	worker := getWorker(&cfg.Ingest, scraper, repo, spoofer)
+	go func() {
+		// The worker gets its own context, because we want it to finish the article
+		// it is working on when we get a signal. Shutdown takes care of that.
+		if err := worker.Run(context.Background()); err != nil {
+			slog.Error("ingest worker stopped", "error", err)
+		}
+	}()
+
+	// Replicas share the database, and only the one holding the lock runs scheduled jobs.
+	// The election outlives the scheduler, so that no one takes over while a run is finishing.
+	var leader schedule.Leader
+	var elector *schedule.Elector
+	electionCtx, stopElection := context.WithCancel(context.Background())
+	electionDone := make(chan struct{})
+	if cfg.Schedule.LeaderElection {
+		elector = schedule.NewElector(db, "ministry-scheduler", cfg.Schedule.LeaderCheckInterval)
+		leader = elector
+		go func() {
+			defer close(electionDone)
+			elector.Run(electionCtx)
+		}()
+	} else {
+		close(electionDone)
+	}
+
+	scheduler := getScheduler(&cfg.Schedule, leader, worker, authService)
+	go func() {
+		// Like the worker, the scheduler lets the runs in progress finish, and Shutdown takes care of that.
+		if err := scheduler.Run(context.Background()); err != nil {
+			slog.Error("scheduler stopped", "error", err)
+		}
+	}()
+
+	readiness := health.NewChecker(cfg.Health.CheckTimeout)
+	readiness.Add(cfg.Database, health.Ping(db))
+	readiness.Add("migrations", health.Migrations(db, latestMigration))
+	if pinger, ok := spoofer.(spoofing.Pinger); ok {
+		readiness.Add("spoofer", health.Cached(health.Ping(pinger), cfg.Health.SpooferCheckInterval))
+	}
+	if cfg.Health.MaxIngestAge > 0 {
+		readiness.Add("ingest", ingestCheck(worker, elector, cfg.Health.MaxIngestAge))
+	}
+
+	const port = "80"
+	handler := web.New(repo, web.Options{
+		Readiness:     readiness,
+		Regenerator:   worker,
+		Requester:     worker,
+		Auth:          authService,
+		SecureCookies: cfg.Auth.SecureCookies,
+	})
+	server := &http.Server{
+		Addr:    ":" + port,
+		Handler: handler,
+	}
+
+	serverErr := make(chan error, 2)
+	go func() {
+		slog.Info("server listening", "port", port)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+	}()
+
+	// Metrics get their own listener, so that nginx never exposes them to the public.
+	var metricsServer *http.Server
+	if cfg.MetricsAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("GET /metrics", metrics.Handler())
+		metricsServer = &http.Server{Addr: cfg.MetricsAddr, Handler: mux}
+		go func() {
+			slog.Info("metrics listening", "addr", cfg.MetricsAddr)
+			if err := metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				serverErr <- err
+			}
+		}()
+	}
+
+	select {
+	case <-ctx.Done():
+		slog.Info("received signal, stopping Ministry")
+	case err := <-serverErr:
+		slog.Error("server failed, stopping Ministry", "error", err)
+	}
+	// A second signal should kill us immediately, like it would without a handler.
+	stop()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			slog.Error("failed to drain HTTP connections", "error", err)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		if err := worker.Shutdown(shutdownCtx); err != nil {
+			slog.Error("failed to let ingest worker finish its article", "error", err)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		if err := scheduler.Shutdown(shutdownCtx); err != nil {
+			slog.Error("failed to let scheduled jobs finish", "error", err)
+		}
+	}()
+	wg.Wait()
+
+	stopElection()
+	<-electionDone
+
+	// Metrics are served until the end, so the last scrape can see how shutdown went.
+	if metricsServer != nil {
+		metricsServer.Shutdown(shutdownCtx)
+	}
+
+	slog.Info("stopped Ministry")
+}
+
+// ingestCheck checks that the last successful ingest isn't older than maxAge.
+// Only the leader ingests, so the others pass, and becoming the leader counts as a success,
+// so that a new leader has time to ingest. If elector is nil, every instance ingests.
+func ingestCheck(worker *ingest.Worker, elector *schedule.Elector, maxAge time.Duration) health.Check {
+	if elector == nil {
+		return health.LastSuccess(worker.LastSuccess, maxAge)
+	}
+	check := health.LastSuccess(func() time.Time {
+		last, since := worker.LastSuccess(), elector.LeaderSince()
+		if last.After(since) {
+			return last
+		}
+		return since
+	}, maxAge)
+
+	return func(ctx context.Context) (string, error) {
+		if !elector.IsLeader() {
+			return "another instance ingests", nil
+		}
+		return check(ctx)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/glizzus/trf/internal/domain"
+	"github.com/glizzus/trf/internal/scraping"
+)
+
+// spoof scrapes and spoofs a single article, for fact checks that ingest never picked up
+// because they weren't on the listing of the latest ones when it ran.
+// Articles that already have a spoof are left alone.
+func spoof(args []string) {
+	flags := flag.NewFlagSet("spoof", flag.ExitOnError)
+	inversionName := flags.String("inversion", "", "inversion to use instead of the usual one")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		fatal("usage: ministry spoof [-inversion name] <url or slug>")
+	}
+
+	slug, err := scraping.ParseArticleTarget(flags.Arg(0))
+	if err != nil {
+		fatal("invalid article", "error", err)
+	}
+	var inversion domain.Inversion
+	if *inversionName != "" {
+		if inversion, err = domain.ParseInversion(*inversionName); err != nil {
+			fatal("invalid inversion", "error", err)
+		}
+	}
+
+	cfg := getConfig()
+	loadRatings(&cfg)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTracing := setupTracing(ctx, &cfg.Tracing)
+	defer shutdownTracing()
+
+	db, _, closeDB := openRepo(ctx, &cfg)
+	defer closeDB()
+
+	selectors := getSelectors(&cfg.Scraper)
+	worker := getWorker(&cfg.Ingest, getScraper(&cfg.Scraper, selectors), db, getSpoofer(&cfg.Spoofer))
+
+	existed, err := worker.SpoofArticle(ctx, slug, inversion)
+	if err != nil {
+		fatal("failed to spoof article", "slug", slug, "error", err)
+	}
+	if existed {
+		fmt.Printf("%s already has a spoof. Regenerate it from /admin to replace it.\n", slug)
+		return
+	}
+	fmt.Printf("Spoofed %s. Review it at /admin/spoofs/%s.\n", slug, slug)
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/glizzus/trf/internal/auth"
+	"github.com/glizzus/trf/internal/domain"
+)
+
+const usersUsage = `Usage: ministry users <command>
+
+Commands:
+  add [-role viewer|editor|admin] [-password-stdin] <username>
+        Create a user. Without -password-stdin, a password is generated and printed
+  remove <username>
+        Delete a user, signing them out and revoking their API tokens
+  reset [-password-stdin] <username>
+        Give a user a new password, signing them out everywhere
+  token [-scopes spoofs:read,...] [-ttl duration] <username> <name>
+        Create an API token for the JSON API, and print it
+`
+
+// users manages the users who can sign in to the admin area. It is how the first admin is created.
+func users(args []string) {
+	if len(args) < 1 {
+		fmt.Fprint(os.Stderr, usersUsage)
+		os.Exit(2)
+	}
+
+	cfg := getConfig()
+	ctx := context.Background()
+	db, _, closeDB := openRepo(ctx, &cfg)
+	defer closeDB()
+	service := getAuth(&cfg.Auth, db)
+
+	switch command, args := args[0], args[1:]; command {
+	case "add":
+		usersAdd(ctx, service, args)
+	case "remove":
+		usersRemove(ctx, service, args)
+	case "reset":
+		usersReset(ctx, service, args)
+	case "token":
+		usersToken(ctx, service, args)
+	default:
+		fmt.Fprint(os.Stderr, usersUsage)
+		fatal("unknown users command", "command", command)
+	}
+}
+
+func usersAdd(ctx context.Context, service *auth.Service, args []string) {
+	flags := flag.NewFlagSet("users add", flag.ExitOnError)
+	roleName := flags.String("role", string(domain.RoleEditor), "role of the user: viewer, editor or admin")
+	passwordStdin := flags.Bool("password-stdin", false, "read the password from the first line of stdin")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		fatal("usage: ministry users add [-role role] [-password-stdin] <username>")
+	}
+	username := flags.Arg(0)
+
+	role, err := domain.ParseRole(*roleName)
+	if err != nil {
+		fatal("invalid role", "error", err)
+	}
+	password, generated := readPassword(*passwordStdin)
+
+	if _, err := service.CreateUser(ctx, username, password, role); err != nil {
+		fatal("failed to add user", "username", username, "error", err)
+	}
+	fmt.Printf("Added %s %s.\n", role, username)
+	if generated {
+		fmt.Printf("Password: %s\n", password)
+	}
+}
+
+func usersRemove(ctx context.Context, service *auth.Service, args []string) {
+	if len(args) != 1 {
+		fatal("usage: ministry users remove <username>")
+	}
+	username := args[0]
+
+	if err := service.RemoveUser(ctx, username); err != nil {
+		fatal("failed to remove user", "username", username, "error", err)
+	}
+	fmt.Printf("Removed %s.\n", username)
+}
+
+func usersReset(ctx context.Context, service *auth.Service, args []string) {
+	flags := flag.NewFlagSet("users reset", flag.ExitOnError)
+	passwordStdin := flags.Bool("password-stdin", false, "read the password from the first line of stdin")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		fatal("usage: ministry users reset [-password-stdin] <username>")
+	}
+	username := flags.Arg(0)
+	password, generated := readPassword(*passwordStdin)
+
+	if err := service.ResetPassword(ctx, username, password); err != nil {
+		fatal("failed to reset password", "username", username, "error", err)
+	}
+	fmt.Printf("Reset the password of %s, and signed them out.\n", username)
+	if generated {
+		fmt.Printf("Password: %s\n", password)
+	}
+}
+
+func usersToken(ctx context.Context, service *auth.Service, args []string) {
+	flags := flag.NewFlagSet("users token", flag.ExitOnError)
+	scopeNames := flags.String("scopes", string(domain.ScopeSpoofsRead), "comma separated scopes of the token")
+	ttl := flags.Duration("ttl", 0, "how long the token works for, or 0 for ever")
+	flags.Parse(args)
+	if flags.NArg() != 2 {
+		fatal("usage: ministry users token [-scopes scopes] [-ttl duration] <username> <name>")
+	}
+	username, name := flags.Arg(0), flags.Arg(1)
+
+	scopes, err := domain.ParseScopes(*scopeNames)
+	if err != nil {
+		fatal("invalid scopes", "error", err)
+	}
+
+	token, err := service.CreateAPIToken(ctx, username, name, scopes, *ttl)
+	if err != nil {
+		fatal("failed to create API token", "username", username, "error", err)
+	}
+	// The token can't be shown again, since only its hash is stored.
+	fmt.Println(token)
+}
+
+// readPassword returns the first line of stdin if fromStdin is set, and a random password otherwise.
+// Passwords are never taken as arguments, which would leave them in the shell history.
+func readPassword(fromStdin bool) (password string, generated bool) {
+	if !fromStdin {
+		b := make([]byte, 18)
+		if _, err := rand.Read(b); err != nil {
+			fatal("failed to generate password", "error", err)
+		}
+		return base64.RawURLEncoding.EncodeToString(b), true
+	}
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		fatal("failed to read password from stdin", "error", err)
+	}
+	return strings.TrimRight(line, "\r\n"), false
+}
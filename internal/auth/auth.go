@@ -0,0 +1,263 @@
+// Package auth signs users in with their password or an API token, and decides what they may do.
+//
+// Passwords are hashed with bcrypt. Session and API tokens are random, and only their SHA-256 hashes
+// are stored, so that someone who reads the database can't use them.
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/glizzus/trf/internal/domain"
+	"github.com/glizzus/trf/internal/repo"
+)
+
+// MinPasswordLength is the fewest characters a password may have.
+const MinPasswordLength = 12
+
+var (
+	// ErrInvalidCredentials is returned when a username and password don't match,
+	// or a token doesn't exist. Which one was wrong is not said, on purpose.
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	// ErrWeakPassword is returned when a new password is too short.
+	ErrWeakPassword = fmt.Errorf("password must have at least %d characters", MinPasswordLength)
+)
+
+// Principal is who made a request, and what they may do.
+type Principal struct {
+	User   domain.User
+	Scopes []domain.Scope
+	// Session is the session the request belongs to. It is nil for requests made with an API token.
+	Session *domain.Session
+}
+
+// Can returns whether the principal has scope.
+func (p Principal) Can(scope domain.Scope) bool {
+	return slices.Contains(p.Scopes, scope)
+}
+
+// Options configures a Service.
+type Options struct {
+	// SessionTTL is how long a session lasts after signing in.
+	SessionTTL time.Duration
+}
+
+// Service manages users, and authenticates them.
+type Service struct {
+	users repo.UserRepo
+	opts  Options
+
+	// dummyHash is compared against when a user doesn't exist,
+	// so that signing in as an unknown user takes as long as with a wrong password.
+	dummyHash []byte
+}
+
+// NewService creates a Service that stores users in the given repo.
+func NewService(users repo.UserRepo, opts Options) *Service {
+	dummyHash, err := bcrypt.GenerateFromPassword([]byte("not a real password"), bcrypt.DefaultCost)
+	if err != nil {
+		// This only fails for passwords over 72 bytes, which ours isn't.
+		panic(err)
+	}
+	return &Service{users: users, opts: opts, dummyHash: dummyHash}
+}
+
+// hashPassword checks that password is good enough, and hashes it.
+func hashPassword(password string) (string, error) {
+	if utf8.RuneCountInString(password) < MinPasswordLength {
+		return "", ErrWeakPassword
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("error hashing password: %w", err)
+	}
+	return string(hash), nil
+}
+
+// newToken returns a random token that is safe to put in a cookie or a header.
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("error generating token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken returns the hash of a token that is stored instead of the token itself.
+// Tokens are random and long, so unlike passwords they don't need a slow hash.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateUser creates a user with the given password and role.
+// It returns an error wrapping repo.ErrConflict if the username is taken.
+func (s *Service) CreateUser(ctx context.Context, username, password string, role domain.Role) (domain.User, error) {
+	if username == "" {
+		return domain.User{}, errors.New("username must not be empty")
+	}
+	hash, err := hashPassword(password)
+	if err != nil {
+		return domain.User{}, err
+	}
+	return s.users.SaveUser(ctx, domain.User{Username: username, PasswordHash: hash, Role: role})
+}
+
+// RemoveUser deletes a user, which signs them out and revokes their API tokens.
+func (s *Service) RemoveUser(ctx context.Context, username string) error {
+	return s.users.DeleteUser(ctx, username)
+}
+
+// ResetPassword gives a user a new password, and signs them out everywhere.
+func (s *Service) ResetPassword(ctx context.Context, username, password string) error {
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+	return s.users.SetPassword(ctx, username, hash)
+}
+
+// DeleteExpiredSessions deletes the sessions that have expired. Sessions are only looked up by their hash,
+// so expired ones would pile up otherwise. It is run on a schedule.
+func (s *Service) DeleteExpiredSessions(ctx context.Context) error {
+	n, err := s.users.DeleteExpiredSessions(ctx)
+	if err != nil {
+		return fmt.Errorf("error deleting expired sessions: %w", err)
+	}
+	slog.InfoContext(ctx, "deleted expired sessions", "count", n)
+	return nil
+}
+
+// Login checks a username and password, and starts a session for the user.
+// It returns the token that identifies the session, which only the user's browser should keep.
+func (s *Service) Login(ctx context.Context, username, password string) (string, Principal, error) {
+	user, err := s.users.GetUser(ctx, username)
+	switch {
+	case errors.Is(err, repo.ErrNotFound):
+		bcrypt.CompareHashAndPassword(s.dummyHash, []byte(password))
+		return "", Principal{}, ErrInvalidCredentials
+	case err != nil:
+		return "", Principal{}, fmt.Errorf("error getting user %s: %w", username, err)
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return "", Principal{}, ErrInvalidCredentials
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return "", Principal{}, err
+	}
+	csrfToken, err := newToken()
+	if err != nil {
+		return "", Principal{}, err
+	}
+	now := time.Now()
+	session := domain.Session{
+		TokenHash: hashToken(token),
+		UserID:    user.ID,
+		CSRFToken: csrfToken,
+		Created:   now,
+		Expires:   now.Add(s.opts.SessionTTL),
+	}
+	if err := s.users.SaveSession(ctx, session); err != nil {
+		return "", Principal{}, err
+	}
+
+	return token, Principal{User: user, Scopes: user.Role.Scopes(), Session: &session}, nil
+}
+
+// Logout ends the session identified by token.
+func (s *Service) Logout(ctx context.Context, token string) error {
+	return s.users.DeleteSession(ctx, hashToken(token))
+}
+
+// Session returns who the session identified by token belongs to.
+// It returns ErrInvalidCredentials if there is no such session, or if it has expired.
+func (s *Service) Session(ctx context.Context, token string) (Principal, error) {
+	session, user, err := s.users.GetSession(ctx, hashToken(token))
+	switch {
+	case errors.Is(err, repo.ErrNotFound):
+		return Principal{}, ErrInvalidCredentials
+	case err != nil:
+		return Principal{}, err
+	}
+	return Principal{User: user, Scopes: user.Role.Scopes(), Session: &session}, nil
+}
+
+// APIToken returns who the API token belongs to.
+// It returns ErrInvalidCredentials if there is no such token, or if it has expired.
+func (s *Service) APIToken(ctx context.Context, token string) (Principal, error) {
+	apiToken, user, err := s.users.UseAPIToken(ctx, hashToken(token))
+	switch {
+	case errors.Is(err, repo.ErrNotFound):
+		return Principal{}, ErrInvalidCredentials
+	case err != nil:
+		return Principal{}, err
+	}
+
+	// A token can't do more than its user, even if the user was demoted after it was created.
+	var scopes []domain.Scope
+	for _, scope := range apiToken.Scopes {
+		if user.Role.Grants(scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	return Principal{User: user, Scopes: scopes}, nil
+}
+
+// CreateAPIToken creates a token for the JSON API, limited to scopes, that expires after ttl.
+// A zero ttl means that it never expires. The token is returned once, and can't be recovered later.
+func (s *Service) CreateAPIToken(ctx context.Context, username, name string, scopes []domain.Scope, ttl time.Duration) (string, error) {
+	user, err := s.users.GetUser(ctx, username)
+	if err != nil {
+		return "", err
+	}
+	for _, scope := range scopes {
+		if !user.Role.Grants(scope) {
+			return "", fmt.Errorf("a %s can't have a token with scope %s", user.Role, scope)
+		}
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return "", err
+	}
+	apiToken := domain.APIToken{
+		TokenHash: hashToken(token),
+		UserID:    user.ID,
+		Name:      name,
+		Scopes:    scopes,
+	}
+	if ttl > 0 {
+		expires := time.Now().Add(ttl)
+		apiToken.Expires = &expires
+	}
+	if _, err := s.users.SaveAPIToken(ctx, apiToken); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx that carries p.
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the principal carried by ctx, if there is one.
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
@@ -1,40 +1,124 @@
 package domain
 
-import "time"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
 
 type Claim struct {
 	Question string `json:"question"`
 	Rating   Rating `json:"rating"`
 
 	Context *string `json:"context,omitempty"`
+
+	// WhatsTrue and WhatsFalse break down a claim that is partly true.
+	// Most fact checks don't have them.
+	WhatsTrue  []string `json:"whats_true,omitempty"`
+	WhatsFalse []string `json:"whats_false,omitempty"`
+}
+
+// Image is an image embedded in an article.
+type Image struct {
+	URL string `json:"url"`
+	Alt string `json:"alt,omitempty"`
+}
+
+// Source is a citation at the end of an article.
+type Source struct {
+	// Text is the citation as it is written in the article.
+	Text string `json:"text"`
+	// URL is the link in the citation, if it has one.
+	URL string `json:"url,omitempty"`
 }
 
 type Article struct {
-	Slug     string    `json:"slug"`
+	Slug string `json:"slug"`
 
 	Title    string    `json:"title"`
 	Subtitle string    `json:"subtitle"`
+	Authors  []string  `json:"authors,omitempty"`
 	Date     time.Time `json:"date"`
+	// Updated is when the article was last updated, if it ever was.
+	Updated *time.Time `json:"updated,omitempty"`
+
+	Category string   `json:"category,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+
+	// Image is the featured image at the top of the article, if it has one.
+	Image *Image `json:"image,omitempty"`
 
-	Claim    Claim     `json:"claim"`
+	Claim Claim `json:"claim"`
 
 	Content []string `json:"content"`
+
+	Sources []Source `json:"sources,omitempty"`
+}
+
+// Hash returns a hash of everything we scrape from the article.
+// If Snopes revises the article, the hash changes.
+func (a *Article) Hash() string {
+	// Dates are formatted because the time zone and location of a time.Time
+	// depend on where it came from, but we only care about the day.
+	var updated string
+	if a.Updated != nil {
+		updated = a.Updated.Format(time.DateOnly)
+	}
+
+	// Fields that are empty are left out, so that an article without them
+	// hashes the same as it did before we scraped them, and so that nil and
+	// empty slices hash the same.
+	b, _ := json.Marshal(struct {
+		Slug     string
+		Title    string
+		Subtitle string
+		Authors  []string `json:",omitempty"`
+		Date     string
+		Updated  string   `json:",omitempty"`
+		Category string   `json:",omitempty"`
+		Tags     []string `json:",omitempty"`
+		Image    *Image   `json:",omitempty"`
+		Claim    Claim
+		Content  []string
+		Sources  []Source `json:",omitempty"`
+	}{
+		a.Slug, a.Title, a.Subtitle, a.Authors, a.Date.Format(time.DateOnly), updated,
+		a.Category, a.Tags, a.Image, a.Claim, a.Content, a.Sources,
+	})
+
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
 }
 
 // ToSpoof converts an Article to a Spoof.
-// The newContent parameter is the content of the spoofed article.
-// Everything else is the same as the original article, except the rating is opposite.
-func (a *Article) ToSpoof(newContent[] string) Spoof {
+// The newContent parameter is the content of the spoofed article, and inversion chose its rating.
+// Everything else is the same as the original article, except the rating is inverted,
+// and what's true and what's false trade places.
+//
+// The authors and sources are left out, because they belong to the original article.
+// The spoof starts as a draft, so that an editor reviews it before it is published.
+func (a *Article) ToSpoof(newContent []string, rating Rating, inversion Inversion) Spoof {
 	return Spoof{
-		Slug:     a.Slug,
-		Title:    a.Title,
-		Subtitle: a.Subtitle,
-		Date:     a.Date,
-		Claim: Claim{
-			Question: a.Claim.Question,
-			Rating:   a.Claim.Rating.Opposite(),
-			Context:  a.Claim.Context,
+		Article: Article{
+			Slug:     a.Slug,
+			Title:    a.Title,
+			Subtitle: a.Subtitle,
+			Date:     a.Date,
+			Updated:  a.Updated,
+			Category: a.Category,
+			Tags:     a.Tags,
+			Image:    a.Image,
+			Claim: Claim{
+				Question:   a.Claim.Question,
+				Rating:     rating,
+				Context:    a.Claim.Context,
+				WhatsTrue:  a.Claim.WhatsFalse,
+				WhatsFalse: a.Claim.WhatsTrue,
+			},
+			Content: newContent,
 		},
-		Content: newContent,
+		Inversion: inversion.Name(),
+		Status:    SpoofDraft,
 	}
 }
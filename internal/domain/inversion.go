@@ -0,0 +1,166 @@
+package domain
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"strings"
+)
+
+// Inversion chooses the rating of a spoof from the rating of the original article.
+type Inversion interface {
+	// Name identifies the inversion. It is recorded on the spoofs it chooses ratings for.
+	Name() string
+	// Description explains the inversion to the spoofer, so that the spoof can play along.
+	Description() string
+	// Invert returns the rating the spoof should have.
+	Invert(r Rating) Rating
+}
+
+// The names of the inversions that ParseInversion understands.
+const (
+	InversionTable   = "table"
+	InversionMirror  = "mirror"
+	InversionAbsurd  = "absurd"
+	InversionRandom  = "random"
+	InversionNeutral = "keep-neutral"
+)
+
+// DefaultInversion is the inversion used when none is chosen. It uses the opposites in the rating map,
+// so that True becomes False, Scam becomes Legit, and vague ratings like Unproven become True.
+const DefaultInversion = InversionTable
+
+// InversionNames lists the names that ParseInversion understands.
+func InversionNames() []string {
+	return []string{InversionTable, InversionMirror, InversionAbsurd, InversionRandom, InversionNeutral}
+}
+
+// ParseInversion returns the inversion with the given name. An empty name is the default inversion.
+func ParseInversion(name string) (Inversion, error) {
+	switch name {
+	case "", InversionTable:
+		return tableInversion{}, nil
+	case InversionMirror:
+		return mirrorInversion{}, nil
+	case InversionAbsurd:
+		return absurdInversion{}, nil
+	case InversionRandom:
+		return randomInversion{}, nil
+	case InversionNeutral:
+		return neutralInversion{}, nil
+	default:
+		return nil, fmt.Errorf("unknown inversion %q, expected one of %s", name, strings.Join(InversionNames(), ", "))
+	}
+}
+
+// tableInversion uses the opposites in the rating map.
+type tableInversion struct{}
+
+func (tableInversion) Name() string { return InversionTable }
+
+func (tableInversion) Description() string {
+	return "The new rating is the usual opposite of the original rating."
+}
+
+func (tableInversion) Invert(r Rating) Rating { return r.Opposite() }
+
+// mirrorInversion mirrors the rating on the truthiness scale, so that Mostly False becomes Mostly True,
+// and Scam becomes Legit. Neutral ratings stay as they are.
+type mirrorInversion struct{}
+
+func (mirrorInversion) Name() string { return InversionMirror }
+
+func (mirrorInversion) Description() string {
+	return "The new rating is exactly as true as the original rating was false, and the other way around."
+}
+
+func (mirrorInversion) Invert(r Rating) Rating {
+	info := r.Info()
+	return closestRating(r, info.Kind, -info.Truthiness)
+}
+
+// absurdInversion goes as far from the original rating as the scale allows.
+// Anything that isn't true becomes entirely true, and anything true becomes entirely false.
+type absurdInversion struct{}
+
+func (absurdInversion) Name() string { return InversionAbsurd }
+
+func (absurdInversion) Description() string {
+	return "The new rating is as far from the original rating as possible. Commit to it completely, however absurd it gets."
+}
+
+func (absurdInversion) Invert(r Rating) Rating {
+	info := r.Info()
+	if info.Truthiness > 0 {
+		return closestRating(r, info.Kind, MinTruthiness)
+	}
+	return closestRating(r, info.Kind, MaxTruthiness)
+}
+
+// randomInversion picks any rating on the other side of the truthiness scale.
+// Neutral ratings have no other side, so they stay as they are.
+type randomInversion struct{}
+
+func (randomInversion) Name() string { return InversionRandom }
+
+func (randomInversion) Description() string {
+	return "The new rating was picked at random from the ratings that disagree with the original rating."
+}
+
+func (randomInversion) Invert(r Rating) Rating {
+	truthiness := r.Info().Truthiness
+	if truthiness == 0 {
+		return r
+	}
+
+	var candidates []Rating
+	for _, info := range RatingInfos() {
+		if info.Truthiness*truthiness < 0 {
+			candidates = append(candidates, info.Rating)
+		}
+	}
+	if len(candidates) == 0 {
+		return r.Opposite()
+	}
+	return candidates[rand.IntN(len(candidates))]
+}
+
+// neutralInversion keeps ratings that don't judge how true a claim is, like Unproven or Outdated,
+// and mirrors the rest.
+type neutralInversion struct{}
+
+func (neutralInversion) Name() string { return InversionNeutral }
+
+func (neutralInversion) Description() string {
+	return "The new rating is the mirror image of the original rating, unless the original rating was undecided, in which case it stays undecided."
+}
+
+func (neutralInversion) Invert(r Rating) Rating {
+	info := r.Info()
+	if info.Truthiness == 0 || info.Kind == KindUndetermined || info.Kind == KindStatus {
+		return r
+	}
+	return mirrorInversion{}.Invert(r)
+}
+
+// closestRating returns a rating with the given truthiness, preferring r itself,
+// then ratings of the same kind, then ratings on the truth scale.
+// Ratings of the same kind and truthiness are picked in alphabetical order, so the result is stable.
+func closestRating(r Rating, kind RatingKind, truthiness int) Rating {
+	if info := r.Info(); info.Kind == kind && info.Truthiness == truthiness {
+		return r
+	}
+
+	infos := RatingInfos()
+	sort.SliceStable(infos, func(i, j int) bool { return infos[i].Rating < infos[j].Rating })
+
+	for _, k := range []RatingKind{kind, KindTruth} {
+		for _, info := range infos {
+			if info.Kind == k && info.Truthiness == truthiness {
+				return info.Rating
+			}
+		}
+	}
+	// The truth scale has a rating for every truthiness, so this is only a safeguard.
+	return r.Opposite()
+}
@@ -0,0 +1,153 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInversions(t *testing.T) {
+	tests := []struct {
+		inversion string
+		rating    Rating
+		want      Rating
+	}{
+		{InversionTable, "True", "False"},
+		{InversionTable, "Mostly False", "Mostly True"},
+		{InversionTable, "Scam", "Legit"},
+		{InversionTable, "Unproven", "True"},
+		{InversionTable, "Mixture", "Mixture"},
+		// Unknown and empty ratings fall back to Unproven.
+		{InversionTable, "Zombie Claim", "True"},
+		{InversionTable, "", "True"},
+
+		{InversionMirror, "Mostly False", "Mostly True"},
+		{InversionMirror, "True", "False"},
+		{InversionMirror, "Scam", "Legit"},
+		{InversionMirror, "Mixture", "Mixture"},
+		{InversionMirror, "Outdated", "Outdated"},
+		// Fake and Scam are both as false as Legit is true, so the first one alphabetically wins.
+		{InversionMirror, "Legit", "Fake"},
+		// Neither attribution nor undetermined ratings have a mostly true one, so they go to the truth scale.
+		{InversionMirror, "Miscaptioned", "Mostly True"},
+		{InversionMirror, "Unproven", "Mostly True"},
+		{InversionMirror, "Zombie Claim", "Mostly True"},
+		{InversionMirror, "", "Mostly True"},
+
+		{InversionAbsurd, "True", "False"},
+		{InversionAbsurd, "Mostly True", "False"},
+		{InversionAbsurd, "Mostly False", "True"},
+		{InversionAbsurd, "Mixture", "True"},
+		{InversionAbsurd, "Legit", "Fake"},
+		{InversionAbsurd, "Lost Legend", "True"},
+		{InversionAbsurd, "Research in Progress", "True"},
+		{InversionAbsurd, "Zombie Claim", "True"},
+
+		{InversionNeutral, "Mostly False", "Mostly True"},
+		{InversionNeutral, "Scam", "Legit"},
+		{InversionNeutral, "Misattributed", "Correct Attribution"},
+		{InversionNeutral, "Mixture", "Mixture"},
+		{InversionNeutral, "Unproven", "Unproven"},
+		{InversionNeutral, "Unfounded", "Unfounded"},
+		{InversionNeutral, "Outdated", "Outdated"},
+		{InversionNeutral, "Recall", "Recall"},
+		{InversionNeutral, "Zombie Claim", "Zombie Claim"},
+		{InversionNeutral, "", ""},
+
+		// Neutral ratings have no other side to pick from.
+		{InversionRandom, "Mixture", "Mixture"},
+		{InversionRandom, "Research in Progress", "Research in Progress"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.inversion+"/"+tt.rating.String(), func(t *testing.T) {
+			inversion, err := ParseInversion(tt.inversion)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := inversion.Invert(tt.rating); got != tt.want {
+				t.Errorf("Invert(%q) = %q, want %q", tt.rating, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRandomInversion(t *testing.T) {
+	inversion, err := ParseInversion(InversionRandom)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, rating := range []Rating{"True", "Mostly True", "Fake", "Unproven", "Legend", "Zombie Claim", ""} {
+		truthiness := rating.Info().Truthiness
+		// The pick is random, so try often enough to see most of the candidates.
+		for range 50 {
+			got := inversion.Invert(rating)
+			if !got.Known() {
+				t.Fatalf("Invert(%q) = %q, which isn't a known rating", rating, got)
+			}
+			if got.Info().Truthiness*truthiness >= 0 {
+				t.Fatalf("Invert(%q) = %q, which doesn't disagree with it", rating, got)
+			}
+		}
+	}
+}
+
+func TestParseInversion(t *testing.T) {
+	for _, name := range InversionNames() {
+		inversion, err := ParseInversion(name)
+		if err != nil {
+			t.Errorf("ParseInversion(%q) failed: %v", name, err)
+			continue
+		}
+		if inversion.Name() != name {
+			t.Errorf("ParseInversion(%q).Name() = %q", name, inversion.Name())
+		}
+		if inversion.Description() == "" {
+			t.Errorf("ParseInversion(%q) has no description", name)
+		}
+	}
+
+	inversion, err := ParseInversion("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inversion.Name() != DefaultInversion {
+		t.Errorf("ParseInversion(\"\").Name() = %q, want %q", inversion.Name(), DefaultInversion)
+	}
+
+	for _, name := range []string{"opposite", "Table", " mirror"} {
+		_, err := ParseInversion(name)
+		if err == nil {
+			t.Errorf("ParseInversion(%q) succeeded, want an error", name)
+			continue
+		}
+		if !strings.Contains(err.Error(), InversionNeutral) {
+			t.Errorf("ParseInversion(%q) = %v, want it to list the known inversions", name, err)
+		}
+	}
+}
+
+func TestClosestRating(t *testing.T) {
+	tests := []struct {
+		rating     Rating
+		kind       RatingKind
+		truthiness int
+		want       Rating
+	}{
+		// The rating itself wins when it fits.
+		{"Scam", KindAuthenticity, -2, "Scam"},
+		{"Fake", KindAuthenticity, -2, "Fake"},
+		// Otherwise the first one of the same kind alphabetically.
+		{"Legit", KindAuthenticity, -2, "Fake"},
+		{"Lost Legend", KindOrigin, -2, "Labeled Satire"},
+		// Otherwise the truth scale.
+		{"Legit", KindAuthenticity, 1, "Mostly True"},
+		{"Recall", KindStatus, 2, "True"},
+		// A kind with no ratings at all.
+		{"Zombie Claim", RatingKind("vibes"), -1, "Mostly False"},
+	}
+	for _, tt := range tests {
+		if got := closestRating(tt.rating, tt.kind, tt.truthiness); got != tt.want {
+			t.Errorf("closestRating(%q, %q, %d) = %q, want %q", tt.rating, tt.kind, tt.truthiness, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package domain
+
+import "time"
+
+// JobStatus is how far along a SpoofJob is.
+type JobStatus string
+
+const (
+	JobQueued  JobStatus = "queued"
+	JobRunning JobStatus = "running"
+	JobDone    JobStatus = "done"
+	JobFailed  JobStatus = "failed"
+)
+
+// Finished returns whether a job with status s is over, one way or the other.
+func (s JobStatus) Finished() bool {
+	return s == JobDone || s == JobFailed
+}
+
+// SpoofJob is a request to scrape and spoof an article on demand,
+// for articles that were never on the listing of the latest fact checks,
+// or to regenerate the spoof of an article we have.
+type SpoofJob struct {
+	ID   string `json:"id"`
+	Slug string `json:"slug"`
+	// Regenerate is whether the job replaces the spoof of an article we have, rather than spoofing a new one.
+	Regenerate bool `json:"regenerate,omitempty"`
+	// Inversion is the name of the inversion that was asked for. It is empty if the usual one applies.
+	Inversion string    `json:"inversion,omitempty"`
+	Status    JobStatus `json:"status"`
+	// Existed is whether the article already had a spoof, in which case nothing was done.
+	Existed bool `json:"existed,omitempty"`
+	// Error says why the job failed.
+	Error    string     `json:"error,omitempty"`
+	Created  time.Time  `json:"created"`
+	Finished *time.Time `json:"finished,omitempty"`
+}
@@ -1,8 +1,20 @@
 package domain
 
-import "fmt"
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+)
 
 // Rating is a type that represents the rating of a claim.
+//
+// A rating is kept exactly as Snopes wrote it, even if we don't know it.
+// Snopes adds ratings from time to time, and we would rather keep the article
+// than drop it. Use Category to get a rating we know how to handle.
 type Rating string
 
 // String returns the string representation of the rating.
@@ -11,22 +23,46 @@ func (r Rating) String() string {
 }
 
 // ParseRating parses a string into a Rating.
-// If the string is not a valid rating, an error is returned.
+// Known ratings and aliases are matched regardless of case and spacing, and are returned in their usual spelling.
+// Unknown ratings are returned as they are, so only an empty string is an error.
 func ParseRating(s string) (Rating, error) {
-	if _, ok := ratingsOpposite[s]; !ok {
-		return "", fmt.Errorf("invalid rating: %s", s)
+	s = strings.Join(strings.Fields(s), " ")
+	if s == "" {
+		return "", fmt.Errorf("invalid rating: empty")
 	}
-	return Rating(s), nil
+	return currentRatingMap().normalize(s), nil
+}
+
+// Known returns whether the rating is mapped, either because it has an opposite or because it is an alias.
+// Ratings that aren't known fall back to the default category.
+func (r Rating) Known() bool {
+	m := currentRatingMap()
+	_, opposite := m.Opposites[r]
+	_, alias := m.Aliases[r]
+	return opposite || alias
+}
+
+// Category returns the known rating that r means.
+// That is r itself if it has an opposite, what it is an alias of, or the fallback otherwise.
+func (r Rating) Category() Rating {
+	m := currentRatingMap()
+	if _, ok := m.Opposites[r]; ok {
+		return r
+	}
+	if category, ok := m.Aliases[r]; ok {
+		return category
+	}
+	return m.Fallback
 }
 
 // Opposite returns the opposite rating of the current rating.
+// A rating without an opposite of its own gets the opposite of its category.
 func (r Rating) Opposite() Rating {
-	return Rating(ratingsOpposite[r.String()])
+	return currentRatingMap().Opposites[r.Category()]
 }
 
-// This map serves two purposes:
-// 1. The keys are all of the valid ratings - this is used to validate the rating
-// 2. The value for each key is the opposite of the key. This is used to generate the opposite rating.
+// ratingsOpposite holds the ratings we know out of the box, each with its opposite.
+// A rating map file can add to it without a new build.
 var ratingsOpposite = map[string]string{
 	// These are strict opposites
 	"True":                "False",
@@ -38,13 +74,15 @@ var ratingsOpposite = map[string]string{
 	"Correct Attribution": "Misattributed",
 	"Misattributed":       "Correct Attribution",
 
+	// A scam is the opposite of something legit, even though legit's opposite is fake
+	"Scam": "Legit",
+
 	// These just get flipped to true because they are vague
 	"Unproven":             "True",
 	"Unfounded":            "True",
 	"Outdated":             "True",
 	"Miscaptioned":         "True",
 	"Legend":               "True",
-	"Scam":                 "True",
 	"Labeled Satire":       "True",
 	"Originated as Satire": "True",
 
@@ -54,3 +92,141 @@ var ratingsOpposite = map[string]string{
 	"Lost Legend":          "Lost Legend",
 	"Recall":               "Recall",
 }
+
+// defaultFallback is the category of ratings we don't know. It is vague enough to not claim anything.
+const defaultFallback = "Unproven"
+
+// RatingMap decides what each rating means, and what its opposite is.
+// It lets us handle a new Snopes rating by editing a file instead of the code.
+type RatingMap struct {
+	// Opposites maps each rating to its opposite. Its keys are the categories.
+	Opposites map[Rating]Rating `json:"opposites"`
+	// Aliases maps ratings to the category they mean, like a new spelling of an old rating.
+	Aliases map[Rating]Rating `json:"aliases"`
+	// Fallback is the category of ratings that are neither in Opposites nor in Aliases.
+	Fallback Rating `json:"fallback"`
+	// Info describes each rating. A rating without an entry uses the one of its category.
+	Info map[Rating]RatingInfo `json:"info"`
+
+	// lookup maps the lowercase form of every known rating to its usual spelling.
+	lookup map[string]Rating
+}
+
+// DefaultRatingMap returns the ratings that are compiled into the binary.
+func DefaultRatingMap() *RatingMap {
+	m := &RatingMap{
+		Opposites: make(map[Rating]Rating, len(ratingsOpposite)),
+		Aliases:   map[Rating]Rating{},
+		Fallback:  defaultFallback,
+		Info:      make(map[Rating]RatingInfo, len(ratingInfos)),
+	}
+	for rating, opposite := range ratingsOpposite {
+		m.Opposites[Rating(rating)] = Rating(opposite)
+	}
+	for _, info := range ratingInfos {
+		m.Info[info.Rating] = info
+	}
+	m.index()
+	return m
+}
+
+// ParseRatingMap parses a rating map file. Its opposites, aliases and info are added to the defaults,
+// and replace them where they overlap. The fallback replaces the default if it is set.
+func ParseRatingMap(data []byte) (*RatingMap, error) {
+	var file RatingMap
+	dec := json.NewDecoder(bytes.NewReader(data))
+	// A typo in a field name would otherwise silently be ignored.
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&file); err != nil {
+		return nil, fmt.Errorf("unable to decode rating map: %w", err)
+	}
+
+	m := DefaultRatingMap()
+	for rating, opposite := range file.Opposites {
+		m.Opposites[rating] = opposite
+	}
+	for rating, category := range file.Aliases {
+		m.Aliases[rating] = category
+	}
+	if file.Fallback != "" {
+		m.Fallback = file.Fallback
+	}
+	for rating, info := range file.Info {
+		info.Rating = rating
+		m.Info[rating] = info
+	}
+
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+	m.index()
+	return m, nil
+}
+
+func (m *RatingMap) validate() error {
+	var errs []error
+	for rating, opposite := range m.Opposites {
+		if _, ok := m.Opposites[opposite]; !ok {
+			errs = append(errs, fmt.Errorf("opposite %q of %q has no opposite of its own", opposite, rating))
+		}
+	}
+	for rating, category := range m.Aliases {
+		if _, ok := m.Opposites[rating]; ok {
+			errs = append(errs, fmt.Errorf("%q is both an alias and has an opposite", rating))
+		}
+		if _, ok := m.Opposites[category]; !ok {
+			errs = append(errs, fmt.Errorf("alias %q points to %q, which has no opposite", rating, category))
+		}
+	}
+	if _, ok := m.Opposites[m.Fallback]; !ok {
+		errs = append(errs, fmt.Errorf("fallback %q has no opposite", m.Fallback))
+	}
+	for _, info := range m.Info {
+		if err := info.validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m *RatingMap) index() {
+	m.lookup = make(map[string]Rating, len(m.Opposites)+len(m.Aliases))
+	for rating := range m.Opposites {
+		m.lookup[strings.ToLower(rating.String())] = rating
+	}
+	for rating := range m.Aliases {
+		m.lookup[strings.ToLower(rating.String())] = rating
+	}
+}
+
+// normalize returns the usual spelling of s if it is known, and s otherwise.
+func (m *RatingMap) normalize(s string) Rating {
+	if rating, ok := m.lookup[strings.ToLower(s)]; ok {
+		return rating
+	}
+	return Rating(s)
+}
+
+var ratingMap atomic.Pointer[RatingMap]
+
+// SetRatingMap replaces the rating map used by every Rating. It is safe to call at any time.
+func SetRatingMap(m *RatingMap) {
+	ratingMap.Store(m)
+}
+
+func currentRatingMap() *RatingMap {
+	if m := ratingMap.Load(); m != nil {
+		return m
+	}
+	// Only the first caller's map is kept, so every caller sees the same one.
+	ratingMap.CompareAndSwap(nil, DefaultRatingMap())
+	return ratingMap.Load()
+}
+
+// RatingCount is how many articles have a rating, and which one of them is the most recent.
+type RatingCount struct {
+	Rating     Rating    `json:"rating"`
+	Articles   int       `json:"articles"`
+	LatestSlug string    `json:"latest_slug"`
+	LatestDate time.Time `json:"latest_date"`
+}
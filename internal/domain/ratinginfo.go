@@ -0,0 +1,142 @@
+package domain
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// RatingKind is the category a rating belongs to, which is what the rating judges about a claim.
+type RatingKind string
+
+const (
+	// KindTruth ratings judge how true a claim is, from True to False.
+	KindTruth RatingKind = "truth"
+	// KindAuthenticity ratings judge whether something is genuine, like a product or a message.
+	KindAuthenticity RatingKind = "authenticity"
+	// KindAttribution ratings judge whether a quote or a picture is credited to the right source.
+	KindAttribution RatingKind = "attribution"
+	// KindOrigin ratings judge where a story came from, like a legend or a piece of satire.
+	KindOrigin RatingKind = "origin"
+	// KindUndetermined ratings mean there isn't enough evidence to judge the claim.
+	KindUndetermined RatingKind = "undetermined"
+	// KindStatus ratings describe the state of a claim rather than its truth, like a recall.
+	KindStatus RatingKind = "status"
+)
+
+// ParseRatingKind returns the kind with the given name.
+func ParseRatingKind(s string) (RatingKind, error) {
+	switch kind := RatingKind(s); kind {
+	case KindTruth, KindAuthenticity, KindAttribution, KindOrigin, KindUndetermined, KindStatus:
+		return kind, nil
+	default:
+		return "", fmt.Errorf("unknown rating kind %q", s)
+	}
+}
+
+// The truthiness scale goes from MinTruthiness, for a claim that is entirely false,
+// to MaxTruthiness, for a claim that is entirely true. Zero means neither.
+const (
+	MinTruthiness = -2
+	MaxTruthiness = 2
+)
+
+// RatingInfo describes a rating, and how to display it.
+type RatingInfo struct {
+	Rating Rating     `json:"rating"`
+	Kind   RatingKind `json:"kind"`
+	// Truthiness is how true a claim with this rating is, from MinTruthiness to MaxTruthiness.
+	Truthiness  int    `json:"truthiness"`
+	Description string `json:"description"`
+	// Color is the CSS hex color of the rating's badge.
+	Color string `json:"color"`
+	// Icon is a single character shown on the rating's badge.
+	Icon string `json:"icon"`
+}
+
+// ratingInfos describes the ratings we know out of the box.
+// The descriptions are paraphrased from https://www.snopes.com/fact-check-ratings/
+var ratingInfos = []RatingInfo{
+	{"True", KindTruth, 2, "The primary elements of the claim are accurate.", "#1a7f37", "✓"},
+	{"Mostly True", KindTruth, 1, "The primary elements of the claim are accurate, but some details are not.", "#57a639", "✓"},
+	{"Mixture", KindTruth, 0, "The claim has significant elements of both truth and falsity.", "#d4a017", "◐"},
+	{"Mostly False", KindTruth, -1, "The primary elements of the claim are inaccurate, but some details are accurate.", "#e06c1f", "✗"},
+	{"False", KindTruth, -2, "The primary elements of the claim are inaccurate.", "#c62828", "✗"},
+
+	{"Legit", KindAuthenticity, 2, "The item is genuine.", "#1a7f37", "✓"},
+	{"Fake", KindAuthenticity, -2, "The item is not genuine.", "#c62828", "✗"},
+	{"Scam", KindAuthenticity, -2, "The item is an attempt to swindle people.", "#8e24aa", "⚠"},
+
+	{"Correct Attribution", KindAttribution, 2, "The quote was said or written by the person it is credited to.", "#1a7f37", "❝"},
+	{"Misattributed", KindAttribution, -2, "The quote was said or written by someone other than the person it is credited to.", "#c62828", "❝"},
+	{"Miscaptioned", KindAttribution, -1, "The picture or video is real, but it is described wrongly.", "#e06c1f", "▣"},
+
+	{"Legend", KindOrigin, -2, "The story is a folk tale that can't be traced to a real event.", "#6d4c41", "❦"},
+	{"Lost Legend", KindOrigin, -1, "The story is a legend we made up to see whether readers would notice.", "#6d4c41", "❦"},
+	{"Labeled Satire", KindOrigin, -2, "The claim comes from a source that says it publishes satire.", "#3949ab", "☺"},
+	{"Originated as Satire", KindOrigin, -2, "The claim started as satire, and was later taken seriously.", "#3949ab", "☺"},
+
+	{"Unproven", KindUndetermined, -1, "There isn't enough evidence to say whether the claim is true.", "#757575", "?"},
+	{"Unfounded", KindUndetermined, -2, "The claim has no evidence behind it at all.", "#757575", "?"},
+	{"Research in Progress", KindUndetermined, 0, "We are still looking into the claim.", "#757575", "…"},
+
+	{"Outdated", KindStatus, 0, "The claim was true once, but it isn't anymore.", "#546e7a", "⏱"},
+	{"Recall", KindStatus, 0, "The product has been recalled.", "#546e7a", "!"},
+}
+
+// unknownRatingInfo describes a rating whose category has no description of its own.
+var unknownRatingInfo = RatingInfo{
+	Kind:        KindUndetermined,
+	Description: "We don't know this rating yet.",
+	Color:       "#757575",
+	Icon:        "?",
+}
+
+var hexColor = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+func (i RatingInfo) validate() error {
+	if i.Truthiness < MinTruthiness || i.Truthiness > MaxTruthiness {
+		return fmt.Errorf("truthiness %d of %q is not between %d and %d", i.Truthiness, i.Rating, MinTruthiness, MaxTruthiness)
+	}
+	// The color ends up in a style attribute, so we only accept what we know is safe.
+	if !hexColor.MatchString(i.Color) {
+		return fmt.Errorf("color %q of %q is not a hex color", i.Color, i.Rating)
+	}
+	return nil
+}
+
+// Info describes the rating. A rating with no description of its own gets the one of its category,
+// but keeps its own name.
+func (r Rating) Info() RatingInfo {
+	m := currentRatingMap()
+
+	info, ok := m.Info[r]
+	if !ok {
+		info, ok = m.Info[r.Category()]
+	}
+	if !ok {
+		info = unknownRatingInfo
+	}
+	info.Rating = r
+	return info
+}
+
+// RatingInfos describes every rating that has an opposite, most true first.
+func RatingInfos() []RatingInfo {
+	m := currentRatingMap()
+
+	infos := make([]RatingInfo, 0, len(m.Opposites))
+	for rating := range m.Opposites {
+		infos = append(infos, rating.Info())
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Truthiness != infos[j].Truthiness {
+			return infos[i].Truthiness > infos[j].Truthiness
+		}
+		if infos[i].Kind != infos[j].Kind {
+			return infos[i].Kind < infos[j].Kind
+		}
+		return infos[i].Rating < infos[j].Rating
+	})
+	return infos
+}
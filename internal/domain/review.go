@@ -0,0 +1,80 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// SpoofStatus is where a spoof is in the review workflow.
+// A spoof starts as a draft, is sent for review, and is then published. Only published spoofs are public.
+type SpoofStatus string
+
+const (
+	SpoofDraft     SpoofStatus = "draft"
+	SpoofReview    SpoofStatus = "review"
+	SpoofPublished SpoofStatus = "published"
+)
+
+// SpoofStatuses lists the statuses in the order a spoof goes through them.
+func SpoofStatuses() []SpoofStatus {
+	return []SpoofStatus{SpoofDraft, SpoofReview, SpoofPublished}
+}
+
+// ParseSpoofStatus returns the status with the given name.
+func ParseSpoofStatus(s string) (SpoofStatus, error) {
+	switch status := SpoofStatus(s); status {
+	case SpoofDraft, SpoofReview, SpoofPublished:
+		return status, nil
+	default:
+		return "", fmt.Errorf("unknown spoof status %q", s)
+	}
+}
+
+// spoofTransitions lists the statuses a spoof can move to from each status.
+// A spoof in review can be sent back to draft, and unpublishing a spoof makes it a draft again.
+var spoofTransitions = map[SpoofStatus][]SpoofStatus{
+	SpoofDraft:     {SpoofReview},
+	SpoofReview:    {SpoofPublished, SpoofDraft},
+	SpoofPublished: {SpoofDraft},
+}
+
+// Transitions returns the statuses that a spoof with status s can move to.
+func (s SpoofStatus) Transitions() []SpoofStatus {
+	return spoofTransitions[s]
+}
+
+// CanBecome returns whether a spoof with status s can move to status next.
+func (s SpoofStatus) CanBecome(next SpoofStatus) bool {
+	for _, t := range spoofTransitions[s] {
+		if t == next {
+			return true
+		}
+	}
+	return false
+}
+
+// ArticleOverview summarizes an article and its spoof, for editors reviewing spoofs.
+type ArticleOverview struct {
+	Slug   string    `json:"slug"`
+	Title  string    `json:"title"`
+	Date   time.Time `json:"date"`
+	Rating Rating    `json:"rating"`
+
+	// SpoofStatus is empty if the article has no spoof, which happens when its spoof was deleted.
+	SpoofStatus SpoofStatus `json:"spoof_status,omitempty"`
+	SpoofRating Rating      `json:"spoof_rating,omitempty"`
+	// Stale is whether the article changed its rating after it was spoofed.
+	Stale bool `json:"stale,omitempty"`
+	// StatusChanged is when the spoof last changed its status.
+	StatusChanged time.Time `json:"status_changed"`
+	// Edited is when an editor last changed the content of the spoof, if ever.
+	Edited *time.Time `json:"edited,omitempty"`
+}
+
+// ArticleFilter narrows down the articles listed for review.
+type ArticleFilter struct {
+	// SpoofStatus only lists articles whose spoof has this status, if it isn't empty.
+	SpoofStatus SpoofStatus
+	Limit       int
+	Offset      int
+}
@@ -0,0 +1,13 @@
+package domain
+
+// SearchResult is a spoof that matched a full-text search query.
+type SearchResult struct {
+	SpoofStub
+
+	// Rank is how well the spoof matched the query. Higher is better.
+	Rank float64 `json:"rank"`
+
+	// Snippet is an HTML fragment of the spoof's content around the matched terms.
+	// The matched terms are wrapped in <mark> tags, and everything else is escaped.
+	Snippet string `json:"snippet"`
+}
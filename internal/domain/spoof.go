@@ -2,11 +2,20 @@ package domain
 
 // A spoof has the same shape as the Article, but has different semantic meaning.
 // Because of the similarity, Article.ToSpoof is a method on Article to easily make spoofs.
-type Spoof Article
+type Spoof struct {
+	Article
+
+	// Inversion is the name of the Inversion that chose the spoof's rating.
+	// It is empty for spoofs made before we recorded it.
+	Inversion string `json:"inversion,omitempty"`
+
+	// Status is where the spoof is in the review workflow. Only published spoofs are public.
+	Status SpoofStatus `json:"status,omitempty"`
+}
 
 type SpoofStub struct {
-	Slug     string
-	Title    string
-	Subtitle string
-	Date     string
+	Slug     string `json:"slug"`
+	Title    string `json:"title"`
+	Subtitle string `json:"subtitle"`
+	Date     string `json:"date"`
 }
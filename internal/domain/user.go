@@ -0,0 +1,120 @@
+package domain
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Role is what a user is allowed to do. Each role can do everything the roles below it can.
+type Role string
+
+const (
+	// RoleViewer can read spoofs in any status, but not change them.
+	RoleViewer Role = "viewer"
+	// RoleEditor can also edit, regenerate and publish spoofs.
+	RoleEditor Role = "editor"
+	// RoleAdmin can also delete spoofs.
+	RoleAdmin Role = "admin"
+)
+
+// Roles lists the roles from the least to the most powerful.
+func Roles() []Role {
+	return []Role{RoleViewer, RoleEditor, RoleAdmin}
+}
+
+// ParseRole returns the role with the given name.
+func ParseRole(s string) (Role, error) {
+	switch role := Role(s); role {
+	case RoleViewer, RoleEditor, RoleAdmin:
+		return role, nil
+	default:
+		return "", fmt.Errorf("unknown role %q, expected viewer, editor or admin", s)
+	}
+}
+
+// Scope is a permission to do something, which API tokens are limited to.
+type Scope string
+
+const (
+	// ScopeSpoofsRead allows reading articles and spoofs, whatever their status.
+	ScopeSpoofsRead Scope = "spoofs:read"
+	// ScopeSpoofsWrite allows editing and regenerating spoofs, and moving them through review.
+	ScopeSpoofsWrite Scope = "spoofs:write"
+	// ScopeSpoofsDelete allows deleting spoofs.
+	ScopeSpoofsDelete Scope = "spoofs:delete"
+)
+
+// roleScopes lists the scopes that each role grants.
+var roleScopes = map[Role][]Scope{
+	RoleViewer: {ScopeSpoofsRead},
+	RoleEditor: {ScopeSpoofsRead, ScopeSpoofsWrite},
+	RoleAdmin:  {ScopeSpoofsRead, ScopeSpoofsWrite, ScopeSpoofsDelete},
+}
+
+// Scopes returns the scopes that the role grants.
+func (r Role) Scopes() []Scope {
+	return slices.Clone(roleScopes[r])
+}
+
+// Grants returns whether the role grants scope.
+func (r Role) Grants(scope Scope) bool {
+	return slices.Contains(roleScopes[r], scope)
+}
+
+// ParseScopes parses a comma separated list of scopes, like "spoofs:read,spoofs:write".
+func ParseScopes(s string) ([]Scope, error) {
+	var scopes []Scope
+	for _, name := range strings.Split(s, ",") {
+		scope := Scope(strings.TrimSpace(name))
+		if scope == "" {
+			continue
+		}
+		if !slices.Contains(roleScopes[RoleAdmin], scope) {
+			return nil, fmt.Errorf("unknown scope %q", scope)
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("no scopes given")
+	}
+	return scopes, nil
+}
+
+// User is someone who can sign in to the admin area, or use an API token.
+type User struct {
+	ID       int64
+	Username string
+	// PasswordHash is the bcrypt hash of the user's password.
+	PasswordHash string
+	Role         Role
+	Created      time.Time
+}
+
+// Session is a signed in user. The token that identifies it is only known to the user's browser;
+// we keep its hash.
+type Session struct {
+	TokenHash string
+	UserID    int64
+	// CSRFToken must be sent with every form the user posts, to prove that the form came from us.
+	CSRFToken string
+	Created   time.Time
+	Expires   time.Time
+}
+
+// APIToken lets a program use the JSON API on behalf of a user, limited to some scopes.
+// Like sessions, we only keep the hash of the token.
+type APIToken struct {
+	ID        int64
+	TokenHash string
+	UserID    int64
+	// Name says what the token is for, like "publishing script".
+	Name    string
+	Scopes  []Scope
+	Created time.Time
+	// Expires is when the token stops working. It is nil if it never does.
+	Expires *time.Time
+}
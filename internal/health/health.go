@@ -0,0 +1,189 @@
+// Package health checks whether the dependencies of the application are usable,
+// so that readiness probes can tell a live process from one that can serve requests.
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Statuses of a Report and of each of its Results.
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+)
+
+// Check returns an error if a dependency is unusable.
+// The detail is shown in the report either way, and may be empty.
+type Check func(ctx context.Context) (detail string, err error)
+
+// Result is the outcome of a single check.
+type Result struct {
+	Name     string  `json:"name"`
+	Status   string  `json:"status"`
+	Detail   string  `json:"detail,omitempty"`
+	Error    string  `json:"error,omitempty"`
+	Duration float64 `json:"duration_seconds"`
+}
+
+// Report is the outcome of every check. It is OK only if every check is.
+type Report struct {
+	Status string   `json:"status"`
+	Checks []Result `json:"checks"`
+}
+
+// OK returns whether every check passed.
+func (r Report) OK() bool {
+	return r.Status == StatusOK
+}
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Checker runs a set of checks concurrently.
+type Checker struct {
+	timeout time.Duration
+	checks  []namedCheck
+}
+
+// NewChecker creates a Checker with no checks. Each check gets at most timeout to finish.
+// A zero timeout means no deadline.
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout}
+}
+
+// Add adds a check to the Checker. Checks are reported in the order they were added.
+// It must not be called while Check is running.
+func (c *Checker) Add(name string, check Check) {
+	c.checks = append(c.checks, namedCheck{name: name, check: check})
+}
+
+// Check runs every check, and reports their outcomes.
+func (c *Checker) Check(ctx context.Context) Report {
+	report := Report{
+		Status: StatusOK,
+		Checks: make([]Result, len(c.checks)),
+	}
+
+	var wg sync.WaitGroup
+	for i, check := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Checks[i] = c.run(ctx, check)
+		}()
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.Status != StatusOK {
+			report.Status = StatusUnavailable
+		}
+	}
+	return report
+}
+
+func (c *Checker) run(ctx context.Context, check namedCheck) Result {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	detail, err := check.check(ctx)
+	result := Result{
+		Name:     check.name,
+		Status:   StatusOK,
+		Detail:   detail,
+		Duration: time.Since(start).Seconds(),
+	}
+	if err != nil {
+		result.Status = StatusUnavailable
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// Cached runs check at most once every ttl, and reuses its last outcome in between.
+// It is for checks that are too slow or costly to run on every probe.
+func Cached(check Check, ttl time.Duration) Check {
+	var (
+		mu      sync.Mutex
+		checked time.Time
+		detail  string
+		err     error
+	)
+	return func(ctx context.Context) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if checked.IsZero() || time.Since(checked) > ttl {
+			detail, err = check(ctx)
+			checked = time.Now()
+		}
+		return detail, err
+	}
+}
+
+// Pinger is a dependency that can tell whether it is reachable.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Ping checks that p is reachable.
+func Ping(p Pinger) Check {
+	return func(ctx context.Context) (string, error) {
+		return "", p.Ping(ctx)
+	}
+}
+
+// MigrationVersioner reports the version of the schema of a database.
+type MigrationVersioner interface {
+	MigrationVersion(ctx context.Context) (version uint, dirty bool, err error)
+}
+
+// Migrations checks that the schema is at least at version want, and that no migration failed halfway.
+// A newer schema is fine, because migrations are applied before the new code is rolled out.
+func Migrations(v MigrationVersioner, want uint) Check {
+	return func(ctx context.Context) (string, error) {
+		version, dirty, err := v.MigrationVersion(ctx)
+		if err != nil {
+			return "", err
+		}
+		detail := fmt.Sprintf("version %d, want %d", version, want)
+		switch {
+		case dirty:
+			return detail, fmt.Errorf("migration %d is dirty", version)
+		case version < want:
+			return detail, fmt.Errorf("schema version %d is older than %d", version, want)
+		}
+		return detail, nil
+	}
+}
+
+// LastSuccess checks that something last succeeded less than maxAge ago.
+// last returns the zero time until it first succeeds, which is fine for maxAge after the check is created,
+// so that a fresh process has time to get going.
+func LastSuccess(last func() time.Time, maxAge time.Duration) Check {
+	created := time.Now()
+	return func(ctx context.Context) (string, error) {
+		t := last()
+		if t.IsZero() {
+			if waited := time.Since(created); waited > maxAge {
+				return "never succeeded", fmt.Errorf("no success in %s", waited.Round(time.Second))
+			}
+			return "not run yet", nil
+		}
+
+		age := time.Since(t)
+		detail := fmt.Sprintf("%s ago", age.Round(time.Second))
+		if age > maxAge {
+			return detail, fmt.Errorf("last success is older than %s", maxAge)
+		}
+		return detail, nil
+	}
+}
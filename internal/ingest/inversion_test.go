@@ -0,0 +1,63 @@
+package ingest
+
+import (
+	"testing"
+
+	"github.com/glizzus/trf/internal/domain"
+)
+
+func TestInversionFor(t *testing.T) {
+	mustParse := func(name string) domain.Inversion {
+		inversion, err := domain.ParseInversion(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return inversion
+	}
+	article := func(rating domain.Rating) domain.Article {
+		return domain.Article{Claim: domain.Claim{Rating: rating}}
+	}
+
+	tests := []struct {
+		name   string
+		opts   Options
+		rating domain.Rating
+		want   string
+	}{
+		{"default", Options{}, "False", domain.DefaultInversion},
+		{"chosen", Options{Inversion: mustParse(domain.InversionMirror)}, "False", domain.InversionMirror},
+		{
+			"kind overrides",
+			Options{
+				Inversion:       mustParse(domain.InversionMirror),
+				InversionByKind: map[domain.RatingKind]domain.Inversion{domain.KindOrigin: mustParse(domain.InversionAbsurd)},
+			},
+			"Labeled Satire",
+			domain.InversionAbsurd,
+		},
+		{
+			"other kinds don't",
+			Options{
+				Inversion:       mustParse(domain.InversionMirror),
+				InversionByKind: map[domain.RatingKind]domain.Inversion{domain.KindOrigin: mustParse(domain.InversionAbsurd)},
+			},
+			"Mostly False",
+			domain.InversionMirror,
+		},
+		{
+			// Unknown ratings are undetermined, like their fallback.
+			"unknown rating",
+			Options{InversionByKind: map[domain.RatingKind]domain.Inversion{domain.KindUndetermined: mustParse(domain.InversionNeutral)}},
+			"Zombie Claim",
+			domain.InversionNeutral,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := New(nil, nil, nil, tt.opts)
+			if got := w.inversionFor(article(tt.rating)).Name(); got != tt.want {
+				t.Errorf("inversionFor(%q) = %q, want %q", tt.rating, got, tt.want)
+			}
+		})
+	}
+}
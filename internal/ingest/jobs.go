@@ -0,0 +1,223 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/glizzus/trf/internal/domain"
+	"github.com/glizzus/trf/internal/logging"
+)
+
+// jobRetention is how long jobs can be looked up for. DeleteOldJobs deletes the ones that are older.
+const jobRetention = time.Hour
+
+// errStopped is why jobs that were still queued when the worker stopped failed.
+var errStopped = errors.New("the worker stopped before it got to this job, request it again")
+
+// ErrQueueFull is returned by Request and Regenerate when too many spoofs are waiting to be made.
+var ErrQueueFull = errors.New("too many spoofs are waiting to be made, try again later")
+
+// jobs keeps track of the jobs requested from this instance, to spoof articles on demand or regenerate their spoofs.
+// The jobs themselves are saved in the repo, so that they can be looked up from any instance,
+// but each one is run by the instance it was requested from.
+type jobs struct {
+	mu sync.Mutex
+	// active maps the slugs of queued and running jobs to their ids,
+	// so that asking twice for the same article doesn't spoof it twice.
+	active map[string]string
+
+	queue chan *job
+}
+
+// job is a SpoofJob, and the inversion it was asked for.
+type job struct {
+	domain.SpoofJob
+	inversion domain.Inversion
+}
+
+func newJobs(size int) *jobs {
+	if size <= 0 {
+		size = 1
+	}
+	return &jobs{
+		active: make(map[string]string),
+		queue:  make(chan *job, size),
+	}
+}
+
+// Request queues the article with the given slug to be spoofed by Run, like SpoofArticle does,
+// and returns the job that tracks it. If inversion is nil, the options choose one.
+//
+// If the article already has a spoof, the job is done straight away. If it is already queued or being spoofed,
+// the job that is doing it is returned. Otherwise, it fails with ErrQueueFull if too many jobs are waiting.
+func (w *Worker) Request(ctx context.Context, slug string, inversion domain.Inversion) (domain.SpoofJob, error) {
+	j := newJob(slug, inversion)
+
+	// Most requests are for articles we already have, which don't need to wait in the queue.
+	existed := step(ctx, w.opts.RepoTimeout, func(ctx context.Context) error {
+		_, err := w.repo.GetSpoof(ctx, slug)
+		return err
+	}) == nil
+
+	return w.enqueue(ctx, j, existed)
+}
+
+// Regenerate queues the saved article with the given slug to be spoofed again by Run, replacing its spoof,
+// and returns the job that tracks it. The new spoof goes through review like any other, even if the old one was published.
+//
+// It returns an error wrapping repo.ErrNotFound if there is no such article. Like Request, it returns the job
+// that is already working on the article if there is one, and fails with ErrQueueFull if too many jobs are waiting.
+func (w *Worker) Regenerate(ctx context.Context, slug string) (domain.SpoofJob, error) {
+	if err := step(ctx, w.opts.RepoTimeout, func(ctx context.Context) error {
+		_, err := w.repo.GetArticle(ctx, slug)
+		return err
+	}); err != nil {
+		return domain.SpoofJob{}, fmt.Errorf("error getting article: %w", err)
+	}
+
+	j := newJob(slug, nil)
+	j.Regenerate = true
+	return w.enqueue(ctx, j, false)
+}
+
+// newJob returns a queued job for the article with the given slug.
+func newJob(slug string, inversion domain.Inversion) *job {
+	j := &job{
+		SpoofJob: domain.SpoofJob{
+			ID:      newRunID(),
+			Slug:    slug,
+			Status:  domain.JobQueued,
+			Created: time.Now(),
+		},
+		inversion: inversion,
+	}
+	if inversion != nil {
+		j.Inversion = inversion.Name()
+	}
+	return j
+}
+
+// enqueue saves j and queues it for Run, unless a job is already working on its article, which is returned instead.
+// If existed is true, the article already has a spoof, and j is saved as done without being queued.
+func (w *Worker) enqueue(ctx context.Context, j *job, existed bool) (domain.SpoofJob, error) {
+	// The lock is held until the job is saved and queued, so that Run never gets a job that isn't saved yet.
+	w.jobs.mu.Lock()
+	defer w.jobs.mu.Unlock()
+
+	if id, ok := w.jobs.active[j.Slug]; ok {
+		return w.Job(ctx, id)
+	}
+	if existed {
+		j.Status = domain.JobDone
+		j.Existed = true
+		finished := j.Created
+		j.Finished = &finished
+	} else if len(w.jobs.queue) == cap(w.jobs.queue) {
+		// Only enqueue sends to the queue, and only with the lock held, so the send below can't block.
+		return domain.SpoofJob{}, ErrQueueFull
+	}
+
+	if err := step(ctx, w.opts.RepoTimeout, func(ctx context.Context) error {
+		return w.repo.SaveJob(ctx, j.SpoofJob)
+	}); err != nil {
+		return domain.SpoofJob{}, fmt.Errorf("error saving job: %w", err)
+	}
+	if existed {
+		return j.SpoofJob, nil
+	}
+
+	w.jobs.queue <- j
+	w.jobs.active[j.Slug] = j.ID
+	slog.InfoContext(ctx, "queued spoof", "job_id", j.ID, "slug", j.Slug, "regenerate", j.Regenerate)
+
+	return j.SpoofJob, nil
+}
+
+// Job returns the job with the given id, whichever instance it was requested from.
+// It returns an error wrapping repo.ErrNotFound if there is no such job, or if it was deleted.
+func (w *Worker) Job(ctx context.Context, id string) (job domain.SpoofJob, err error) {
+	err = step(ctx, w.opts.RepoTimeout, func(ctx context.Context) (err error) {
+		job, err = w.repo.GetJob(ctx, id)
+		return err
+	})
+	return job, err
+}
+
+// DeleteOldJobs deletes the jobs that were requested more than jobRetention ago, from every instance.
+func (w *Worker) DeleteOldJobs(ctx context.Context) error {
+	var n int64
+	if err := step(ctx, w.opts.RepoTimeout, func(ctx context.Context) (err error) {
+		n, err = w.repo.DeleteJobsBefore(ctx, time.Now().Add(-jobRetention))
+		return err
+	}); err != nil {
+		return fmt.Errorf("error deleting old jobs: %w", err)
+	}
+	slog.InfoContext(ctx, "deleted old jobs", "count", n)
+	return nil
+}
+
+// updateJob saves how far along j is. Failing to is logged, because the spoof matters more than its job.
+func (w *Worker) updateJob(ctx context.Context, j *job) {
+	if err := step(ctx, w.opts.RepoTimeout, func(ctx context.Context) error {
+		return w.repo.UpdateJob(ctx, j.SpoofJob)
+	}); err != nil {
+		slog.ErrorContext(ctx, "failed to update job", "status", j.Status, "error", err)
+	}
+}
+
+// runJob spoofs or regenerates the article of j, and records how it went.
+func (w *Worker) runJob(ctx context.Context, j *job) {
+	ctx = logging.With(ctx, "job_id", j.ID)
+	j.Status = domain.JobRunning
+	w.updateJob(ctx, j)
+
+	runCtx, cancel := withTimeout(ctx, w.opts.RunTimeout)
+	var existed bool
+	var err error
+	if j.Regenerate {
+		err = w.regenerate(runCtx, j.Slug)
+	} else {
+		existed, err = w.SpoofArticle(runCtx, j.Slug, j.inversion)
+	}
+	cancel()
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to spoof article on demand", "slug", j.Slug, "regenerate", j.Regenerate, "error", err)
+	}
+
+	w.finishJob(ctx, j, existed, err)
+}
+
+// finishJob records that j is done, or failed with err, and lets the article be requested again.
+func (w *Worker) finishJob(ctx context.Context, j *job, existed bool, err error) {
+	now := time.Now()
+	j.Finished = &now
+	j.Existed = existed
+	j.Status = domain.JobDone
+	if err != nil {
+		j.Status = domain.JobFailed
+		j.Error = err.Error()
+	}
+	// The job is over even if the run was cancelled, and that must still be saved.
+	w.updateJob(context.WithoutCancel(ctx), j)
+
+	w.jobs.mu.Lock()
+	defer w.jobs.mu.Unlock()
+	delete(w.jobs.active, j.Slug)
+}
+
+// failQueuedJobs fails the jobs that are still queued when Run returns,
+// so that whoever polls them learns that they won't run rather than waiting forever.
+func (w *Worker) failQueuedJobs(ctx context.Context) {
+	for {
+		select {
+		case j := <-w.jobs.queue:
+			w.finishJob(logging.With(ctx, "job_id", j.ID), j, false, errStopped)
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,632 @@
+// Package ingest scrapes new fact checks from Snopes, spoofs them, and saves both.
+package ingest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/glizzus/trf/internal/domain"
+	"github.com/glizzus/trf/internal/logging"
+	"github.com/glizzus/trf/internal/metrics"
+	"github.com/glizzus/trf/internal/repo"
+	"github.com/glizzus/trf/internal/scraping"
+	"github.com/glizzus/trf/internal/spoofing"
+	"github.com/glizzus/trf/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Options configure how long each part of ingesting may take, and what is made of each article.
+// A zero timeout means no deadline.
+type Options struct {
+	// RunTimeout bounds a whole run: listing, scraping, spoofing and saving every new article.
+	// It bounds each recheck and each spoof requested on demand too.
+	RunTimeout time.Duration
+
+	// ScrapeTimeout bounds each request to Snopes.
+	ScrapeTimeout time.Duration
+
+	// SpoofTimeout bounds each call to the Spoofer.
+	SpoofTimeout time.Duration
+
+	// RepoTimeout bounds each call to the Repo.
+	RepoTimeout time.Duration
+
+	// RecheckWindow is how far back, by publish date, we look for articles to check for revisions.
+	// Snopes revises articles while a story develops, which is usually soon after publishing.
+	// Zero disables rechecking.
+	RecheckWindow time.Duration
+
+	// RecheckLimit is the most articles we recheck in a run. The least recently checked go first,
+	// so every article in the window is eventually rechecked.
+	RecheckLimit int
+
+	// RespoofOnRatingChange makes us spoof an article again when Snopes changes its rating.
+	// Otherwise, the spoof is flagged as stale so that an editor can decide what to do.
+	RespoofOnRatingChange bool
+
+	// Inversion chooses the rating of each spoof. If it is nil, domain.DefaultInversion is used.
+	Inversion domain.Inversion
+
+	// InversionByKind overrides Inversion for articles whose rating is of the given kind,
+	// so that, say, satire can be inverted differently from everything else.
+	InversionByKind map[domain.RatingKind]domain.Inversion
+
+	// AutoPublish publishes spoofs as soon as they are made, instead of leaving them for an editor to review.
+	AutoPublish bool
+
+	// MaxQueuedJobs is the most spoofs requested on demand that can wait to be made.
+	// Requests beyond it fail with ErrQueueFull.
+	MaxQueuedJobs int
+}
+
+// Worker scrapes the latest fact checks from Snopes, and spoofs the ones that we haven't seen before.
+// A scheduler calls Ingest and Recheck, and Run spoofs the articles requested on demand.
+type Worker struct {
+	scraper scraping.Scraper
+	repo    repo.Repo
+	spoofer spoofing.Spoofer
+	opts    Options
+
+	// stopping is closed by Shutdown to ask the worker to stop after the current article.
+	stopping chan struct{}
+	stopOnce sync.Once
+
+	// done is closed when Run returns.
+	done chan struct{}
+
+	mu sync.Mutex
+	// cancel aborts the work in progress. It is nil until Run is called.
+	cancel context.CancelFunc
+
+	// busy is held while the worker scrapes and spoofs, so that ingest, rechecks and jobs take turns.
+	// Spoofing a few articles at once wouldn't be faster. See spoofWith.
+	busy sync.Mutex
+
+	// lastSuccess is when the last successful ingest run finished, in Unix nanoseconds.
+	lastSuccess atomic.Int64
+
+	// jobs are the spoofs requested on demand. Run works through the queue.
+	jobs *jobs
+}
+
+// New creates a Worker.
+func New(scraper scraping.Scraper, repo repo.Repo, spoofer spoofing.Spoofer, opts Options) *Worker {
+	return &Worker{
+		scraper:  scraper,
+		repo:     repo,
+		spoofer:  spoofer,
+		opts:     opts,
+		stopping: make(chan struct{}),
+		done:     make(chan struct{}),
+		jobs:     newJobs(opts.MaxQueuedJobs),
+	}
+}
+
+// Run spoofs the articles requested with Request, one at a time.
+// It blocks until Shutdown is called or ctx is done, and must only be called once.
+//
+// Cancelling ctx aborts the article in progress. Use Shutdown to let it finish instead.
+func (w *Worker) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	w.mu.Lock()
+	w.cancel = cancel
+	w.mu.Unlock()
+	defer close(w.done)
+	defer w.failQueuedJobs(ctx)
+
+	for {
+		select {
+		case <-w.stopping:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		case j := <-w.jobs.queue:
+			// A job and a stop can be ready at the same time, and the stop wins.
+			if w.isStopping() {
+				w.finishJob(logging.With(ctx, "job_id", j.ID), j, false, errStopped)
+				return nil
+			}
+			w.busy.Lock()
+			w.runJob(ctx, j)
+			w.busy.Unlock()
+		}
+	}
+}
+
+// Shutdown asks the worker to stop once it finishes the article it is working on,
+// and waits for Run to return. Ingest and Recheck stop before their next article too,
+// but their callers wait for them.
+//
+// If ctx is done before then, the article in progress is cancelled
+// and Shutdown returns ctx.Err() without waiting any further.
+func (w *Worker) Shutdown(ctx context.Context) error {
+	w.stopOnce.Do(func() { close(w.stopping) })
+
+	w.mu.Lock()
+	cancel := w.cancel
+	w.mu.Unlock()
+	// Run was never called, so there is nothing to wait for.
+	if cancel == nil {
+		return nil
+	}
+
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		cancel()
+		return ctx.Err()
+	}
+}
+
+// LastSuccess returns when the last successful ingest run finished,
+// or the zero time if none has yet.
+func (w *Worker) LastSuccess() time.Time {
+	nanos := w.lastSuccess.Load()
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos)
+}
+
+func (w *Worker) isStopping() bool {
+	select {
+	case <-w.stopping:
+		return true
+	default:
+		return false
+	}
+}
+
+// withTimeout is like context.WithTimeout, except that a zero timeout means no deadline.
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// step runs fn with a context that expires after timeout.
+func step(ctx context.Context, timeout time.Duration, fn func(context.Context) error) error {
+	ctx, cancel := withTimeout(ctx, timeout)
+	defer cancel()
+	return fn(ctx)
+}
+
+func newRunID() string {
+	b := make([]byte, 8)
+	// crypto/rand.Read never returns an error on supported platforms.
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// startRun waits for the worker to be free, and starts a run with the given span name.
+// Each run is a trace of its own, and everything logged during it has the same run_id,
+// so the run id is all we need to find it. The returned function ends the run.
+func (w *Worker) startRun(ctx context.Context, name string) (context.Context, trace.Span, func()) {
+	w.busy.Lock()
+	runID := newRunID()
+	ctx = logging.With(ctx, "run_id", runID)
+	ctx, cancel := withTimeout(ctx, w.opts.RunTimeout)
+	ctx, span := tracing.Start(ctx, name,
+		trace.WithNewRoot(),
+		trace.WithAttributes(attribute.String("run_id", runID)),
+	)
+	return ctx, span, func() {
+		cancel()
+		w.busy.Unlock()
+	}
+}
+
+// Ingest scrapes the latest fact checks, and spoofs and saves the ones we haven't seen before.
+// It returns an error unless every new article was saved.
+func (w *Worker) Ingest(ctx context.Context) (err error) {
+	if w.isStopping() {
+		return nil
+	}
+	ctx, span, end := w.startRun(ctx, "ingest.run")
+	defer end()
+	defer func() { tracing.End(span, err) }()
+
+	err = w.ingest(ctx)
+	metrics.IngestRuns.WithLabelValues(metrics.Outcome(err)).Inc()
+	if err == nil {
+		metrics.LastSuccessfulIngest.SetToCurrentTime()
+		w.lastSuccess.Store(time.Now().UnixNano())
+	}
+
+	if reporter, ok := w.scraper.(healthReporter); ok {
+		reporter.Health().LogReport(ctx)
+	}
+	return err
+}
+
+// Recheck scrapes recently published articles again, to pick up any revisions Snopes made.
+// It returns an error unless every article was rechecked.
+func (w *Worker) Recheck(ctx context.Context) (err error) {
+	if w.isStopping() || w.opts.RecheckWindow <= 0 || w.opts.RecheckLimit <= 0 {
+		return nil
+	}
+	ctx, span, end := w.startRun(ctx, "ingest.recheck")
+	defer end()
+	defer func() { tracing.End(span, err) }()
+
+	return w.recheck(ctx)
+}
+
+// healthReporter is implemented by scrapers that track how well their selectors match.
+type healthReporter interface {
+	Health() *scraping.Health
+}
+
+// ingest scrapes the latest fact checks, and spoofs the new ones one at a time.
+// It checks between articles whether it should stop.
+//
+// It returns an error unless every new article was saved, so that a run that
+// quietly fails on every article doesn't count as a success.
+func (w *Worker) ingest(ctx context.Context) error {
+	slog.InfoContext(ctx, "scraping latest fact checks")
+	var slugs []string
+	if err := step(ctx, w.opts.ScrapeTimeout, func(ctx context.Context) (err error) {
+		slugs, err = w.scraper.LatestFactChecks(ctx)
+		return err
+	}); err != nil {
+		return fmt.Errorf("error scraping latest fact checks: %w", err)
+	}
+	metrics.ArticlesDiscovered.Add(float64(len(slugs)))
+
+	var newSlugs []string
+	if err := step(ctx, w.opts.RepoTimeout, func(ctx context.Context) (err error) {
+		newSlugs, err = w.repo.GetAllNotExistingSpoofSlugs(ctx, slugs)
+		return err
+	}); err != nil {
+		return fmt.Errorf("error getting all not existing spoof slugs: %w", err)
+	}
+	metrics.ArticlesNew.Add(float64(len(newSlugs)))
+
+	// We don't need to return early logic-wise, but it helps us log more confidently
+	// if we know we have new slugs later.
+	if len(newSlugs) == 0 {
+		slog.InfoContext(ctx, "no new articles to scrape")
+		return nil
+	}
+	slog.InfoContext(ctx, "found new articles to scrape", "count", len(newSlugs))
+
+	var failed int
+	for i, slug := range newSlugs {
+		if w.isStopping() || ctx.Err() != nil {
+			slog.InfoContext(ctx, "stopping before scraping remaining articles",
+				"remaining", len(newSlugs)-i,
+				"error", ctx.Err(),
+			)
+			return fmt.Errorf("stopped with %d articles remaining", len(newSlugs)-i)
+		}
+
+		err := w.ingestArticle(ctx, slug)
+		metrics.ArticlesIngested.WithLabelValues(metrics.Outcome(err)).Inc()
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to ingest article", "slug", slug, "error", err)
+			failed++
+			continue
+		}
+		slog.InfoContext(ctx, "scraped and saved article", "slug", slug)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to ingest %d of %d new articles", failed, len(newSlugs))
+	}
+	return nil
+}
+
+// recheck scrapes the least recently checked of the recent articles again, one at a time.
+// It checks between articles whether it should stop.
+func (w *Worker) recheck(ctx context.Context) error {
+	since := time.Now().Add(-w.opts.RecheckWindow)
+	var slugs []string
+	if err := step(ctx, w.opts.RepoTimeout, func(ctx context.Context) (err error) {
+		slugs, err = w.repo.GetArticleSlugsToRecheck(ctx, since, w.opts.RecheckLimit)
+		return err
+	}); err != nil {
+		return fmt.Errorf("error getting articles to recheck: %w", err)
+	}
+	slog.InfoContext(ctx, "rechecking articles for revisions", "count", len(slugs))
+
+	var failed int
+	for i, slug := range slugs {
+		if w.isStopping() || ctx.Err() != nil {
+			slog.InfoContext(ctx, "stopping before rechecking remaining articles",
+				"remaining", len(slugs)-i,
+				"error", ctx.Err(),
+			)
+			return fmt.Errorf("stopped with %d articles remaining", len(slugs)-i)
+		}
+
+		if err := w.recheckArticle(ctx, slug); err != nil {
+			slog.ErrorContext(ctx, "failed to recheck article", "slug", slug, "error", err)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to recheck %d of %d articles", failed, len(slugs))
+	}
+	return nil
+}
+
+func (w *Worker) recheckArticle(ctx context.Context, slug string) (err error) {
+	ctx, span := tracing.Start(ctx, "ingest.recheck_article", trace.WithAttributes(attribute.String("slug", slug)))
+	defer func() { tracing.End(span, err) }()
+
+	var existing domain.Article
+	if err := step(ctx, w.opts.RepoTimeout, func(ctx context.Context) (err error) {
+		existing, err = w.repo.GetArticle(ctx, slug)
+		return err
+	}); err != nil {
+		return fmt.Errorf("error getting article: %w", err)
+	}
+
+	var article domain.Article
+	if err := step(ctx, w.opts.ScrapeTimeout, func(ctx context.Context) (err error) {
+		article, err = w.scraper.ScrapeArticle(ctx, slug)
+		return err
+	}); err != nil {
+		return fmt.Errorf("error scraping article: %w", err)
+	}
+
+	if article.Hash() == existing.Hash() {
+		return step(ctx, w.opts.RepoTimeout, func(ctx context.Context) error {
+			return w.repo.MarkArticleChecked(ctx, slug)
+		})
+	}
+
+	if err := step(ctx, w.opts.RepoTimeout, func(ctx context.Context) error {
+		return w.repo.UpdateArticle(ctx, article)
+	}); err != nil {
+		return fmt.Errorf("error updating article: %w", err)
+	}
+
+	if article.Claim.Rating == existing.Claim.Rating {
+		slog.InfoContext(ctx, "saved revision of article", "slug", slug)
+		return nil
+	}
+
+	slog.InfoContext(ctx, "article changed its rating",
+		"slug", slug,
+		"old_rating", existing.Claim.Rating,
+		"new_rating", article.Claim.Rating,
+	)
+
+	if !w.opts.RespoofOnRatingChange {
+		err := step(ctx, w.opts.RepoTimeout, func(ctx context.Context) error {
+			return w.repo.MarkSpoofStale(ctx, slug)
+		})
+		// If the spoof was deleted, or spoofing failed back when articles were saved first, there is nothing to flag.
+		if err != nil && !errors.Is(err, repo.ErrNotFound) {
+			return fmt.Errorf("error marking spoof as stale: %w", err)
+		}
+		return nil
+	}
+
+	spoof, err := w.spoof(ctx, article)
+	if err != nil {
+		return err
+	}
+	if err := w.replaceSpoof(ctx, spoof); err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "respoofed article", "slug", slug)
+
+	return nil
+}
+
+// regenerate spoofs the saved article with the given slug again, and replaces its spoof.
+// The new spoof goes through review like any other, even if the old one was published.
+// It returns an error wrapping repo.ErrNotFound if there is no such article.
+func (w *Worker) regenerate(ctx context.Context, slug string) (err error) {
+	ctx, span := tracing.Start(ctx, "ingest.regenerate", trace.WithAttributes(attribute.String("slug", slug)))
+	defer func() { tracing.End(span, err) }()
+
+	var article domain.Article
+	if err := step(ctx, w.opts.RepoTimeout, func(ctx context.Context) (err error) {
+		article, err = w.repo.GetArticle(ctx, slug)
+		return err
+	}); err != nil {
+		return fmt.Errorf("error getting article: %w", err)
+	}
+
+	spoof, err := w.spoof(ctx, article)
+	if err != nil {
+		return err
+	}
+	if err := w.replaceSpoof(ctx, spoof); err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "regenerated spoof", "slug", slug)
+
+	return nil
+}
+
+// SpoofArticle scrapes the article with the given slug, spoofs it and saves both,
+// for articles that never showed up in the listing of the latest fact checks.
+// If inversion is nil, the options choose one like for any other article.
+//
+// Articles that were saved before aren't scraped again, and articles that already have a spoof are left alone,
+// in which case existed is true. Use Regenerate to replace a spoof.
+func (w *Worker) SpoofArticle(ctx context.Context, slug string, inversion domain.Inversion) (existed bool, err error) {
+	ctx, span := tracing.Start(ctx, "ingest.spoof_article", trace.WithAttributes(attribute.String("slug", slug)))
+	defer func() { tracing.End(span, err) }()
+
+	err = step(ctx, w.opts.RepoTimeout, func(ctx context.Context) error {
+		_, err := w.repo.GetSpoof(ctx, slug)
+		return err
+	})
+	switch {
+	case err == nil:
+		return true, nil
+	case !errors.Is(err, repo.ErrNotFound):
+		return false, fmt.Errorf("error getting spoof: %w", err)
+	}
+
+	var article domain.Article
+	err = step(ctx, w.opts.RepoTimeout, func(ctx context.Context) (err error) {
+		article, err = w.repo.GetArticle(ctx, slug)
+		return err
+	})
+	// We have never seen the article if it isn't saved, or spoofing it failed before we saved both together.
+	saved := err == nil
+	switch {
+	case errors.Is(err, repo.ErrNotFound):
+		if err := step(ctx, w.opts.ScrapeTimeout, func(ctx context.Context) (err error) {
+			article, err = w.scraper.ScrapeArticle(ctx, slug)
+			return err
+		}); err != nil {
+			return false, fmt.Errorf("error scraping article: %w", err)
+		}
+	case err != nil:
+		return false, fmt.Errorf("error getting article: %w", err)
+	}
+
+	if inversion == nil {
+		inversion = w.inversionFor(article)
+	}
+	spoof, err := w.spoofWith(ctx, article, inversion)
+	if err != nil {
+		return false, err
+	}
+
+	err = step(ctx, w.opts.RepoTimeout, func(ctx context.Context) error {
+		return w.repo.WithTx(ctx, func(tx repo.Repo) error {
+			// A conflict means that an ingest run saved another version in the meantime, which is fine.
+			if !saved {
+				if err := tx.SaveArticle(ctx, article); err != nil && !errors.Is(err, repo.ErrConflict) {
+					return fmt.Errorf("error saving article: %w", err)
+				}
+			}
+			if err := tx.SaveSpoof(ctx, spoof); err != nil {
+				return fmt.Errorf("error saving spoof: %w", err)
+			}
+			return nil
+		})
+	})
+	switch {
+	case errors.Is(err, repo.ErrConflict):
+		// An ingest run spoofed it while we were, and its spoof is as good as ours.
+		return true, nil
+	case err != nil:
+		return false, err
+	}
+	slog.InfoContext(ctx, "spoofed article on demand", "slug", slug, "inversion", inversion.Name())
+
+	return false, nil
+}
+
+// replaceSpoof saves spoof over the existing spoof of its article, if there is one.
+func (w *Worker) replaceSpoof(ctx context.Context, spoof domain.Spoof) error {
+	if err := step(ctx, w.opts.RepoTimeout, func(ctx context.Context) error {
+		err := w.repo.UpdateSpoof(ctx, spoof)
+		if errors.Is(err, repo.ErrNotFound) {
+			return w.repo.SaveSpoof(ctx, spoof)
+		}
+		return err
+	}); err != nil {
+		return fmt.Errorf("error updating spoof: %w", err)
+	}
+	return nil
+}
+
+func (w *Worker) ingestArticle(ctx context.Context, slug string) (err error) {
+	ctx, span := tracing.Start(ctx, "ingest.article", trace.WithAttributes(attribute.String("slug", slug)))
+	defer func() { tracing.End(span, err) }()
+
+	var article domain.Article
+	if err := step(ctx, w.opts.ScrapeTimeout, func(ctx context.Context) (err error) {
+		article, err = w.scraper.ScrapeArticle(ctx, slug)
+		return err
+	}); err != nil {
+		return fmt.Errorf("error scraping article: %w", err)
+	}
+
+	spoof, err := w.spoof(ctx, article)
+	if err != nil {
+		return err
+	}
+
+	// The article and its spoof are saved together, or not at all. An article saved without its spoof
+	// would never be ingested again, while one saved with neither is retried by the next run.
+	return step(ctx, w.opts.RepoTimeout, func(ctx context.Context) error {
+		return w.repo.WithTx(ctx, func(tx repo.Repo) error {
+			if err := tx.SaveArticle(ctx, article); err != nil {
+				return fmt.Errorf("error saving article: %w", err)
+			}
+			if err := tx.SaveSpoof(ctx, spoof); err != nil {
+				return fmt.Errorf("error saving spoof: %w", err)
+			}
+			return nil
+		})
+	})
+}
+
+// inversionFor returns the inversion that chooses the rating of the article's spoof.
+func (w *Worker) inversionFor(article domain.Article) domain.Inversion {
+	if inversion, ok := w.opts.InversionByKind[article.Claim.Rating.Info().Kind]; ok {
+		return inversion
+	}
+	if w.opts.Inversion != nil {
+		return w.opts.Inversion
+	}
+	inversion, _ := domain.ParseInversion(domain.DefaultInversion)
+	return inversion
+}
+
+// spoof generates a spoof of the article, with the inversion that the options choose for it.
+func (w *Worker) spoof(ctx context.Context, article domain.Article) (domain.Spoof, error) {
+	return w.spoofWith(ctx, article, w.inversionFor(article))
+}
+
+// spoofWith generates a spoof of the article, with the given inversion.
+func (w *Worker) spoofWith(ctx context.Context, article domain.Article, inversion domain.Inversion) (domain.Spoof, error) {
+	target := inversion.Invert(article.Claim.Rating)
+	slog.DebugContext(ctx, "inverted rating",
+		"slug", article.Slug,
+		"rating", article.Claim.Rating,
+		"target", target,
+		"inversion", inversion.Name(),
+	)
+
+	// If we are spoofing with a real LLM, this will be slow.
+	// Concurrency is not the answer here, because either:
+	//
+	// 1. If we are using OpenAI, we will be rate limited.
+	// 2. If we are using our own LLM, we will get lower quality spoofs due to resource constraints.
+	content := strings.Join(article.Content, "\n")
+	var spoofContent string
+	if err := step(ctx, w.opts.SpoofTimeout, func(ctx context.Context) (err error) {
+		spoofContent, err = w.spoofer.Spoof(ctx, spoofing.Request{
+			Content:   content,
+			Rating:    article.Claim.Rating.String(),
+			Target:    target.String(),
+			Inversion: inversion.Description(),
+		})
+		return err
+	}); err != nil {
+		return domain.Spoof{}, fmt.Errorf("error spoofing article: %w", err)
+	}
+
+	spoofContentSplit := strings.Split(spoofContent, "\n")
+	spoof := article.ToSpoof(spoofContentSplit, target, inversion)
+	if w.opts.AutoPublish {
+		spoof.Status = domain.SpoofPublished
+	}
+	return spoof, nil
+}
@@ -0,0 +1,70 @@
+// Package logging contains helpers for structured logging with log/slog.
+package logging
+
+import (
+	"context"
+	"log/slog"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+type attrsKey struct{}
+
+// With returns a copy of ctx that carries the given attributes.
+// The arguments are interpreted like those of slog.Logger.With.
+//
+// Records logged with the returned context through a ContextHandler include the attributes,
+// so that everything logged on behalf of, say, one ingest run can be correlated.
+func With(ctx context.Context, args ...any) context.Context {
+	existing, _ := ctx.Value(attrsKey{}).([]slog.Attr)
+	attrs := make([]slog.Attr, len(existing), len(existing)+len(args))
+	copy(attrs, existing)
+
+	// slog.Record.Add does the same argument parsing as slog.Logger.With,
+	// so we borrow it rather than reimplementing it.
+	var r slog.Record
+	r.Add(args...)
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+
+	return context.WithValue(ctx, attrsKey{}, attrs)
+}
+
+// ContextHandler is a slog.Handler that adds the attributes carried by a context
+// (see With) to every record logged with that context.
+// If the context belongs to a trace, the record also gets the trace and span ids,
+// so that logs can be found from traces and the other way around.
+type ContextHandler struct {
+	slog.Handler
+}
+
+// NewContextHandler wraps h in a ContextHandler.
+func NewContextHandler(h slog.Handler) *ContextHandler {
+	return &ContextHandler{Handler: h}
+}
+
+// Handle implements slog.Handler.
+func (h *ContextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if attrs, ok := ctx.Value(attrsKey{}).([]slog.Attr); ok {
+		r.AddAttrs(attrs...)
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(
+			slog.String("trace_id", sc.TraceID().String()),
+			slog.String("span_id", sc.SpanID().String()),
+		)
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+// WithAttrs implements slog.Handler.
+func (h *ContextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &ContextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup implements slog.Handler.
+func (h *ContextHandler) WithGroup(name string) slog.Handler {
+	return &ContextHandler{Handler: h.Handler.WithGroup(name)}
+}
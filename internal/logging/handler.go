@@ -0,0 +1,43 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// The formats that NewHandler understands.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Options configures the handler made by NewHandler.
+type Options struct {
+	// Level is the lowest level that is logged: "debug", "info", "warn" or "error".
+	Level string
+	// Format is FormatText or FormatJSON.
+	Format string
+}
+
+// NewHandler returns the handler that everything should be logged through.
+// It writes records to w in the format of opts, and is a ContextHandler.
+func NewHandler(w io.Writer, opts Options) (slog.Handler, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(opts.Level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q: %w", opts.Level, err)
+	}
+
+	handlerOpts := &slog.HandlerOptions{Level: level}
+	var h slog.Handler
+	switch strings.ToLower(opts.Format) {
+	case FormatText:
+		h = slog.NewTextHandler(w, handlerOpts)
+	case FormatJSON:
+		h = slog.NewJSONHandler(w, handlerOpts)
+	default:
+		return nil, fmt.Errorf("unknown log format %q, expected %s or %s", opts.Format, FormatText, FormatJSON)
+	}
+	return NewContextHandler(h), nil
+}
@@ -0,0 +1,34 @@
+package logging
+
+import "log/slog"
+
+// redacted replaces the value of a Secret wherever it is printed.
+const redacted = "[REDACTED]"
+
+// Secret is a string, like a password or an API key, that must never end up in the logs.
+// It is redacted when it is logged, formatted or marshaled, so a config holding one can be logged whole.
+// Convert it to a string to use its value.
+type Secret string
+
+// String implements fmt.Stringer.
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+// GoString implements fmt.GoStringer, so that %#v doesn't reveal it either.
+func (s Secret) GoString() string {
+	return `"` + s.String() + `"`
+}
+
+// LogValue implements slog.LogValuer.
+func (s Secret) LogValue() slog.Value {
+	return slog.StringValue(s.String())
+}
+
+// MarshalText implements encoding.TextMarshaler, which covers JSON.
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
@@ -0,0 +1,151 @@
+// Package metrics defines the Prometheus metrics of the application, and serves them.
+//
+// The metrics are registered with the default registry when the package is loaded,
+// so the packages that record them only need to import this one.
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "trf"
+
+// Outcomes used as the value of "outcome" labels.
+const (
+	OutcomeSuccess = "success"
+	OutcomeError   = "error"
+)
+
+// Outcome returns the outcome label for err.
+func Outcome(err error) string {
+	if err != nil {
+		return OutcomeError
+	}
+	return OutcomeSuccess
+}
+
+var (
+	// ScrapeDuration is how long each stage of scraping a page from Snopes took.
+	// The page is "listing" or "article", and the stage is "fetch" or "parse".
+	ScrapeDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "scrape",
+		Name:      "duration_seconds",
+		Help:      "How long each stage of scraping a Snopes page took.",
+		Buckets:   []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"page", "stage", "outcome"})
+
+	// IngestRuns counts ingest runs by outcome. A run succeeds if every new article was saved.
+	IngestRuns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "ingest",
+		Name:      "runs_total",
+		Help:      "Ingest runs, by outcome. A run succeeds if every new article was saved.",
+	}, []string{"outcome"})
+
+	// ArticlesDiscovered counts the articles found in the listing of the latest fact checks.
+	ArticlesDiscovered = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "ingest",
+		Name:      "articles_discovered_total",
+		Help:      "Articles found in the listing of the latest fact checks, new or not.",
+	})
+
+	// ArticlesNew counts the discovered articles that we hadn't seen before.
+	ArticlesNew = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "ingest",
+		Name:      "articles_new_total",
+		Help:      "Discovered articles that we hadn't seen before.",
+	})
+
+	// ArticlesIngested counts new articles by whether they were scraped, spoofed and saved.
+	ArticlesIngested = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "ingest",
+		Name:      "articles_ingested_total",
+		Help:      "New articles, by whether they were scraped, spoofed and saved.",
+	}, []string{"outcome"})
+
+	// LastSuccessfulIngest is when the last successful ingest run finished, as a Unix timestamp.
+	LastSuccessfulIngest = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "ingest",
+		Name:      "last_success_timestamp_seconds",
+		Help:      "When the last successful ingest run finished, as a Unix timestamp.",
+	})
+
+	// ScheduledRuns counts the runs of each scheduled job by outcome, on the instance that ran them.
+	ScheduledRuns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "schedule",
+		Name:      "runs_total",
+		Help:      "Runs of each scheduled job, by outcome.",
+	}, []string{"job", "outcome"})
+
+	// ScheduleLeader is 1 if this instance is the one that runs scheduled jobs, and 0 otherwise.
+	ScheduleLeader = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "schedule",
+		Name:      "leader",
+		Help:      "Whether this instance is the one that runs scheduled jobs.",
+	})
+
+	// SpoofDuration is how long each call to the spoofer took.
+	SpoofDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "spoof",
+		Name:      "duration_seconds",
+		Help:      "How long each call to the spoofer took.",
+		Buckets:   []float64{0.1, 0.5, 1, 2.5, 5, 10, 20, 30, 60, 120, 180},
+	}, []string{"model", "outcome"})
+
+	// SpoofTokens counts the tokens used by the spoofer. The type is "prompt" or "completion".
+	SpoofTokens = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "spoof",
+		Name:      "tokens_total",
+		Help:      "Tokens used by the spoofer, by model and type.",
+	}, []string{"model", "type"})
+
+	// RepoQueryDuration is how long each call to the Repo took.
+	RepoQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "repo",
+		Name:      "query_duration_seconds",
+		Help:      "How long each call to the repo took, by method.",
+		Buckets:   []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5},
+	}, []string{"method", "outcome"})
+
+	// HTTPRequestDuration is how long each HTTP request took. Its count is the number of requests.
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "How long each HTTP request took, by route and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// HTTPRequests counts HTTP requests.
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "HTTP requests, by route and status.",
+	}, []string{"method", "route", "status"})
+)
+
+// Since returns the seconds elapsed since start, for observing durations.
+func Since(start time.Time) float64 {
+	return time.Since(start).Seconds()
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
@@ -0,0 +1,11 @@
+package repo
+
+import "errors"
+
+var (
+	// ErrNotFound is returned when the requested article or spoof does not exist.
+	ErrNotFound = errors.New("not found")
+
+	// ErrConflict is returned when saving an article or spoof that already exists.
+	ErrConflict = errors.New("already exists")
+)
@@ -0,0 +1,174 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/glizzus/trf/internal/domain"
+	"github.com/glizzus/trf/internal/metrics"
+	"github.com/glizzus/trf/internal/tracing"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentedRepo traces each call to the Repo it wraps, and records how long it took.
+type instrumentedRepo struct {
+	next Repo
+}
+
+// Instrument wraps r so that every call gets a span, and its latency is recorded in metrics.RepoQueryDuration.
+func Instrument(r Repo) Repo {
+	return &instrumentedRepo{next: r}
+}
+
+// start starts a span for a call to method, and returns a function that ends it
+// and records the call's latency.
+// Not found and conflicts are answers rather than failures, so they count as successes.
+func start(ctx context.Context, method string) (context.Context, func(err error)) {
+	ctx, span := tracing.Start(ctx, "Repo."+method, trace.WithSpanKind(trace.SpanKindClient))
+	start := time.Now()
+	return ctx, func(err error) {
+		if errors.Is(err, ErrNotFound) || errors.Is(err, ErrConflict) {
+			err = nil
+		}
+		metrics.RepoQueryDuration.WithLabelValues(method, metrics.Outcome(err)).Observe(metrics.Since(start))
+		tracing.End(span, err)
+	}
+}
+
+// WithTx traces the whole transaction, and the calls made in it like any other.
+func (r *instrumentedRepo) WithTx(ctx context.Context, fn func(Repo) error) (err error) {
+	ctx, done := start(ctx, "WithTx")
+	defer func() { done(err) }()
+	return r.next.WithTx(ctx, func(tx Repo) error {
+		return fn(&instrumentedRepo{next: tx})
+	})
+}
+
+func (r *instrumentedRepo) SaveArticle(ctx context.Context, article domain.Article) (err error) {
+	ctx, done := start(ctx, "SaveArticle")
+	defer func() { done(err) }()
+	return r.next.SaveArticle(ctx, article)
+}
+
+func (r *instrumentedRepo) GetArticle(ctx context.Context, slug string) (_ domain.Article, err error) {
+	ctx, done := start(ctx, "GetArticle")
+	defer func() { done(err) }()
+	return r.next.GetArticle(ctx, slug)
+}
+
+func (r *instrumentedRepo) UpdateArticle(ctx context.Context, article domain.Article) (err error) {
+	ctx, done := start(ctx, "UpdateArticle")
+	defer func() { done(err) }()
+	return r.next.UpdateArticle(ctx, article)
+}
+
+func (r *instrumentedRepo) MarkArticleChecked(ctx context.Context, slug string) (err error) {
+	ctx, done := start(ctx, "MarkArticleChecked")
+	defer func() { done(err) }()
+	return r.next.MarkArticleChecked(ctx, slug)
+}
+
+func (r *instrumentedRepo) GetArticleSlugsToRecheck(ctx context.Context, since time.Time, limit int) (_ []string, err error) {
+	ctx, done := start(ctx, "GetArticleSlugsToRecheck")
+	defer func() { done(err) }()
+	return r.next.GetArticleSlugsToRecheck(ctx, since, limit)
+}
+
+func (r *instrumentedRepo) GetRatingCounts(ctx context.Context) (_ []domain.RatingCount, err error) {
+	ctx, done := start(ctx, "GetRatingCounts")
+	defer func() { done(err) }()
+	return r.next.GetRatingCounts(ctx)
+}
+
+func (r *instrumentedRepo) SaveSpoof(ctx context.Context, spoof domain.Spoof) (err error) {
+	ctx, done := start(ctx, "SaveSpoof")
+	defer func() { done(err) }()
+	return r.next.SaveSpoof(ctx, spoof)
+}
+
+func (r *instrumentedRepo) UpdateSpoof(ctx context.Context, spoof domain.Spoof) (err error) {
+	ctx, done := start(ctx, "UpdateSpoof")
+	defer func() { done(err) }()
+	return r.next.UpdateSpoof(ctx, spoof)
+}
+
+func (r *instrumentedRepo) MarkSpoofStale(ctx context.Context, slug string) (err error) {
+	ctx, done := start(ctx, "MarkSpoofStale")
+	defer func() { done(err) }()
+	return r.next.MarkSpoofStale(ctx, slug)
+}
+
+func (r *instrumentedRepo) GetSpoof(ctx context.Context, slug string) (_ domain.Spoof, err error) {
+	ctx, done := start(ctx, "GetSpoof")
+	defer func() { done(err) }()
+	return r.next.GetSpoof(ctx, slug)
+}
+
+func (r *instrumentedRepo) GetLatestSpoofStubs(ctx context.Context) (_ []domain.SpoofStub, err error) {
+	ctx, done := start(ctx, "GetLatestSpoofStubs")
+	defer func() { done(err) }()
+	return r.next.GetLatestSpoofStubs(ctx)
+}
+
+func (r *instrumentedRepo) GetAllNotExistingSpoofSlugs(ctx context.Context, slugs []string) (_ []string, err error) {
+	ctx, done := start(ctx, "GetAllNotExistingSpoofSlugs")
+	defer func() { done(err) }()
+	return r.next.GetAllNotExistingSpoofSlugs(ctx, slugs)
+}
+
+func (r *instrumentedRepo) Search(ctx context.Context, query string, limit int) (_ []domain.SearchResult, err error) {
+	ctx, done := start(ctx, "Search")
+	defer func() { done(err) }()
+	return r.next.Search(ctx, query, limit)
+}
+
+func (r *instrumentedRepo) UpdateSpoofStatus(ctx context.Context, slug string, from, to domain.SpoofStatus) (err error) {
+	ctx, done := start(ctx, "UpdateSpoofStatus")
+	defer func() { done(err) }()
+	return r.next.UpdateSpoofStatus(ctx, slug, from, to)
+}
+
+func (r *instrumentedRepo) EditSpoofContent(ctx context.Context, slug string, content []string) (err error) {
+	ctx, done := start(ctx, "EditSpoofContent")
+	defer func() { done(err) }()
+	return r.next.EditSpoofContent(ctx, slug, content)
+}
+
+func (r *instrumentedRepo) DeleteSpoof(ctx context.Context, slug string) (err error) {
+	ctx, done := start(ctx, "DeleteSpoof")
+	defer func() { done(err) }()
+	return r.next.DeleteSpoof(ctx, slug)
+}
+
+func (r *instrumentedRepo) ListArticles(ctx context.Context, filter domain.ArticleFilter) (_ []domain.ArticleOverview, err error) {
+	ctx, done := start(ctx, "ListArticles")
+	defer func() { done(err) }()
+	return r.next.ListArticles(ctx, filter)
+}
+
+func (r *instrumentedRepo) SaveJob(ctx context.Context, job domain.SpoofJob) (err error) {
+	ctx, done := start(ctx, "SaveJob")
+	defer func() { done(err) }()
+	return r.next.SaveJob(ctx, job)
+}
+
+func (r *instrumentedRepo) UpdateJob(ctx context.Context, job domain.SpoofJob) (err error) {
+	ctx, done := start(ctx, "UpdateJob")
+	defer func() { done(err) }()
+	return r.next.UpdateJob(ctx, job)
+}
+
+func (r *instrumentedRepo) GetJob(ctx context.Context, id string) (_ domain.SpoofJob, err error) {
+	ctx, done := start(ctx, "GetJob")
+	defer func() { done(err) }()
+	return r.next.GetJob(ctx, id)
+}
+
+func (r *instrumentedRepo) DeleteJobsBefore(ctx context.Context, before time.Time) (_ int64, err error) {
+	ctx, done := start(ctx, "DeleteJobsBefore")
+	defer func() { done(err) }()
+	return r.next.DeleteJobsBefore(ctx, before)
+}
+
+var _ Repo = &instrumentedRepo{}
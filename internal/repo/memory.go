@@ -0,0 +1,708 @@
+package repo
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"html"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/glizzus/trf/internal/domain"
+)
+
+// MemoryRepo is an implementation of the Repo interface that keeps everything in memory,
+// so that handlers and the ingest worker can be tested without a database.
+// It behaves like the database implementations, except that search doesn't stem words,
+// and everything is lost when the process exits.
+//
+// Transactions hold the whole repo from start to end. Calling the MemoryRepo that WithTx was called on
+// from inside fn, rather than the one fn gets, waits for the transaction and so never returns.
+type MemoryRepo struct {
+	db *memoryDB
+	// tx is the copy of the state that the transaction the repo was made for changes, if any.
+	// It replaces the state when the transaction is committed.
+	tx *memoryState
+}
+
+type memoryDB struct {
+	mu    sync.Mutex
+	state *memoryState
+	locks *localLocks
+}
+
+// memoryState holds the rows of every table. The values are never changed in place, but replaced,
+// so that a shallow copy of the maps is enough for a transaction to work on.
+type memoryState struct {
+	articles map[string]memoryArticle
+	// revisions are the versions of each article that were replaced, oldest first.
+	revisions map[string][]domain.Article
+	spoofs    map[string]memorySpoof
+
+	users    map[int64]domain.User
+	sessions map[string]domain.Session
+	tokens   map[string]domain.APIToken
+
+	jobs map[string]domain.SpoofJob
+
+	// lastUserID and lastTokenID are the IDs given to the last user and API token saved.
+	lastUserID  int64
+	lastTokenID int64
+}
+
+type memoryArticle struct {
+	article domain.Article
+	hash    string
+	checked time.Time
+}
+
+// memorySpoof is what a spoof has of its own. The rest comes from its article.
+type memorySpoof struct {
+	rating     domain.Rating
+	content    []string
+	whatsTrue  []string
+	whatsFalse []string
+	inversion  string
+	status     domain.SpoofStatus
+
+	stale         bool
+	statusChanged time.Time
+	edited        *time.Time
+}
+
+// NewMemory creates an empty MemoryRepo.
+func NewMemory() *MemoryRepo {
+	return &MemoryRepo{db: &memoryDB{
+		state: &memoryState{
+			articles:  make(map[string]memoryArticle),
+			revisions: make(map[string][]domain.Article),
+			spoofs:    make(map[string]memorySpoof),
+			users:     make(map[int64]domain.User),
+			sessions:  make(map[string]domain.Session),
+			tokens:    make(map[string]domain.APIToken),
+			jobs:      make(map[string]domain.SpoofJob),
+		},
+		locks: newLocalLocks(),
+	}}
+}
+
+func (s *memoryState) clone() *memoryState {
+	c := *s
+	c.articles = maps.Clone(s.articles)
+	c.revisions = maps.Clone(s.revisions)
+	c.spoofs = maps.Clone(s.spoofs)
+	c.users = maps.Clone(s.users)
+	c.sessions = maps.Clone(s.sessions)
+	c.tokens = maps.Clone(s.tokens)
+	c.jobs = maps.Clone(s.jobs)
+	return &c
+}
+
+// with runs fn on the state, in the transaction the repo was made for if there is one.
+// Changes that fn makes are kept even if it fails, so it must check everything before it changes anything.
+func (r *MemoryRepo) with(fn func(s *memoryState) error) error {
+	if r.tx != nil {
+		return fn(r.tx)
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	return fn(r.db.state)
+}
+
+// WithTx runs fn with a repo whose calls all happen in one transaction,
+// which is committed if fn returns nil and rolled back otherwise.
+// Calling WithTx on the repo that fn gets joins the same transaction.
+func (r *MemoryRepo) WithTx(ctx context.Context, fn func(Repo) error) error {
+	return r.inTx(func(tx *MemoryRepo) error {
+		return fn(tx)
+	})
+}
+
+// inTx runs fn in a transaction, or in the one r was made for if there is one.
+func (r *MemoryRepo) inTx(fn func(*MemoryRepo) error) error {
+	if r.tx != nil {
+		return fn(r)
+	}
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	tx := &MemoryRepo{db: r.db, tx: r.db.state.clone()}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	r.db.state = tx.tx
+	return nil
+}
+
+// TryLock takes the lock with the given name, if it isn't held.
+// A MemoryRepo belongs to one process, so the lock only needs to keep its goroutines apart.
+func (r *MemoryRepo) TryLock(ctx context.Context, name string) (Lock, bool, error) {
+	return r.db.locks.tryLock(name)
+}
+
+// day returns the day of t as midnight in UTC, which is how the databases return a DATE.
+func day(t time.Time) time.Time {
+	year, month, d := t.Date()
+	return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+}
+
+// cloneStrings copies s, so that neither the caller nor the repo sees the other change it.
+// Like the database implementations, it returns an empty list rather than nil.
+func cloneStrings(s []string) []string {
+	return append([]string{}, s...)
+}
+
+// cloneArticle copies article, with what the database implementations would lose dropped:
+// the time of day of its dates, an image without a URL, and the difference between nil and empty lists.
+func cloneArticle(article domain.Article) domain.Article {
+	c := article
+	c.Date = day(article.Date)
+	if article.Updated != nil {
+		updated := day(*article.Updated)
+		c.Updated = &updated
+	}
+	c.Image = nil
+	if article.Image != nil && article.Image.URL != "" {
+		image := *article.Image
+		c.Image = &image
+	}
+	if article.Claim.Context != nil {
+		claimContext := *article.Claim.Context
+		c.Claim.Context = &claimContext
+	}
+	c.Authors = cloneStrings(article.Authors)
+	c.Tags = cloneStrings(article.Tags)
+	c.Content = cloneStrings(article.Content)
+	c.Claim.WhatsTrue = cloneStrings(article.Claim.WhatsTrue)
+	c.Claim.WhatsFalse = cloneStrings(article.Claim.WhatsFalse)
+	c.Sources = append([]domain.Source{}, article.Sources...)
+	return c
+}
+
+// stubDate formats the date of a SpoofStub like the database implementations do.
+func stubDate(t time.Time) string {
+	return day(t).Format(time.RFC3339Nano)
+}
+
+func (r *MemoryRepo) SaveArticle(ctx context.Context, article domain.Article) error {
+	return r.with(func(s *memoryState) error {
+		if saved, ok := s.articles[article.Slug]; ok {
+			// The article was saved before. That is fine if it was this version, so that saves can be retried.
+			if saved.hash != article.Hash() {
+				return fmt.Errorf("error saving article %s: %w: another version is saved", article.Slug, ErrConflict)
+			}
+			return nil
+		}
+		if article.Slug == "" {
+			return fmt.Errorf("error saving article: empty slug")
+		}
+		s.articles[article.Slug] = memoryArticle{article: cloneArticle(article), hash: article.Hash(), checked: time.Now()}
+		return nil
+	})
+}
+
+func (r *MemoryRepo) GetArticle(ctx context.Context, slug string) (domain.Article, error) {
+	var article domain.Article
+	err := r.with(func(s *memoryState) error {
+		saved, ok := s.articles[slug]
+		if !ok {
+			return fmt.Errorf("error getting article %s: %w", slug, ErrNotFound)
+		}
+		article = cloneArticle(saved.article)
+		return nil
+	})
+	return article, err
+}
+
+func (r *MemoryRepo) UpdateArticle(ctx context.Context, article domain.Article) error {
+	return r.with(func(s *memoryState) error {
+		saved, ok := s.articles[article.Slug]
+		if !ok {
+			return fmt.Errorf("error updating article %s: %w", article.Slug, ErrNotFound)
+		}
+		s.revisions[article.Slug] = append(slices.Clip(s.revisions[article.Slug]), saved.article)
+		s.articles[article.Slug] = memoryArticle{article: cloneArticle(article), hash: article.Hash(), checked: time.Now()}
+		return nil
+	})
+}
+
+func (r *MemoryRepo) MarkArticleChecked(ctx context.Context, slug string) error {
+	return r.with(func(s *memoryState) error {
+		saved, ok := s.articles[slug]
+		if !ok {
+			return fmt.Errorf("error marking article %s as checked: %w", slug, ErrNotFound)
+		}
+		saved.checked = time.Now()
+		s.articles[slug] = saved
+		return nil
+	})
+}
+
+func (r *MemoryRepo) GetArticleSlugsToRecheck(ctx context.Context, since time.Time, limit int) ([]string, error) {
+	var slugs []string
+	err := r.with(func(s *memoryState) error {
+		var recent []memoryArticle
+		for _, saved := range s.articles {
+			if !saved.article.Date.Before(since) {
+				recent = append(recent, saved)
+			}
+		}
+		slices.SortFunc(recent, func(a, b memoryArticle) int {
+			return cmp.Or(a.checked.Compare(b.checked), strings.Compare(a.article.Slug, b.article.Slug))
+		})
+		for _, saved := range recent[:min(limit, len(recent))] {
+			slugs = append(slugs, saved.article.Slug)
+		}
+		return nil
+	})
+	return slugs, err
+}
+
+func (r *MemoryRepo) GetRatingCounts(ctx context.Context) ([]domain.RatingCount, error) {
+	var counts []domain.RatingCount
+	err := r.with(func(s *memoryState) error {
+		byRating := make(map[domain.Rating]*domain.RatingCount)
+		for _, saved := range s.articles {
+			article := saved.article
+			count, ok := byRating[article.Claim.Rating]
+			if !ok {
+				count = &domain.RatingCount{Rating: article.Claim.Rating}
+				byRating[article.Claim.Rating] = count
+			}
+			count.Articles++
+			// The latest article is the most recent, and the first by slug of those on the same day.
+			if !ok || article.Date.After(count.LatestDate) ||
+				(article.Date.Equal(count.LatestDate) && article.Slug < count.LatestSlug) {
+				count.LatestSlug = article.Slug
+				count.LatestDate = article.Date
+			}
+		}
+		for _, count := range byRating {
+			counts = append(counts, *count)
+		}
+		slices.SortFunc(counts, func(a, b domain.RatingCount) int {
+			return cmp.Or(cmp.Compare(b.Articles, a.Articles), strings.Compare(string(a.Rating), string(b.Rating)))
+		})
+		return nil
+	})
+	return counts, err
+}
+
+func (r *MemoryRepo) SaveSpoof(ctx context.Context, spoof domain.Spoof) error {
+	return r.with(func(s *memoryState) error {
+		if saved, ok := s.spoofs[spoof.Slug]; ok {
+			// Like articles, a spoof can be saved again as long as it is the same.
+			if saved.rating != spoof.Claim.Rating || !slices.Equal(saved.content, spoof.Content) || saved.inversion != spoof.Inversion {
+				return fmt.Errorf("error saving spoof %s: %w: another spoof is saved", spoof.Slug, ErrConflict)
+			}
+			return nil
+		}
+		if _, ok := s.articles[spoof.Slug]; !ok {
+			return fmt.Errorf("error saving spoof %s: there is no article %s", spoof.Slug, spoof.Slug)
+		}
+		s.spoofs[spoof.Slug] = memorySpoof{
+			rating:        spoof.Claim.Rating,
+			content:       cloneStrings(spoof.Content),
+			whatsTrue:     cloneStrings(spoof.Claim.WhatsTrue),
+			whatsFalse:    cloneStrings(spoof.Claim.WhatsFalse),
+			inversion:     spoof.Inversion,
+			status:        spoofStatus(spoof.Status),
+			statusChanged: time.Now(),
+		}
+		return nil
+	})
+}
+
+func (r *MemoryRepo) UpdateSpoof(ctx context.Context, spoof domain.Spoof) error {
+	return r.with(func(s *memoryState) error {
+		saved, ok := s.spoofs[spoof.Slug]
+		if !ok {
+			return fmt.Errorf("error updating spoof %s: %w", spoof.Slug, ErrNotFound)
+		}
+		status := spoofStatus(spoof.Status)
+		if status != saved.status {
+			saved.statusChanged = time.Now()
+		}
+		saved.rating = spoof.Claim.Rating
+		saved.content = cloneStrings(spoof.Content)
+		saved.whatsTrue = cloneStrings(spoof.Claim.WhatsTrue)
+		saved.whatsFalse = cloneStrings(spoof.Claim.WhatsFalse)
+		saved.inversion = spoof.Inversion
+		saved.status = status
+		saved.stale = false
+		saved.edited = nil
+		s.spoofs[spoof.Slug] = saved
+		return nil
+	})
+}
+
+func (r *MemoryRepo) MarkSpoofStale(ctx context.Context, slug string) error {
+	return r.with(func(s *memoryState) error {
+		saved, ok := s.spoofs[slug]
+		if !ok {
+			return fmt.Errorf("error marking spoof %s as stale: %w", slug, ErrNotFound)
+		}
+		saved.stale = true
+		s.spoofs[slug] = saved
+		return nil
+	})
+}
+
+// spoof joins the spoof with the given slug to its article, like GetSpoof does in the database implementations.
+func (s *memoryState) spoof(slug string) (domain.Spoof, bool) {
+	saved, ok := s.spoofs[slug]
+	if !ok {
+		return domain.Spoof{}, false
+	}
+	article := cloneArticle(s.articles[slug].article)
+
+	// The authors and sources belong to the original article, so a spoof doesn't have them.
+	return domain.Spoof{
+		Article: domain.Article{
+			Slug:     article.Slug,
+			Title:    article.Title,
+			Subtitle: article.Subtitle,
+			Date:     article.Date,
+			Updated:  article.Updated,
+			Category: article.Category,
+			Tags:     article.Tags,
+			Image:    article.Image,
+			Claim: domain.Claim{
+				Question:   article.Claim.Question,
+				Rating:     saved.rating,
+				Context:    article.Claim.Context,
+				WhatsTrue:  cloneStrings(saved.whatsTrue),
+				WhatsFalse: cloneStrings(saved.whatsFalse),
+			},
+			Content: cloneStrings(saved.content),
+		},
+		Inversion: saved.inversion,
+		Status:    saved.status,
+	}, true
+}
+
+func (r *MemoryRepo) GetSpoof(ctx context.Context, slug string) (domain.Spoof, error) {
+	var spoof domain.Spoof
+	err := r.with(func(s *memoryState) error {
+		var ok bool
+		if spoof, ok = s.spoof(slug); !ok {
+			return fmt.Errorf("error getting spoof %s: %w", slug, ErrNotFound)
+		}
+		return nil
+	})
+	return spoof, err
+}
+
+// published returns the published spoofs, newest first.
+func (s *memoryState) published() []domain.Spoof {
+	var spoofs []domain.Spoof
+	for slug, saved := range s.spoofs {
+		if saved.status == domain.SpoofPublished {
+			spoof, _ := s.spoof(slug)
+			spoofs = append(spoofs, spoof)
+		}
+	}
+	slices.SortFunc(spoofs, func(a, b domain.Spoof) int {
+		return cmp.Or(b.Date.Compare(a.Date), strings.Compare(a.Slug, b.Slug))
+	})
+	return spoofs
+}
+
+func (r *MemoryRepo) GetLatestSpoofStubs(ctx context.Context) ([]domain.SpoofStub, error) {
+	var stubs []domain.SpoofStub
+	err := r.with(func(s *memoryState) error {
+		spoofs := s.published()
+		for _, spoof := range spoofs[:min(21, len(spoofs))] {
+			stubs = append(stubs, domain.SpoofStub{
+				Slug:     spoof.Slug,
+				Title:    spoof.Title,
+				Subtitle: spoof.Subtitle,
+				Date:     stubDate(spoof.Date),
+			})
+		}
+		return nil
+	})
+	return stubs, err
+}
+
+// GetAllNotExistingSpoofSlugs returns the slugs that do not exist in the database,
+// in the same order as they were passed in.
+func (r *MemoryRepo) GetAllNotExistingSpoofSlugs(ctx context.Context, slugs []string) ([]string, error) {
+	var notExisting []string
+	err := r.with(func(s *memoryState) error {
+		for _, slug := range slugs {
+			if _, ok := s.articles[slug]; !ok {
+				notExisting = append(notExisting, slug)
+			}
+		}
+		return nil
+	})
+	return notExisting, err
+}
+
+// searchWords splits text into the lowercase words that search matches.
+func searchWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// searchField is a field of a spoof that search matches, with its weight in the rank.
+// The weights are those of the database implementations.
+type searchField struct {
+	words  []string
+	weight float64
+}
+
+// containsPhrase returns whether the words of phrase appear one after another in words.
+func containsPhrase(words, phrase []string) bool {
+	if len(phrase) == 0 {
+		return false
+	}
+	for i := 0; i+len(phrase) <= len(words); i++ {
+		if slices.Equal(words[i:i+len(phrase)], phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+// rankSpoof returns how well the spoof matches search, and whether it matches at all.
+// The rank adds up the weights of the fields that each phrase is found in.
+func rankSpoof(spoof domain.Spoof, article domain.Article, search websearch) (float64, bool) {
+	fields := []searchField{
+		{searchWords(spoof.Title), 10},
+		{searchWords(spoof.Subtitle), 4},
+		{searchWords(spoof.Claim.Question), 4},
+		{searchWords(strings.Join(article.Content, " ")), 1},
+		{searchWords(strings.Join(spoof.Content, " ")), 1},
+	}
+	find := func(phrase string) float64 {
+		var rank float64
+		for _, field := range fields {
+			if containsPhrase(field.words, searchWords(phrase)) {
+				rank += field.weight
+			}
+		}
+		return rank
+	}
+
+	for _, phrase := range search.none {
+		if find(phrase) > 0 {
+			return 0, false
+		}
+	}
+
+	var rank float64
+	matched := false
+	for _, all := range search.any {
+		var allRank float64
+		allMatched := true
+		for _, phrase := range all {
+			found := find(phrase)
+			allRank += found
+			allMatched = allMatched && found > 0
+		}
+		if allMatched {
+			rank += allRank
+			matched = true
+		}
+	}
+	return rank, matched
+}
+
+// searchSnippet returns the first 30 words of the spoof's content around the first match,
+// escaped for HTML, with the words of the query wrapped in <mark> tags.
+func searchSnippet(content []string, search websearch) string {
+	queryWords := make(map[string]bool)
+	for _, all := range search.any {
+		for _, phrase := range all {
+			for _, word := range searchWords(phrase) {
+				queryWords[word] = true
+			}
+		}
+	}
+
+	const maxWords = 30
+	words := strings.Fields(strings.Join(content, " "))
+	marked := make([]bool, len(words))
+	first := -1
+	for i, word := range words {
+		for _, w := range searchWords(word) {
+			if queryWords[w] {
+				marked[i] = true
+			}
+		}
+		if marked[i] && first < 0 {
+			first = i
+		}
+	}
+
+	start := max(0, min(first-maxWords/3, len(words)-maxWords))
+	end := min(len(words), start+maxWords)
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	for i := start; i < end; i++ {
+		if i > start {
+			b.WriteString(" ")
+		}
+		if marked[i] {
+			b.WriteString(markWords(words[i], queryWords))
+		} else {
+			b.WriteString(html.EscapeString(words[i]))
+		}
+	}
+	if end < len(words) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+// markWords escapes token for HTML, and wraps the words in it that are in queryWords in <mark> tags.
+// Like the databases, it only marks the word itself, and not the punctuation or markup around it.
+func markWords(token string, queryWords map[string]bool) string {
+	var b strings.Builder
+	isWord := func(r rune) bool { return unicode.IsLetter(r) || unicode.IsNumber(r) }
+	for token != "" {
+		// Take the run of word or non-word characters at the start of token.
+		word := isWord([]rune(token)[0])
+		end := strings.IndexFunc(token, func(r rune) bool { return isWord(r) != word })
+		if end < 0 {
+			end = len(token)
+		}
+		part := token[:end]
+		token = token[end:]
+
+		if word && queryWords[strings.ToLower(part)] {
+			b.WriteString("<mark>" + html.EscapeString(part) + "</mark>")
+		} else {
+			b.WriteString(html.EscapeString(part))
+		}
+	}
+	return b.String()
+}
+
+// Search matches whole words, without stemming them like the databases do.
+func (r *MemoryRepo) Search(ctx context.Context, query string, limit int) ([]domain.SearchResult, error) {
+	search := parseWebsearch(query)
+
+	var results []domain.SearchResult
+	err := r.with(func(s *memoryState) error {
+		// published is sorted by date, and the stable sort keeps that order among equal ranks.
+		for _, spoof := range s.published() {
+			rank, ok := rankSpoof(spoof, s.articles[spoof.Slug].article, search)
+			if !ok {
+				continue
+			}
+			results = append(results, domain.SearchResult{
+				SpoofStub: domain.SpoofStub{
+					Slug:     spoof.Slug,
+					Title:    spoof.Title,
+					Subtitle: spoof.Subtitle,
+					Date:     stubDate(spoof.Date),
+				},
+				Rank:    rank,
+				Snippet: searchSnippet(spoof.Content, search),
+			})
+		}
+		return nil
+	})
+	slices.SortStableFunc(results, func(a, b domain.SearchResult) int {
+		return cmp.Compare(b.Rank, a.Rank)
+	})
+	return results[:min(max(limit, 0), len(results))], err
+}
+
+func (r *MemoryRepo) UpdateSpoofStatus(ctx context.Context, slug string, from, to domain.SpoofStatus) error {
+	return r.with(func(s *memoryState) error {
+		saved, ok := s.spoofs[slug]
+		if !ok {
+			return fmt.Errorf("error updating status of spoof %s: %w", slug, ErrNotFound)
+		}
+		if saved.status != from {
+			return fmt.Errorf("error updating status of spoof %s: %w: it is %s, not %s", slug, ErrConflict, saved.status, from)
+		}
+		saved.status = to
+		saved.statusChanged = time.Now()
+		s.spoofs[slug] = saved
+		return nil
+	})
+}
+
+func (r *MemoryRepo) EditSpoofContent(ctx context.Context, slug string, content []string) error {
+	return r.with(func(s *memoryState) error {
+		saved, ok := s.spoofs[slug]
+		if !ok {
+			return fmt.Errorf("error editing spoof %s: %w", slug, ErrNotFound)
+		}
+		now := time.Now()
+		saved.content = cloneStrings(content)
+		saved.edited = &now
+		if saved.status == domain.SpoofPublished {
+			saved.status = domain.SpoofReview
+			saved.statusChanged = now
+		}
+		s.spoofs[slug] = saved
+		return nil
+	})
+}
+
+func (r *MemoryRepo) DeleteSpoof(ctx context.Context, slug string) error {
+	return r.with(func(s *memoryState) error {
+		if _, ok := s.spoofs[slug]; !ok {
+			return fmt.Errorf("error deleting spoof %s: %w", slug, ErrNotFound)
+		}
+		delete(s.spoofs, slug)
+		return nil
+	})
+}
+
+func (r *MemoryRepo) ListArticles(ctx context.Context, filter domain.ArticleFilter) ([]domain.ArticleOverview, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+
+	var overviews []domain.ArticleOverview
+	err := r.with(func(s *memoryState) error {
+		for slug, saved := range s.articles {
+			spoof, ok := s.spoofs[slug]
+			// Articles without a spoof are listed when there is no filter, so that editors can see the ones whose spoof was deleted.
+			if filter.SpoofStatus != "" && (!ok || spoof.status != filter.SpoofStatus) {
+				continue
+			}
+			overview := domain.ArticleOverview{
+				Slug:   slug,
+				Title:  saved.article.Title,
+				Date:   saved.article.Date,
+				Rating: saved.article.Claim.Rating,
+			}
+			if ok {
+				overview.SpoofStatus = spoof.status
+				overview.SpoofRating = spoof.rating
+				overview.Stale = spoof.stale
+				overview.StatusChanged = spoof.statusChanged
+				overview.Edited = spoof.edited
+			}
+			overviews = append(overviews, overview)
+		}
+		return nil
+	})
+	slices.SortFunc(overviews, func(a, b domain.ArticleOverview) int {
+		return cmp.Or(b.Date.Compare(a.Date), strings.Compare(a.Slug, b.Slug))
+	})
+
+	start := min(max(filter.Offset, 0), len(overviews))
+	return overviews[start:min(start+limit, len(overviews))], err
+}
+
+var (
+	_ Repo   = &MemoryRepo{}
+	_ Locker = &MemoryRepo{}
+)
@@ -0,0 +1,70 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/glizzus/trf/internal/domain"
+)
+
+// cloneJob copies job, so that neither the caller nor the repo sees the other change it.
+func cloneJob(job domain.SpoofJob) domain.SpoofJob {
+	if job.Finished != nil {
+		finished := *job.Finished
+		job.Finished = &finished
+	}
+	return job
+}
+
+func (r *MemoryRepo) SaveJob(ctx context.Context, job domain.SpoofJob) error {
+	return r.with(func(s *memoryState) error {
+		if _, ok := s.jobs[job.ID]; ok {
+			return fmt.Errorf("error saving job %s: %w", job.ID, ErrConflict)
+		}
+		s.jobs[job.ID] = cloneJob(job)
+		return nil
+	})
+}
+
+func (r *MemoryRepo) UpdateJob(ctx context.Context, job domain.SpoofJob) error {
+	return r.with(func(s *memoryState) error {
+		saved, ok := s.jobs[job.ID]
+		if !ok {
+			return fmt.Errorf("error updating job %s: %w", job.ID, ErrNotFound)
+		}
+		saved.Status = job.Status
+		saved.Existed = job.Existed
+		saved.Error = job.Error
+		saved.Finished = job.Finished
+		s.jobs[job.ID] = cloneJob(saved)
+		return nil
+	})
+}
+
+func (r *MemoryRepo) GetJob(ctx context.Context, id string) (domain.SpoofJob, error) {
+	var job domain.SpoofJob
+	err := r.with(func(s *memoryState) error {
+		saved, ok := s.jobs[id]
+		if !ok {
+			return fmt.Errorf("error getting job %s: %w", id, ErrNotFound)
+		}
+		job = cloneJob(saved)
+		return nil
+	})
+	return job, err
+}
+
+func (r *MemoryRepo) DeleteJobsBefore(ctx context.Context, before time.Time) (int64, error) {
+	var n int64
+	err := r.with(func(s *memoryState) error {
+		for id, job := range s.jobs {
+			if job.Created.Before(before) {
+				delete(s.jobs, id)
+				n++
+			}
+		}
+		return nil
+	})
+	return n, err
+}
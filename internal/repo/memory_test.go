@@ -0,0 +1,14 @@
+package repo_test
+
+import (
+	"testing"
+
+	"github.com/glizzus/trf/internal/repo"
+	"github.com/glizzus/trf/internal/repo/repotest"
+)
+
+func TestMemoryRepo(t *testing.T) {
+	repotest.TestRepo(t, func(t *testing.T) repo.Repo {
+		return repo.NewMemory()
+	})
+}
@@ -0,0 +1,194 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/glizzus/trf/internal/domain"
+)
+
+// user returns the user with the given username.
+func (s *memoryState) user(username string) (domain.User, bool) {
+	for _, user := range s.users {
+		if user.Username == username {
+			return user, true
+		}
+	}
+	return domain.User{}, false
+}
+
+func (r *MemoryRepo) SaveUser(ctx context.Context, user domain.User) (domain.User, error) {
+	err := r.with(func(s *memoryState) error {
+		if user.Username == "" {
+			return fmt.Errorf("error saving user: empty username")
+		}
+		if _, ok := s.user(user.Username); ok {
+			return fmt.Errorf("error saving user %s: %w", user.Username, ErrConflict)
+		}
+		s.lastUserID++
+		user.ID = s.lastUserID
+		user.Created = time.Now()
+		s.users[user.ID] = user
+		return nil
+	})
+	if err != nil {
+		return domain.User{}, err
+	}
+	return user, nil
+}
+
+func (r *MemoryRepo) GetUser(ctx context.Context, username string) (domain.User, error) {
+	var user domain.User
+	err := r.with(func(s *memoryState) error {
+		var ok bool
+		if user, ok = s.user(username); !ok {
+			return fmt.Errorf("error getting user %s: %w", username, ErrNotFound)
+		}
+		return nil
+	})
+	return user, err
+}
+
+// deleteSessions ends every session of the user with the given ID.
+func (s *memoryState) deleteSessions(userID int64) {
+	for tokenHash, session := range s.sessions {
+		if session.UserID == userID {
+			delete(s.sessions, tokenHash)
+		}
+	}
+}
+
+func (r *MemoryRepo) SetPassword(ctx context.Context, username, passwordHash string) error {
+	return r.with(func(s *memoryState) error {
+		user, ok := s.user(username)
+		if !ok {
+			return fmt.Errorf("error setting password of user %s: %w", username, ErrNotFound)
+		}
+		// Whoever knew the old password must not stay signed in.
+		user.PasswordHash = passwordHash
+		s.users[user.ID] = user
+		s.deleteSessions(user.ID)
+		return nil
+	})
+}
+
+func (r *MemoryRepo) DeleteUser(ctx context.Context, username string) error {
+	return r.with(func(s *memoryState) error {
+		user, ok := s.user(username)
+		if !ok {
+			return fmt.Errorf("error deleting user %s: %w", username, ErrNotFound)
+		}
+		delete(s.users, user.ID)
+		s.deleteSessions(user.ID)
+		for tokenHash, token := range s.tokens {
+			if token.UserID == user.ID {
+				delete(s.tokens, tokenHash)
+			}
+		}
+		return nil
+	})
+}
+
+func (r *MemoryRepo) SaveSession(ctx context.Context, session domain.Session) error {
+	return r.with(func(s *memoryState) error {
+		if _, ok := s.users[session.UserID]; !ok {
+			return fmt.Errorf("error saving session of user %d: there is no such user", session.UserID)
+		}
+		if _, ok := s.sessions[session.TokenHash]; ok {
+			return fmt.Errorf("error saving session of user %d: %w", session.UserID, ErrConflict)
+		}
+		session.Created = time.Now()
+		s.sessions[session.TokenHash] = session
+		return nil
+	})
+}
+
+func (r *MemoryRepo) GetSession(ctx context.Context, tokenHash string) (domain.Session, domain.User, error) {
+	var session domain.Session
+	var user domain.User
+	err := r.with(func(s *memoryState) error {
+		var ok bool
+		session, ok = s.sessions[tokenHash]
+		if !ok || !session.Expires.After(time.Now()) {
+			// The token hash is as good as a password, so it stays out of the error.
+			return fmt.Errorf("error getting session: %w", ErrNotFound)
+		}
+		user = s.users[session.UserID]
+		return nil
+	})
+	if err != nil {
+		return domain.Session{}, domain.User{}, err
+	}
+	return session, user, nil
+}
+
+func (r *MemoryRepo) DeleteSession(ctx context.Context, tokenHash string) error {
+	return r.with(func(s *memoryState) error {
+		delete(s.sessions, tokenHash)
+		return nil
+	})
+}
+
+func (r *MemoryRepo) DeleteExpiredSessions(ctx context.Context) (int64, error) {
+	var n int64
+	err := r.with(func(s *memoryState) error {
+		now := time.Now()
+		for tokenHash, session := range s.sessions {
+			if !session.Expires.After(now) {
+				delete(s.sessions, tokenHash)
+				n++
+			}
+		}
+		return nil
+	})
+	return n, err
+}
+
+func (r *MemoryRepo) SaveAPIToken(ctx context.Context, token domain.APIToken) (domain.APIToken, error) {
+	err := r.with(func(s *memoryState) error {
+		if _, ok := s.users[token.UserID]; !ok {
+			return fmt.Errorf("error saving API token %q: there is no user %d", token.Name, token.UserID)
+		}
+		if _, ok := s.tokens[token.TokenHash]; ok {
+			return fmt.Errorf("error saving API token %q: %w", token.Name, ErrConflict)
+		}
+		s.lastTokenID++
+		token.ID = s.lastTokenID
+		token.Created = time.Now()
+		token.Scopes = slices.Clone(token.Scopes)
+		if token.Expires != nil {
+			expires := *token.Expires
+			token.Expires = &expires
+		}
+		s.tokens[token.TokenHash] = token
+		return nil
+	})
+	if err != nil {
+		return domain.APIToken{}, err
+	}
+	return token, nil
+}
+
+// UseAPIToken doesn't record when the token was used, because nothing reads it back.
+func (r *MemoryRepo) UseAPIToken(ctx context.Context, tokenHash string) (domain.APIToken, domain.User, error) {
+	var token domain.APIToken
+	var user domain.User
+	err := r.with(func(s *memoryState) error {
+		var ok bool
+		token, ok = s.tokens[tokenHash]
+		if !ok || (token.Expires != nil && !token.Expires.After(time.Now())) {
+			return fmt.Errorf("error using API token: %w", ErrNotFound)
+		}
+		token.Scopes = slices.Clone(token.Scopes)
+		user = s.users[token.UserID]
+		return nil
+	})
+	if err != nil {
+		return domain.APIToken{}, domain.User{}, err
+	}
+	return token, user, nil
+}
+
+var _ UserRepo = &MemoryRepo{}
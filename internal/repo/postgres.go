@@ -3,7 +3,11 @@ package repo
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/lib/pq"
 
@@ -13,22 +17,169 @@ import (
 // PostgresRepo is a PostgreSQL implementation of the Repo interface.
 type PostgresRepo struct {
 	db *sql.DB
+	// q runs the queries. It is db, or the transaction that the repo was made for by WithTx.
+	q querier
+	// tx is the transaction that the repo was made for, if any.
+	tx *sql.Tx
+}
+
+// querier runs queries, on the database or in a transaction.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
 }
 
 // NewPostgres creates a new PostgresRepo with the given database connection.
 func NewPostgres(db *sql.DB) *PostgresRepo {
-	return &PostgresRepo{db: db}
+	return &PostgresRepo{db: db, q: db}
+}
+
+// WithTx runs fn with a repo whose calls all happen in one transaction,
+// which is committed if fn returns nil and rolled back otherwise.
+// Calling WithTx on the repo that fn gets joins the same transaction.
+func (r *PostgresRepo) WithTx(ctx context.Context, fn func(Repo) error) error {
+	return r.inTx(ctx, func(tx *PostgresRepo) error {
+		return fn(tx)
+	})
+}
+
+// inTx runs fn in a transaction, or in the one r was made for if there is one.
+func (r *PostgresRepo) inTx(ctx context.Context, fn func(*PostgresRepo) error) error {
+	if r.tx != nil {
+		return fn(r)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %w", err)
+	}
+	// This is a no-op if the transaction was committed.
+	defer tx.Rollback()
+
+	if err := fn(&PostgresRepo{db: r.db, q: tx, tx: tx}); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// Ping checks that the database can be reached.
+func (r *PostgresRepo) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
+
+// MigrationVersion returns the version of the last migration applied by the migrate tool,
+// and whether it failed halfway through.
+func (r *PostgresRepo) MigrationVersion(ctx context.Context) (version uint, dirty bool, err error) {
+	err = r.q.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations`).Scan(&version, &dirty)
+	if err != nil {
+		return 0, false, fmt.Errorf("error querying migration version: %w", translateError(err))
+	}
+	return version, dirty, nil
+}
+
+// spoofStatus returns the status a spoof is saved with. Spoofs are drafts unless they say otherwise.
+func spoofStatus(status domain.SpoofStatus) domain.SpoofStatus {
+	if status == "" {
+		return domain.SpoofDraft
+	}
+	return status
+}
+
+// uniqueViolation is the PostgreSQL error code for a unique constraint violation.
+// See https://www.postgresql.org/docs/current/errcodes-appendix.html
+const uniqueViolation = "23505"
+
+// translateError converts driver errors into the errors defined by this package.
+// Errors that have no equivalent are returned unchanged.
+func translateError(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+		return fmt.Errorf("%w: %s", ErrConflict, pqErr.Detail)
+	}
+	return err
+}
+
+// textArray converts s into a TEXT[] parameter. pq turns a nil slice into NULL,
+// but our array columns are NOT NULL and use an empty array instead.
+func textArray(s []string) driver.Valuer {
+	if s == nil {
+		s = []string{}
+	}
+	return pq.StringArray(s)
+}
+
+// nullString converts an empty string into NULL.
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// metadataColumns holds the columns of an article's metadata that need converting when they are scanned.
+type metadataColumns struct {
+	category sql.NullString
+	imageURL sql.NullString
+	imageAlt sql.NullString
+	sources  []byte
+}
+
+// metadataArgs returns the category, image_url, image_alt and sources parameters of an article.
+func metadataArgs(article domain.Article) ([]any, error) {
+	sources := article.Sources
+	if sources == nil {
+		sources = []domain.Source{}
+	}
+	sourcesJSON, err := json.Marshal(sources)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding sources of article %s: %w", article.Slug, err)
+	}
+
+	var imageURL, imageAlt sql.NullString
+	if article.Image != nil {
+		imageURL = nullString(article.Image.URL)
+		imageAlt = nullString(article.Image.Alt)
+	}
+
+	return []any{nullString(article.Category), imageURL, imageAlt, sourcesJSON}, nil
+}
+
+// apply copies the scanned columns into the article. Sources are left alone if they weren't scanned.
+func (c *metadataColumns) apply(article *domain.Article) error {
+	article.Category = c.category.String
+	if c.imageURL.Valid {
+		article.Image = &domain.Image{URL: c.imageURL.String, Alt: c.imageAlt.String}
+	}
+	if c.sources == nil {
+		return nil
+	}
+	if err := json.Unmarshal(c.sources, &article.Sources); err != nil {
+		return fmt.Errorf("error decoding sources of article %s: %w", article.Slug, err)
+	}
+	return nil
 }
 
 func (r *PostgresRepo) SaveArticle(ctx context.Context, article domain.Article) error {
 	const query = `
-		INSERT INTO articles (slug, title, subtitle, date, question, rating, context, content)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO articles (
+			slug, title, subtitle, date, question, rating, context, content, content_hash,
+			authors, updated_date, tags, whats_true, whats_false,
+			category, image_url, image_alt, sources
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
+		ON CONFLICT (slug) DO NOTHING
 	`
 
-	_, err := r.db.ExecContext(
-		ctx,
-		query,
+	metadata, err := metadataArgs(article)
+	if err != nil {
+		return err
+	}
+
+	args := append([]any{
 		article.Slug,
 		article.Title,
 		article.Subtitle,
@@ -37,18 +188,302 @@ func (r *PostgresRepo) SaveArticle(ctx context.Context, article domain.Article)
 		article.Claim.Rating,
 		article.Claim.Context,
 		pq.Array(article.Content),
-	)
-	return err
+		article.Hash(),
+		textArray(article.Authors),
+		article.Updated,
+		textArray(article.Tags),
+		textArray(article.Claim.WhatsTrue),
+		textArray(article.Claim.WhatsFalse),
+	}, metadata...)
+
+	// A unique violation would abort the transaction the save is part of, so conflicts insert nothing instead.
+	res, err := r.q.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("error saving article %s: %w", article.Slug, translateError(err))
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 1 {
+		return nil
+	}
+
+	// The article was saved before. That is fine if it was this version, so that saves can be retried.
+	var same bool
+	err = r.q.QueryRowContext(ctx, `SELECT content_hash IS NOT DISTINCT FROM $2 FROM articles WHERE slug = $1`, article.Slug, article.Hash()).Scan(&same)
+	if err != nil {
+		return fmt.Errorf("error saving article %s: %w", article.Slug, translateError(err))
+	}
+	if !same {
+		return fmt.Errorf("error saving article %s: %w: another version is saved", article.Slug, ErrConflict)
+	}
+	return nil
+}
+
+func (r *PostgresRepo) GetArticle(ctx context.Context, slug string) (domain.Article, error) {
+	const query = `
+		SELECT
+			slug, title, subtitle, date, question, rating, context, content,
+			authors, updated_date, tags, whats_true, whats_false,
+			category, image_url, image_alt, sources
+		FROM articles
+		WHERE slug = $1
+	`
+
+	var article domain.Article
+	var metadata metadataColumns
+	if err := r.q.QueryRowContext(ctx, query, slug).Scan(
+		&article.Slug,
+		&article.Title,
+		&article.Subtitle,
+		&article.Date,
+		&article.Claim.Question,
+		&article.Claim.Rating,
+		&article.Claim.Context,
+		pq.Array(&article.Content),
+		pq.Array(&article.Authors),
+		&article.Updated,
+		pq.Array(&article.Tags),
+		pq.Array(&article.Claim.WhatsTrue),
+		pq.Array(&article.Claim.WhatsFalse),
+		&metadata.category,
+		&metadata.imageURL,
+		&metadata.imageAlt,
+		&metadata.sources,
+	); err != nil {
+		return domain.Article{}, fmt.Errorf("error getting article %s: %w", slug, translateError(err))
+	}
+	if err := metadata.apply(&article); err != nil {
+		return domain.Article{}, err
+	}
+
+	return article, nil
+}
+
+func (r *PostgresRepo) UpdateArticle(ctx context.Context, article domain.Article) error {
+	// Copying the current version into the history and replacing it must happen together,
+	// otherwise we could lose a version or record one twice.
+	const archiveQuery = `
+		INSERT INTO article_revisions (
+			slug, title, subtitle, date, question, rating, context, content, content_hash,
+			authors, updated_date, tags, whats_true, whats_false,
+			category, image_url, image_alt, sources
+		)
+		SELECT
+			slug, title, subtitle, date, question, rating, context, content, content_hash,
+			authors, updated_date, tags, whats_true, whats_false,
+			category, image_url, image_alt, sources
+		FROM articles
+		WHERE slug = $1
+	`
+	const updateQuery = `
+		UPDATE articles
+		SET
+			title = $2, subtitle = $3, date = $4, question = $5, rating = $6, context = $7, content = $8,
+			content_hash = $9, authors = $10, updated_date = $11, tags = $12, whats_true = $13, whats_false = $14,
+			category = $15, image_url = $16, image_alt = $17, sources = $18,
+			checked_at = NOW(), updated_at = NOW()
+		WHERE slug = $1
+	`
+
+	metadata, err := metadataArgs(article)
+	if err != nil {
+		return err
+	}
+
+	args := append([]any{
+		article.Slug,
+		article.Title,
+		article.Subtitle,
+		article.Date,
+		article.Claim.Question,
+		article.Claim.Rating,
+		article.Claim.Context,
+		pq.Array(article.Content),
+		article.Hash(),
+		textArray(article.Authors),
+		article.Updated,
+		textArray(article.Tags),
+		textArray(article.Claim.WhatsTrue),
+		textArray(article.Claim.WhatsFalse),
+	}, metadata...)
+
+	return r.inTx(ctx, func(r *PostgresRepo) error {
+		res, err := r.q.ExecContext(ctx, archiveQuery, article.Slug)
+		if err != nil {
+			return fmt.Errorf("error archiving revision of article %s: %w", article.Slug, translateError(err))
+		}
+		if n, err := res.RowsAffected(); err == nil && n == 0 {
+			return fmt.Errorf("error updating article %s: %w", article.Slug, ErrNotFound)
+		}
+
+		if _, err := r.q.ExecContext(ctx, updateQuery, args...); err != nil {
+			return fmt.Errorf("error updating article %s: %w", article.Slug, translateError(err))
+		}
+		return nil
+	})
+}
+
+func (r *PostgresRepo) MarkArticleChecked(ctx context.Context, slug string) error {
+	const query = `UPDATE articles SET checked_at = NOW() WHERE slug = $1`
+
+	res, err := r.q.ExecContext(ctx, query, slug)
+	if err != nil {
+		return fmt.Errorf("error marking article %s as checked: %w", slug, translateError(err))
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("error marking article %s as checked: %w", slug, ErrNotFound)
+	}
+	return nil
+}
+
+func (r *PostgresRepo) GetArticleSlugsToRecheck(ctx context.Context, since time.Time, limit int) ([]string, error) {
+	const query = `
+		SELECT slug
+		FROM articles
+		WHERE date >= $1
+		ORDER BY checked_at ASC
+		LIMIT $2
+	`
+
+	rows, err := r.q.QueryContext(ctx, query, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error querying for articles to recheck: %w", err)
+	}
+	defer rows.Close()
+
+	var slugs []string
+	for rows.Next() {
+		var slug string
+		if err := rows.Scan(&slug); err != nil {
+			return nil, fmt.Errorf("error scanning articles to recheck: %w", err)
+		}
+		slugs = append(slugs, slug)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating articles to recheck: %w", err)
+	}
+
+	return slugs, nil
+}
+
+func (r *PostgresRepo) GetRatingCounts(ctx context.Context) ([]domain.RatingCount, error) {
+	// DISTINCT ON keeps the first row of each rating, which is the most recent article because of the ORDER BY.
+	const query = `
+		WITH latest AS (
+			SELECT DISTINCT ON (rating) rating, slug, date
+			FROM articles
+			ORDER BY rating, date DESC, slug
+		)
+		SELECT articles.rating, COUNT(*), latest.slug, latest.date
+		FROM articles
+		JOIN latest ON latest.rating = articles.rating
+		GROUP BY articles.rating, latest.slug, latest.date
+		ORDER BY COUNT(*) DESC, articles.rating
+	`
+
+	rows, err := r.q.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error querying for rating counts: %w", err)
+	}
+	defer rows.Close()
+
+	var counts []domain.RatingCount
+	for rows.Next() {
+		var count domain.RatingCount
+		if err := rows.Scan(&count.Rating, &count.Articles, &count.LatestSlug, &count.LatestDate); err != nil {
+			return nil, fmt.Errorf("error scanning rating counts: %w", err)
+		}
+		counts = append(counts, count)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rating counts: %w", err)
+	}
+
+	return counts, nil
 }
 
 func (r *PostgresRepo) SaveSpoof(ctx context.Context, spoof domain.Spoof) error {
 	const query = `
-		INSERT INTO spoofs (slug, rating, content)
-		VALUES ($1, $2, $3)
+		INSERT INTO spoofs (slug, rating, content, whats_true, whats_false, inversion, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (slug) DO NOTHING
+	`
+	// Like articles, a spoof can be saved again as long as it is the same.
+	const sameQuery = `
+		SELECT rating = $2 AND content = $3 AND inversion IS NOT DISTINCT FROM $4
+		FROM spoofs
+		WHERE slug = $1
 	`
 
-	_, err := r.db.ExecContext(ctx, query, spoof.Slug, spoof.Claim.Rating, pq.Array(spoof.Content))
-	return err
+	res, err := r.q.ExecContext(
+		ctx,
+		query,
+		spoof.Slug,
+		spoof.Claim.Rating,
+		pq.Array(spoof.Content),
+		textArray(spoof.Claim.WhatsTrue),
+		textArray(spoof.Claim.WhatsFalse),
+		nullString(spoof.Inversion),
+		spoofStatus(spoof.Status),
+	)
+	if err != nil {
+		return fmt.Errorf("error saving spoof %s: %w", spoof.Slug, translateError(err))
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 1 {
+		return nil
+	}
+
+	var same bool
+	err = r.q.QueryRowContext(ctx, sameQuery, spoof.Slug, spoof.Claim.Rating, pq.Array(spoof.Content), nullString(spoof.Inversion)).Scan(&same)
+	if err != nil {
+		return fmt.Errorf("error saving spoof %s: %w", spoof.Slug, translateError(err))
+	}
+	if !same {
+		return fmt.Errorf("error saving spoof %s: %w: another spoof is saved", spoof.Slug, ErrConflict)
+	}
+	return nil
+}
+
+func (r *PostgresRepo) UpdateSpoof(ctx context.Context, spoof domain.Spoof) error {
+	const query = `
+		UPDATE spoofs
+		SET
+			rating = $2, content = $3, whats_true = $4, whats_false = $5, inversion = $6, stale = FALSE,
+			status = $7,
+			status_changed_at = CASE WHEN status = $7 THEN status_changed_at ELSE NOW() END,
+			edited_at = NULL
+		WHERE slug = $1
+	`
+
+	res, err := r.q.ExecContext(
+		ctx,
+		query,
+		spoof.Slug,
+		spoof.Claim.Rating,
+		pq.Array(spoof.Content),
+		textArray(spoof.Claim.WhatsTrue),
+		textArray(spoof.Claim.WhatsFalse),
+		nullString(spoof.Inversion),
+		spoofStatus(spoof.Status),
+	)
+	if err != nil {
+		return fmt.Errorf("error updating spoof %s: %w", spoof.Slug, translateError(err))
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("error updating spoof %s: %w", spoof.Slug, ErrNotFound)
+	}
+	return nil
+}
+
+func (r *PostgresRepo) MarkSpoofStale(ctx context.Context, slug string) error {
+	const query = `UPDATE spoofs SET stale = TRUE WHERE slug = $1`
+
+	res, err := r.q.ExecContext(ctx, query, slug)
+	if err != nil {
+		return fmt.Errorf("error marking spoof %s as stale: %w", slug, translateError(err))
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("error marking spoof %s as stale: %w", slug, ErrNotFound)
+	}
+	return nil
 }
 
 func (r *PostgresRepo) GetSpoof(ctx context.Context, slug string) (domain.Spoof, error) {
@@ -61,14 +496,26 @@ func (r *PostgresRepo) GetSpoof(ctx context.Context, slug string) (domain.Spoof,
 			articles.question,
 			spoofs.rating,
 			articles.context,
-			spoofs.content
+			spoofs.content,
+			articles.updated_date,
+			articles.tags,
+			spoofs.whats_true,
+			spoofs.whats_false,
+			articles.category,
+			articles.image_url,
+			articles.image_alt,
+			spoofs.inversion,
+			spoofs.status
 		FROM spoofs
 		JOIN articles ON articles.slug = spoofs.slug
 		WHERE spoofs.slug = $1
 	`
 
+	// The authors and sources belong to the original article, so a spoof doesn't have them.
 	var spoof domain.Spoof
-	if err := r.db.QueryRowContext(ctx, query, slug).Scan(
+	var metadata metadataColumns
+	var inversion sql.NullString
+	if err := r.q.QueryRowContext(ctx, query, slug).Scan(
 		&spoof.Slug,
 		&spoof.Title,
 		&spoof.Subtitle,
@@ -77,9 +524,21 @@ func (r *PostgresRepo) GetSpoof(ctx context.Context, slug string) (domain.Spoof,
 		&spoof.Claim.Rating,
 		&spoof.Claim.Context,
 		pq.Array(&spoof.Content),
+		&spoof.Updated,
+		pq.Array(&spoof.Tags),
+		pq.Array(&spoof.Claim.WhatsTrue),
+		pq.Array(&spoof.Claim.WhatsFalse),
+		&metadata.category,
+		&metadata.imageURL,
+		&metadata.imageAlt,
+		&inversion,
+		&spoof.Status,
 	); err != nil {
-		return domain.Spoof{}, err
+		return domain.Spoof{}, fmt.Errorf("error getting spoof %s: %w", slug, translateError(err))
 	}
+	// Sources weren't scanned, so this can't fail.
+	_ = metadata.apply(&spoof.Article)
+	spoof.Inversion = inversion.String
 
 	return spoof, nil
 }
@@ -93,11 +552,12 @@ func (r *PostgresRepo) GetLatestSpoofStubs(ctx context.Context) ([]domain.SpoofS
 			articles.date
 		FROM spoofs
 		JOIN articles ON articles.slug = spoofs.slug
+		WHERE spoofs.status = 'published'
 		ORDER BY articles.date DESC
 		LIMIT 21
 	`
 
-	rows, err := r.db.QueryContext(ctx, query)
+	rows, err := r.q.QueryContext(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("error querying for latest spoof stubs: %w", err)
 	}
@@ -140,7 +600,7 @@ func (r *PostgresRepo) GetAllNotExistingSpoofSlugs(ctx context.Context, slugs []
 		ORDER BY i.idx
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, pq.Array(slugs))
+	rows, err := r.q.QueryContext(ctx, query, pq.Array(slugs))
 	if err != nil {
 		return nil, fmt.Errorf("error querying for non-existing slugs: %w", err)
 	}
@@ -158,4 +618,187 @@ func (r *PostgresRepo) GetAllNotExistingSpoofSlugs(ctx context.Context, slugs []
 	return notExisting, nil
 }
 
+func (r *PostgresRepo) Search(ctx context.Context, query string, limit int) ([]domain.SearchResult, error) {
+	// websearch_to_tsquery accepts the same syntax people use in search engines,
+	// such as quoted phrases and -excluded words, and never fails on bad input.
+	//
+	// We match against both the original article and the spoof, but the snippet
+	// only ever comes from the spoof because that is what we publish.
+	// The content is escaped before it goes into ts_headline so that the only
+	// markup in the snippet is the <mark> tags that ts_headline adds.
+	const q = `
+		WITH q AS (
+			SELECT websearch_to_tsquery('english', $1) AS query
+		)
+		SELECT
+			spoofs.slug,
+			articles.title,
+			articles.subtitle,
+			articles.date,
+			ts_rank(articles.search || spoofs.search, q.query) AS rank,
+			ts_headline(
+				'english',
+				replace(replace(replace(
+					array_to_string(spoofs.content::text[], ' '),
+					'&', '&amp;'), '<', '&lt;'), '>', '&gt;'),
+				q.query,
+				'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=30, MinWords=10'
+			) AS snippet
+		FROM
+			spoofs
+			JOIN articles ON articles.slug = spoofs.slug,
+			q
+		WHERE
+			spoofs.status = 'published'
+			AND (articles.search @@ q.query OR spoofs.search @@ q.query)
+		ORDER BY rank DESC, articles.date DESC
+		LIMIT $2
+	`
+
+	rows, err := r.q.QueryContext(ctx, q, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error querying for search results: %w", err)
+	}
+	defer rows.Close()
+
+	var results []domain.SearchResult
+	for rows.Next() {
+		var result domain.SearchResult
+		if err := rows.Scan(
+			&result.Slug,
+			&result.Title,
+			&result.Subtitle,
+			&result.Date,
+			&result.Rank,
+			&result.Snippet,
+		); err != nil {
+			return nil, fmt.Errorf("error scanning search results: %w", err)
+		}
+		results = append(results, result)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating search results: %w", err)
+	}
+
+	return results, nil
+}
+
+func (r *PostgresRepo) UpdateSpoofStatus(ctx context.Context, slug string, from, to domain.SpoofStatus) error {
+	const query = `
+		UPDATE spoofs
+		SET status = $3, status_changed_at = NOW()
+		WHERE slug = $1 AND status = $2
+	`
+
+	res, err := r.q.ExecContext(ctx, query, slug, from, to)
+	if err != nil {
+		return fmt.Errorf("error updating status of spoof %s: %w", slug, translateError(err))
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 1 {
+		return nil
+	}
+
+	// Nothing was updated, so either there is no such spoof, or someone else changed its status first.
+	var current domain.SpoofStatus
+	if err := r.q.QueryRowContext(ctx, `SELECT status FROM spoofs WHERE slug = $1`, slug).Scan(&current); err != nil {
+		return fmt.Errorf("error updating status of spoof %s: %w", slug, translateError(err))
+	}
+	return fmt.Errorf("error updating status of spoof %s: %w: it is %s, not %s", slug, ErrConflict, current, from)
+}
+
+func (r *PostgresRepo) EditSpoofContent(ctx context.Context, slug string, content []string) error {
+	// A published spoof goes back to review, so that the public never sees an edit that nobody checked.
+	const query = `
+		UPDATE spoofs
+		SET
+			content = $2, edited_at = NOW(),
+			status = CASE WHEN status = $3 THEN $4 ELSE status END,
+			status_changed_at = CASE WHEN status = $3 THEN NOW() ELSE status_changed_at END
+		WHERE slug = $1
+	`
+
+	res, err := r.q.ExecContext(ctx, query, slug, pq.Array(content), domain.SpoofPublished, domain.SpoofReview)
+	if err != nil {
+		return fmt.Errorf("error editing spoof %s: %w", slug, translateError(err))
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("error editing spoof %s: %w", slug, ErrNotFound)
+	}
+	return nil
+}
+
+func (r *PostgresRepo) DeleteSpoof(ctx context.Context, slug string) error {
+	res, err := r.q.ExecContext(ctx, `DELETE FROM spoofs WHERE slug = $1`, slug)
+	if err != nil {
+		return fmt.Errorf("error deleting spoof %s: %w", slug, translateError(err))
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("error deleting spoof %s: %w", slug, ErrNotFound)
+	}
+	return nil
+}
+
+func (r *PostgresRepo) ListArticles(ctx context.Context, filter domain.ArticleFilter) ([]domain.ArticleOverview, error) {
+	// Articles without a spoof are listed when there is no filter, so that editors can see the ones whose spoof was deleted.
+	const query = `
+		SELECT
+			articles.slug,
+			articles.title,
+			articles.date,
+			articles.rating,
+			spoofs.status,
+			spoofs.rating,
+			spoofs.stale,
+			spoofs.status_changed_at,
+			spoofs.edited_at
+		FROM articles
+		LEFT JOIN spoofs ON spoofs.slug = articles.slug
+		WHERE $1 = '' OR spoofs.status = $1
+		ORDER BY articles.date DESC, articles.slug
+		LIMIT $2 OFFSET $3
+	`
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+
+	rows, err := r.q.QueryContext(ctx, query, filter.SpoofStatus, limit, filter.Offset)
+	if err != nil {
+		return nil, fmt.Errorf("error querying for articles: %w", err)
+	}
+	defer rows.Close()
+
+	var overviews []domain.ArticleOverview
+	for rows.Next() {
+		var overview domain.ArticleOverview
+		var status, rating sql.NullString
+		var stale sql.NullBool
+		var statusChanged sql.NullTime
+		if err := rows.Scan(
+			&overview.Slug,
+			&overview.Title,
+			&overview.Date,
+			&overview.Rating,
+			&status,
+			&rating,
+			&stale,
+			&statusChanged,
+			&overview.Edited,
+		); err != nil {
+			return nil, fmt.Errorf("error scanning articles: %w", err)
+		}
+		overview.SpoofStatus = domain.SpoofStatus(status.String)
+		overview.SpoofRating = domain.Rating(rating.String)
+		overview.Stale = stale.Bool
+		overview.StatusChanged = statusChanged.Time
+		overviews = append(overviews, overview)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating articles: %w", err)
+	}
+
+	return overviews, nil
+}
+
 var _ Repo = &PostgresRepo{}
@@ -0,0 +1,93 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/glizzus/trf/internal/domain"
+)
+
+// jobColumns are the columns of spoof_jobs that the queries below read, in the order scanJob expects them.
+const jobColumns = `id, slug, regenerate, inversion, status, existed, error, created_at, finished_at`
+
+func (r *PostgresRepo) SaveJob(ctx context.Context, job domain.SpoofJob) error {
+	const query = `
+		INSERT INTO spoof_jobs (id, slug, regenerate, inversion, status, existed, error, created_at, finished_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+
+	_, err := r.q.ExecContext(
+		ctx,
+		query,
+		job.ID,
+		job.Slug,
+		job.Regenerate,
+		nullString(job.Inversion),
+		job.Status,
+		job.Existed,
+		nullString(job.Error),
+		utc(job.Created),
+		nullTime(job.Finished),
+	)
+	if err != nil {
+		return fmt.Errorf("error saving job %s: %w", job.ID, translateError(err))
+	}
+	return nil
+}
+
+func (r *PostgresRepo) UpdateJob(ctx context.Context, job domain.SpoofJob) error {
+	const query = `
+		UPDATE spoof_jobs
+		SET status = $2, existed = $3, error = $4, finished_at = $5
+		WHERE id = $1
+	`
+
+	res, err := r.q.ExecContext(ctx, query, job.ID, job.Status, job.Existed, nullString(job.Error), nullTime(job.Finished))
+	if err != nil {
+		return fmt.Errorf("error updating job %s: %w", job.ID, translateError(err))
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("error updating job %s: %w", job.ID, ErrNotFound)
+	}
+	return nil
+}
+
+func (r *PostgresRepo) GetJob(ctx context.Context, id string) (domain.SpoofJob, error) {
+	const query = `SELECT ` + jobColumns + ` FROM spoof_jobs WHERE id = $1`
+
+	var job domain.SpoofJob
+	var inversion, jobError sql.NullString
+	var finished sql.NullTime
+	if err := r.q.QueryRowContext(ctx, query, id).Scan(
+		&job.ID,
+		&job.Slug,
+		&job.Regenerate,
+		&inversion,
+		&job.Status,
+		&job.Existed,
+		&jobError,
+		&job.Created,
+		&finished,
+	); err != nil {
+		return domain.SpoofJob{}, fmt.Errorf("error getting job %s: %w", id, translateError(err))
+	}
+	job.Inversion, job.Error = inversion.String, jobError.String
+	if finished.Valid {
+		job.Finished = &finished.Time
+	}
+	return job, nil
+}
+
+func (r *PostgresRepo) DeleteJobsBefore(ctx context.Context, before time.Time) (int64, error) {
+	res, err := r.q.ExecContext(ctx, `DELETE FROM spoof_jobs WHERE created_at < $1`, utc(before))
+	if err != nil {
+		return 0, fmt.Errorf("error deleting old jobs: %w", translateError(err))
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("error counting old jobs: %w", err)
+	}
+	return n, nil
+}
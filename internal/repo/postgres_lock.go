@@ -0,0 +1,65 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"hash/fnv"
+)
+
+// advisoryLock is a session-level advisory lock, held by a connection of its own.
+// Postgres releases it when the connection closes, so an instance that dies doesn't keep it.
+type advisoryLock struct {
+	conn *sql.Conn
+	name string
+}
+
+// lockKey returns the key of the advisory lock with the given name.
+func lockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return int64(h.Sum64())
+}
+
+// TryLock takes the advisory lock with the given name, if no other session holds it.
+func (r *PostgresRepo) TryLock(ctx context.Context, name string) (Lock, bool, error) {
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("error getting connection: %w", err)
+	}
+
+	var locked bool
+	err = conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1::bigint)`, lockKey(name)).Scan(&locked)
+	if err != nil {
+		discard(conn)
+		return nil, false, fmt.Errorf("error taking advisory lock %s: %w", name, err)
+	}
+	if !locked {
+		conn.Close()
+		return nil, false, nil
+	}
+	return &advisoryLock{conn: conn, name: name}, true, nil
+}
+
+// Check pings the connection that holds the lock. If it is broken, Postgres will release the lock.
+func (l *advisoryLock) Check(ctx context.Context) error {
+	if err := l.conn.PingContext(ctx); err != nil {
+		return fmt.Errorf("error checking advisory lock %s: %w", l.name, err)
+	}
+	return nil
+}
+
+// Unlock closes the session that holds the lock, which releases it even if the connection is broken.
+func (l *advisoryLock) Unlock(ctx context.Context) error {
+	discard(l.conn)
+	return nil
+}
+
+// discard closes conn instead of returning it to the pool, which ends its session.
+// database/sql closes connections that Raw reports as bad.
+func discard(conn *sql.Conn) {
+	conn.Raw(func(any) error { return driver.ErrBadConn })
+}
+
+var _ Locker = &PostgresRepo{}
@@ -0,0 +1,55 @@
+package repo_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	"github.com/glizzus/trf/internal/repo"
+	"github.com/glizzus/trf/internal/repo/repotest"
+	"github.com/glizzus/trf/migrations"
+)
+
+// postgresDSNEnv names the variable with the DSN of a scratch PostgreSQL database to test against.
+// Every check empties it, so it must never be a database whose data matters.
+const postgresDSNEnv = "MINISTRY_TEST_POSTGRES_DSN"
+
+func TestPostgresRepo(t *testing.T) {
+	dsn := os.Getenv(postgresDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", postgresDSNEnv)
+	}
+
+	ctx := context.Background()
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	version, dirty, err := repo.NewPostgres(db).MigrationVersion(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := migrations.Latest(); version != want || dirty {
+		t.Fatalf("database is at migration %d (dirty: %t), want %d: apply the migrations first", version, dirty, want)
+	}
+
+	// A database with data in it is more likely a mistake than a scratch one, so it is left alone.
+	var rows int
+	if err := db.QueryRowContext(ctx, `SELECT (SELECT COUNT(*) FROM articles) + (SELECT COUNT(*) FROM users)`).Scan(&rows); err != nil {
+		t.Fatal(err)
+	}
+	if rows > 0 {
+		t.Fatalf("refusing to test against a database with %d articles and users, because the checks empty it", rows)
+	}
+
+	repotest.TestRepo(t, func(t *testing.T) repo.Repo {
+		const query = `TRUNCATE articles, article_revisions, spoofs, spoof_jobs, users, sessions, api_tokens RESTART IDENTITY CASCADE`
+		if _, err := db.ExecContext(ctx, query); err != nil {
+			t.Fatal(err)
+		}
+		return repo.NewPostgres(db)
+	})
+}
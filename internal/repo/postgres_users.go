@@ -0,0 +1,243 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/glizzus/trf/internal/domain"
+)
+
+// nowUTC is the current time in UTC. Expiry times are compared against it,
+// because their columns have no time zone.
+const nowUTC = `(NOW() AT TIME ZONE 'UTC')`
+
+// utc converts t before it is written to a column with no time zone,
+// which keeps t's wall clock and drops its offset.
+func utc(t time.Time) time.Time {
+	return t.UTC()
+}
+
+// nullTime converts a nil time into NULL.
+func nullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: utc(*t), Valid: true}
+}
+
+func scopeStrings(scopes []domain.Scope) []string {
+	s := make([]string, len(scopes))
+	for i, scope := range scopes {
+		s[i] = string(scope)
+	}
+	return s
+}
+
+func (r *PostgresRepo) SaveUser(ctx context.Context, user domain.User) (domain.User, error) {
+	const query = `
+		INSERT INTO users (username, password_hash, role)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at
+	`
+
+	if err := r.q.QueryRowContext(ctx, query, user.Username, user.PasswordHash, user.Role).Scan(&user.ID, &user.Created); err != nil {
+		return domain.User{}, fmt.Errorf("error saving user %s: %w", user.Username, translateError(err))
+	}
+	return user, nil
+}
+
+func (r *PostgresRepo) GetUser(ctx context.Context, username string) (domain.User, error) {
+	const query = `
+		SELECT id, username, password_hash, role, created_at
+		FROM users
+		WHERE username = $1
+	`
+
+	var user domain.User
+	if err := r.q.QueryRowContext(ctx, query, username).Scan(
+		&user.ID,
+		&user.Username,
+		&user.PasswordHash,
+		&user.Role,
+		&user.Created,
+	); err != nil {
+		return domain.User{}, fmt.Errorf("error getting user %s: %w", username, translateError(err))
+	}
+	return user, nil
+}
+
+func (r *PostgresRepo) SetPassword(ctx context.Context, username, passwordHash string) error {
+	// Whoever knew the old password must not stay signed in, so both happen together.
+	return r.inTx(ctx, func(r *PostgresRepo) error {
+		var id int64
+		err := r.q.QueryRowContext(ctx, `UPDATE users SET password_hash = $2 WHERE username = $1 RETURNING id`, username, passwordHash).Scan(&id)
+		if err != nil {
+			return fmt.Errorf("error setting password of user %s: %w", username, translateError(err))
+		}
+		if _, err := r.q.ExecContext(ctx, `DELETE FROM sessions WHERE user_id = $1`, id); err != nil {
+			return fmt.Errorf("error ending sessions of user %s: %w", username, translateError(err))
+		}
+		return nil
+	})
+}
+
+func (r *PostgresRepo) DeleteUser(ctx context.Context, username string) error {
+	// Sessions and API tokens are deleted by the cascade.
+	res, err := r.q.ExecContext(ctx, `DELETE FROM users WHERE username = $1`, username)
+	if err != nil {
+		return fmt.Errorf("error deleting user %s: %w", username, translateError(err))
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("error deleting user %s: %w", username, ErrNotFound)
+	}
+	return nil
+}
+
+func (r *PostgresRepo) SaveSession(ctx context.Context, session domain.Session) error {
+	const query = `
+		INSERT INTO sessions (token_hash, user_id, csrf_token, expires_at)
+		VALUES ($1, $2, $3, $4)
+	`
+
+	_, err := r.q.ExecContext(ctx, query, session.TokenHash, session.UserID, session.CSRFToken, utc(session.Expires))
+	if err != nil {
+		return fmt.Errorf("error saving session of user %d: %w", session.UserID, translateError(err))
+	}
+	return nil
+}
+
+func (r *PostgresRepo) GetSession(ctx context.Context, tokenHash string) (domain.Session, domain.User, error) {
+	const query = `
+		SELECT
+			sessions.token_hash,
+			sessions.user_id,
+			sessions.csrf_token,
+			sessions.created_at,
+			sessions.expires_at,
+			users.id,
+			users.username,
+			users.password_hash,
+			users.role,
+			users.created_at
+		FROM sessions
+		JOIN users ON users.id = sessions.user_id
+		WHERE sessions.token_hash = $1 AND sessions.expires_at > ` + nowUTC
+
+	var session domain.Session
+	var user domain.User
+	if err := r.q.QueryRowContext(ctx, query, tokenHash).Scan(
+		&session.TokenHash,
+		&session.UserID,
+		&session.CSRFToken,
+		&session.Created,
+		&session.Expires,
+		&user.ID,
+		&user.Username,
+		&user.PasswordHash,
+		&user.Role,
+		&user.Created,
+	); err != nil {
+		// The token hash is as good as a password, so it stays out of the error.
+		return domain.Session{}, domain.User{}, fmt.Errorf("error getting session: %w", translateError(err))
+	}
+	return session, user, nil
+}
+
+func (r *PostgresRepo) DeleteSession(ctx context.Context, tokenHash string) error {
+	if _, err := r.q.ExecContext(ctx, `DELETE FROM sessions WHERE token_hash = $1`, tokenHash); err != nil {
+		return fmt.Errorf("error deleting session: %w", translateError(err))
+	}
+	return nil
+}
+
+func (r *PostgresRepo) DeleteExpiredSessions(ctx context.Context) (int64, error) {
+	res, err := r.q.ExecContext(ctx, `DELETE FROM sessions WHERE expires_at <= `+nowUTC)
+	if err != nil {
+		return 0, fmt.Errorf("error deleting expired sessions: %w", translateError(err))
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("error counting expired sessions: %w", err)
+	}
+	return n, nil
+}
+
+func (r *PostgresRepo) SaveAPIToken(ctx context.Context, token domain.APIToken) (domain.APIToken, error) {
+	const query = `
+		INSERT INTO api_tokens (token_hash, user_id, name, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+
+	if err := r.q.QueryRowContext(
+		ctx,
+		query,
+		token.TokenHash,
+		token.UserID,
+		token.Name,
+		pq.Array(scopeStrings(token.Scopes)),
+		nullTime(token.Expires),
+	).Scan(&token.ID, &token.Created); err != nil {
+		return domain.APIToken{}, fmt.Errorf("error saving API token %q: %w", token.Name, translateError(err))
+	}
+	return token, nil
+}
+
+func (r *PostgresRepo) UseAPIToken(ctx context.Context, tokenHash string) (domain.APIToken, domain.User, error) {
+	const query = `
+		UPDATE api_tokens
+		SET last_used_at = NOW()
+		FROM users
+		WHERE
+			users.id = api_tokens.user_id
+			AND api_tokens.token_hash = $1
+			AND (api_tokens.expires_at IS NULL OR api_tokens.expires_at > ` + nowUTC + `)
+		RETURNING
+			api_tokens.id,
+			api_tokens.token_hash,
+			api_tokens.user_id,
+			api_tokens.name,
+			api_tokens.scopes,
+			api_tokens.created_at,
+			api_tokens.expires_at,
+			users.id,
+			users.username,
+			users.password_hash,
+			users.role,
+			users.created_at
+	`
+
+	var token domain.APIToken
+	var user domain.User
+	var scopes pq.StringArray
+	var expires sql.NullTime
+	if err := r.q.QueryRowContext(ctx, query, tokenHash).Scan(
+		&token.ID,
+		&token.TokenHash,
+		&token.UserID,
+		&token.Name,
+		&scopes,
+		&token.Created,
+		&expires,
+		&user.ID,
+		&user.Username,
+		&user.PasswordHash,
+		&user.Role,
+		&user.Created,
+	); err != nil {
+		return domain.APIToken{}, domain.User{}, fmt.Errorf("error using API token: %w", translateError(err))
+	}
+	for _, scope := range scopes {
+		token.Scopes = append(token.Scopes, domain.Scope(scope))
+	}
+	if expires.Valid {
+		token.Expires = &expires.Time
+	}
+	return token, user, nil
+}
+
+var _ UserRepo = &PostgresRepo{}
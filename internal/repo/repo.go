@@ -2,18 +2,128 @@ package repo
 
 import (
 	"context"
+	"time"
 
 	"github.com/glizzus/trf/internal/domain"
 )
 
 // Repo is an interface for interacting with the database.
 // It is responsible for saving and retrieving articles and spoofs.
+//
+// Implementations return errors wrapping ErrNotFound and ErrConflict
+// so that callers can tell them apart with errors.Is.
 type Repo interface {
+	// WithTx runs fn with a Repo whose calls all happen in one transaction,
+	// which is committed if fn returns nil and rolled back otherwise.
+	// Calling WithTx on the Repo that fn gets joins the same transaction.
+	WithTx(ctx context.Context, fn func(tx Repo) error) error
+
+	// SaveArticle saves a new article. Saving the same version again does nothing, so that saves can be retried.
+	// It returns ErrConflict if another version of the article is saved. Use UpdateArticle to replace it.
 	SaveArticle(ctx context.Context, article domain.Article) error
+	// GetArticle returns ErrNotFound if there is no article with the given slug.
+	GetArticle(ctx context.Context, slug string) (domain.Article, error)
+	// UpdateArticle replaces the article with the same slug, keeping the version
+	// it replaces in the article's revision history. It also marks the article as checked.
+	// It returns ErrNotFound if there is no such article.
+	UpdateArticle(ctx context.Context, article domain.Article) error
+	// MarkArticleChecked records that the article was checked for revisions and had none.
+	MarkArticleChecked(ctx context.Context, slug string) error
+	// GetArticleSlugsToRecheck returns the slugs of at most limit articles published since the given time,
+	// least recently checked first.
+	GetArticleSlugsToRecheck(ctx context.Context, since time.Time, limit int) ([]string, error)
+	// GetRatingCounts returns how many articles have each rating, most common first.
+	GetRatingCounts(ctx context.Context) ([]domain.RatingCount, error)
 
+	// SaveSpoof saves a new spoof. Saving the same spoof again does nothing, so that saves can be retried.
+	// It returns ErrConflict if another spoof of the article is saved. Use UpdateSpoof to replace it.
 	SaveSpoof(ctx context.Context, spoof domain.Spoof) error
+	// UpdateSpoof replaces the spoof with the same slug, including its status,
+	// and clears its stale flag and when it was edited.
+	// It returns ErrNotFound if there is no such spoof.
+	UpdateSpoof(ctx context.Context, spoof domain.Spoof) error
+	// MarkSpoofStale flags a spoof whose original changed its rating after it was spoofed.
+	MarkSpoofStale(ctx context.Context, slug string) error
+	// GetSpoof returns the spoof whatever its status.
+	// It returns ErrNotFound if there is no spoof with the given slug.
 	GetSpoof(ctx context.Context, slug string) (domain.Spoof, error)
+	// GetLatestSpoofStubs returns the latest published spoofs.
 	GetLatestSpoofStubs(ctx context.Context) ([]domain.SpoofStub, error)
 
 	GetAllNotExistingSpoofSlugs(ctx context.Context, slugs []string) ([]string, error)
+
+	// Search returns at most limit published spoofs matching the query, best match first.
+	Search(ctx context.Context, query string, limit int) ([]domain.SearchResult, error)
+
+	// UpdateSpoofStatus moves the spoof with the given slug from one status to another.
+	// It returns ErrNotFound if there is no such spoof, and ErrConflict if its status isn't from,
+	// so that two editors can't both act on the same spoof.
+	UpdateSpoofStatus(ctx context.Context, slug string, from, to domain.SpoofStatus) error
+	// EditSpoofContent replaces the content of a spoof with an editor's version.
+	// A published spoof is sent back to review, so that its new content is checked before the public sees it.
+	// It returns ErrNotFound if there is no such spoof.
+	EditSpoofContent(ctx context.Context, slug string, content []string) error
+	// DeleteSpoof deletes a spoof, but not its article.
+	// It returns ErrNotFound if there is no such spoof.
+	DeleteSpoof(ctx context.Context, slug string) error
+	// ListArticles returns articles with the status of their spoofs, newest first.
+	ListArticles(ctx context.Context, filter domain.ArticleFilter) ([]domain.ArticleOverview, error)
+
+	// SaveJob saves a new spoof requested on demand, so that every instance can look it up.
+	// It returns ErrConflict if a job with the same ID exists.
+	SaveJob(ctx context.Context, job domain.SpoofJob) error
+	// UpdateJob replaces the status, outcome and finish time of the job with the same ID.
+	// It returns ErrNotFound if there is no such job.
+	UpdateJob(ctx context.Context, job domain.SpoofJob) error
+	// GetJob returns ErrNotFound if there is no job with the given ID.
+	GetJob(ctx context.Context, id string) (domain.SpoofJob, error)
+	// DeleteJobsBefore deletes the jobs created before the given time, and returns how many there were.
+	DeleteJobsBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+// UserRepo stores users, their sessions and their API tokens.
+//
+// Like Repo, implementations return errors wrapping ErrNotFound and ErrConflict.
+type UserRepo interface {
+	// SaveUser saves a new user, and returns it with its ID.
+	// It returns ErrConflict if a user with the same username exists.
+	SaveUser(ctx context.Context, user domain.User) (domain.User, error)
+	// GetUser returns ErrNotFound if there is no user with the given username.
+	GetUser(ctx context.Context, username string) (domain.User, error)
+	// SetPassword replaces the password hash of a user, and ends all of their sessions.
+	// It returns ErrNotFound if there is no such user.
+	SetPassword(ctx context.Context, username, passwordHash string) error
+	// DeleteUser deletes a user with their sessions and API tokens.
+	// It returns ErrNotFound if there is no such user.
+	DeleteUser(ctx context.Context, username string) error
+
+	SaveSession(ctx context.Context, session domain.Session) error
+	// GetSession returns the session with the given token hash, and its user.
+	// It returns ErrNotFound if there is no such session, or if it has expired.
+	GetSession(ctx context.Context, tokenHash string) (domain.Session, domain.User, error)
+	// DeleteSession ends a session. Ending a session that doesn't exist is not an error.
+	DeleteSession(ctx context.Context, tokenHash string) error
+	// DeleteExpiredSessions deletes the sessions that have expired, and returns how many there were.
+	DeleteExpiredSessions(ctx context.Context) (int64, error)
+
+	// SaveAPIToken saves a new API token, and returns it with its ID.
+	SaveAPIToken(ctx context.Context, token domain.APIToken) (domain.APIToken, error)
+	// UseAPIToken returns the API token with the given hash and its user, and records that it was used.
+	// It returns ErrNotFound if there is no such token, or if it has expired.
+	UseAPIToken(ctx context.Context, tokenHash string) (domain.APIToken, domain.User, error)
+}
+
+// Locker takes locks that every instance sharing the database sees,
+// so that only one of them does something at a time.
+type Locker interface {
+	// TryLock takes the lock with the given name if no one else holds it, and returns whether it did.
+	TryLock(ctx context.Context, name string) (Lock, bool, error)
+}
+
+// Lock is held until it is unlocked, or until the connection that holds it is lost.
+type Lock interface {
+	// Check returns an error if the lock may have been lost.
+	Check(ctx context.Context) error
+	// Unlock releases the lock.
+	Unlock(ctx context.Context) error
 }
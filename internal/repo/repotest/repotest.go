@@ -0,0 +1,962 @@
+// Package repotest checks that an implementation of repo.Repo behaves the way its callers rely on,
+// like testing/fstest does for file systems. Every implementation must pass it:
+//
+//	func TestMemoryRepo(t *testing.T) {
+//		repotest.TestRepo(t, func(t *testing.T) repo.Repo {
+//			return repo.NewMemory()
+//		})
+//	}
+//
+// Each check is a subtest, so a single one can be run with go test -run, like -run 'TestSQLiteRepo/search'.
+//
+// The tests of package repo run it against every implementation we have.
+package repotest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glizzus/trf/internal/domain"
+	"github.com/glizzus/trf/internal/repo"
+)
+
+// check is one of the checks that TestRepo runs, on a repo of its own.
+type check struct {
+	name string
+	run  func(ctx context.Context, r repo.Repo) error
+}
+
+var checks = []check{
+	{"article not found", checkArticleNotFound},
+	{"article round trip", checkArticleRoundTrip},
+	{"article uniqueness", checkArticleUniqueness},
+	{"article update", checkArticleUpdate},
+	{"not existing slugs", checkNotExistingSlugs},
+	{"articles to recheck", checkArticlesToRecheck},
+	{"rating counts", checkRatingCounts},
+	{"spoof not found", checkSpoofNotFound},
+	{"spoof uniqueness", checkSpoofUniqueness},
+	{"spoof status", checkSpoofStatus},
+	{"edit spoof", checkEditSpoof},
+	{"latest stubs", checkLatestStubs},
+	{"delete spoof", checkDeleteSpoof},
+	{"search", checkSearch},
+	{"list articles", checkListArticles},
+	{"transactions", checkTransactions},
+	{"jobs", checkJobs},
+	{"users", checkUsers},
+}
+
+// TestRepo runs every check as a subtest of t, on a new repo from newRepo, which must be empty.
+// If the repo is also a repo.UserRepo, users, sessions and API tokens are checked too.
+func TestRepo(t *testing.T, newRepo func(t *testing.T) repo.Repo) {
+	for _, c := range checks {
+		t.Run(c.name, func(t *testing.T) {
+			if err := c.run(context.Background(), newRepo(t)); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+// testDate is the day of the articles that the checks make, unless they need different days.
+var testDate = time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+// testArticle returns an article with every field set.
+func testArticle(slug string, date time.Time) domain.Article {
+	claimContext := "Some context."
+	updated := date.AddDate(0, 0, 2)
+	return domain.Article{
+		Slug:     slug,
+		Title:    "Did a cat " + slug + " become mayor?",
+		Subtitle: "A town elected a cat.",
+		Authors:  []string{"Jane Doe"},
+		Date:     date,
+		Updated:  &updated,
+		Category: "Animals",
+		Tags:     []string{"cats", "elections"},
+		Image:    &domain.Image{URL: "https://example.com/cat.jpg", Alt: "A cat"},
+		Claim: domain.Claim{
+			Question:   "Did a cat become mayor?",
+			Rating:     "True",
+			Context:    &claimContext,
+			WhatsTrue:  []string{"A cat was elected."},
+			WhatsFalse: []string{"It has no powers."},
+		},
+		Content: []string{"The cat won the election.", "It was <b>unopposed</b>."},
+		Sources: []domain.Source{{Text: "The Town Gazette", URL: "https://example.com/gazette"}},
+	}
+}
+
+// testSpoof returns a spoof of article, with its own rating and content.
+func testSpoof(article domain.Article) domain.Spoof {
+	spoof := domain.Spoof{Article: article, Inversion: "mirror"}
+	spoof.Claim.Rating = "False"
+	spoof.Claim.WhatsTrue, spoof.Claim.WhatsFalse = article.Claim.WhatsFalse, article.Claim.WhatsTrue
+	spoof.Content = []string{"The dog won the election.", "It was opposed by <i>everyone</i>."}
+	return spoof
+}
+
+// saveArticles saves an article for each slug, the first on testDate and each of the others a day later.
+func saveArticles(ctx context.Context, r repo.Repo, slugs ...string) ([]domain.Article, error) {
+	var articles []domain.Article
+	for i, slug := range slugs {
+		article := testArticle(slug, testDate.AddDate(0, 0, i))
+		if err := r.SaveArticle(ctx, article); err != nil {
+			return nil, fmt.Errorf("SaveArticle(%s): %w", slug, err)
+		}
+		articles = append(articles, article)
+	}
+	return articles, nil
+}
+
+// wantErr returns an error unless err wraps want.
+func wantErr(call string, err, want error) error {
+	if !errors.Is(err, want) {
+		return fmt.Errorf("%s returned %v, want %v", call, err, want)
+	}
+	return nil
+}
+
+// normalize returns article as a repo gives it back: dates are days in UTC,
+// and nil lists are empty, because databases don't tell them apart.
+func normalize(article domain.Article) domain.Article {
+	utc := func(t time.Time) time.Time {
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	strs := func(s []string) []string {
+		return append([]string{}, s...)
+	}
+
+	article.Date = utc(article.Date)
+	if article.Updated != nil {
+		updated := utc(*article.Updated)
+		article.Updated = &updated
+	}
+	article.Authors = strs(article.Authors)
+	article.Tags = strs(article.Tags)
+	article.Content = strs(article.Content)
+	article.Claim.WhatsTrue = strs(article.Claim.WhatsTrue)
+	article.Claim.WhatsFalse = strs(article.Claim.WhatsFalse)
+	article.Sources = append([]domain.Source{}, article.Sources...)
+	return article
+}
+
+// sameArticle returns an error unless got is want, as far as a repo can keep it.
+func sameArticle(call string, got, want domain.Article) error {
+	got, want = normalize(got), normalize(want)
+	if !reflect.DeepEqual(got, want) {
+		return fmt.Errorf("%s returned %+v, want %+v", call, got, want)
+	}
+	return nil
+}
+
+func checkArticleNotFound(ctx context.Context, r repo.Repo) error {
+	_, err := r.GetArticle(ctx, "missing")
+	if err := wantErr("GetArticle", err, repo.ErrNotFound); err != nil {
+		return err
+	}
+	if err := wantErr("UpdateArticle", r.UpdateArticle(ctx, testArticle("missing", testDate)), repo.ErrNotFound); err != nil {
+		return err
+	}
+	return wantErr("MarkArticleChecked", r.MarkArticleChecked(ctx, "missing"), repo.ErrNotFound)
+}
+
+func checkArticleRoundTrip(ctx context.Context, r repo.Repo) error {
+	// The time of day and the time zone are lost, because articles are dated by the day.
+	article := testArticle("round-trip", testDate.Add(15*time.Hour))
+	if err := r.SaveArticle(ctx, article); err != nil {
+		return fmt.Errorf("SaveArticle: %w", err)
+	}
+	got, err := r.GetArticle(ctx, article.Slug)
+	if err != nil {
+		return fmt.Errorf("GetArticle: %w", err)
+	}
+	if err := sameArticle("GetArticle", got, article); err != nil {
+		return err
+	}
+
+	// The optional fields come back empty.
+	bare := domain.Article{Slug: "bare", Title: "Bare", Date: testDate, Claim: domain.Claim{Rating: "True"}}
+	if err := r.SaveArticle(ctx, bare); err != nil {
+		return fmt.Errorf("SaveArticle: %w", err)
+	}
+	got, err = r.GetArticle(ctx, bare.Slug)
+	if err != nil {
+		return fmt.Errorf("GetArticle: %w", err)
+	}
+	return sameArticle("GetArticle", got, bare)
+}
+
+func checkArticleUniqueness(ctx context.Context, r repo.Repo) error {
+	articles, err := saveArticles(ctx, r, "unique")
+	if err != nil {
+		return err
+	}
+	article := articles[0]
+
+	// Saving the same version again is a retry, and does nothing.
+	if err := r.SaveArticle(ctx, article); err != nil {
+		return fmt.Errorf("SaveArticle of the same version returned %w, want nil", err)
+	}
+
+	revised := article
+	revised.Content = []string{"The cat lost the election."}
+	if err := wantErr("SaveArticle of another version", r.SaveArticle(ctx, revised), repo.ErrConflict); err != nil {
+		return err
+	}
+	got, err := r.GetArticle(ctx, article.Slug)
+	if err != nil {
+		return fmt.Errorf("GetArticle: %w", err)
+	}
+	return sameArticle("GetArticle after a conflict", got, article)
+}
+
+func checkArticleUpdate(ctx context.Context, r repo.Repo) error {
+	articles, err := saveArticles(ctx, r, "updated")
+	if err != nil {
+		return err
+	}
+	revised := articles[0]
+	revised.Content = []string{"The cat lost the election."}
+	revised.Claim.Rating = "False"
+	if err := r.UpdateArticle(ctx, revised); err != nil {
+		return fmt.Errorf("UpdateArticle: %w", err)
+	}
+	got, err := r.GetArticle(ctx, revised.Slug)
+	if err != nil {
+		return fmt.Errorf("GetArticle: %w", err)
+	}
+	if err := sameArticle("GetArticle after UpdateArticle", got, revised); err != nil {
+		return err
+	}
+	// Saving the revised version is now a retry.
+	if err := r.SaveArticle(ctx, revised); err != nil {
+		return fmt.Errorf("SaveArticle of the updated version returned %w, want nil", err)
+	}
+	return nil
+}
+
+func checkNotExistingSlugs(ctx context.Context, r repo.Repo) error {
+	if _, err := saveArticles(ctx, r, "b", "d"); err != nil {
+		return err
+	}
+
+	// Ingest relies on the order, and on repeated slugs being kept.
+	got, err := r.GetAllNotExistingSpoofSlugs(ctx, []string{"e", "a", "b", "c", "d", "a"})
+	if err != nil {
+		return fmt.Errorf("GetAllNotExistingSpoofSlugs: %w", err)
+	}
+	if want := []string{"e", "a", "c", "a"}; !slices.Equal(got, want) {
+		return fmt.Errorf("GetAllNotExistingSpoofSlugs returned %q, want %q", got, want)
+	}
+
+	got, err = r.GetAllNotExistingSpoofSlugs(ctx, []string{"d", "b"})
+	if err != nil {
+		return fmt.Errorf("GetAllNotExistingSpoofSlugs: %w", err)
+	}
+	if len(got) != 0 {
+		return fmt.Errorf("GetAllNotExistingSpoofSlugs of saved slugs returned %q, want none", got)
+	}
+
+	got, err = r.GetAllNotExistingSpoofSlugs(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("GetAllNotExistingSpoofSlugs of no slugs: %w", err)
+	}
+	if len(got) != 0 {
+		return fmt.Errorf("GetAllNotExistingSpoofSlugs of no slugs returned %q, want none", got)
+	}
+	return nil
+}
+
+func checkArticlesToRecheck(ctx context.Context, r repo.Repo) error {
+	articles, err := saveArticles(ctx, r, "old", "checked-last", "checked-first")
+	if err != nil {
+		return err
+	}
+	for _, slug := range []string{"checked-first", "checked-last"} {
+		if err := r.MarkArticleChecked(ctx, slug); err != nil {
+			return fmt.Errorf("MarkArticleChecked(%s): %w", slug, err)
+		}
+	}
+
+	// Articles published before the window are left alone, and the least recently checked go first.
+	since := articles[1].Date
+	got, err := r.GetArticleSlugsToRecheck(ctx, since, 10)
+	if err != nil {
+		return fmt.Errorf("GetArticleSlugsToRecheck: %w", err)
+	}
+	if want := []string{"checked-first", "checked-last"}; !slices.Equal(got, want) {
+		return fmt.Errorf("GetArticleSlugsToRecheck returned %q, want %q", got, want)
+	}
+	got, err = r.GetArticleSlugsToRecheck(ctx, since, 1)
+	if err != nil {
+		return fmt.Errorf("GetArticleSlugsToRecheck: %w", err)
+	}
+	if want := []string{"checked-first"}; !slices.Equal(got, want) {
+		return fmt.Errorf("GetArticleSlugsToRecheck with a limit of 1 returned %q, want %q", got, want)
+	}
+
+	// Updating an article checks it too.
+	updated := articles[2]
+	updated.Content = []string{"The cat resigned."}
+	if err := r.UpdateArticle(ctx, updated); err != nil {
+		return fmt.Errorf("UpdateArticle: %w", err)
+	}
+	got, err = r.GetArticleSlugsToRecheck(ctx, since, 10)
+	if err != nil {
+		return fmt.Errorf("GetArticleSlugsToRecheck: %w", err)
+	}
+	if want := []string{"checked-last", "checked-first"}; !slices.Equal(got, want) {
+		return fmt.Errorf("GetArticleSlugsToRecheck after UpdateArticle returned %q, want %q", got, want)
+	}
+
+	err = r.MarkArticleChecked(ctx, "missing")
+	return wantErr("MarkArticleChecked of a missing article", err, repo.ErrNotFound)
+}
+
+func checkRatingCounts(ctx context.Context, r repo.Repo) error {
+	counts, err := r.GetRatingCounts(ctx)
+	if err != nil {
+		return fmt.Errorf("GetRatingCounts: %w", err)
+	}
+	if len(counts) != 0 {
+		return fmt.Errorf("GetRatingCounts of no articles returned %+v, want none", counts)
+	}
+
+	// Three True, and two each of False and Mixture, which are ordered by name.
+	// The latest Mixture articles are on the same day, where the first by slug wins.
+	ratings := []struct {
+		slug   string
+		rating domain.Rating
+		day    int
+	}{
+		{"true-1", "True", 0},
+		{"true-2", "True", 4},
+		{"true-3", "True", 2},
+		{"mixture-b", "Mixture", 3},
+		{"mixture-a", "Mixture", 3},
+		{"false-1", "False", 5},
+		{"false-2", "False", 1},
+	}
+	for _, a := range ratings {
+		article := testArticle(a.slug, testDate.AddDate(0, 0, a.day))
+		article.Claim.Rating = a.rating
+		if err := r.SaveArticle(ctx, article); err != nil {
+			return fmt.Errorf("SaveArticle(%s): %w", a.slug, err)
+		}
+	}
+
+	counts, err = r.GetRatingCounts(ctx)
+	if err != nil {
+		return fmt.Errorf("GetRatingCounts: %w", err)
+	}
+	want := []domain.RatingCount{
+		{Rating: "True", Articles: 3, LatestSlug: "true-2", LatestDate: testDate.AddDate(0, 0, 4)},
+		{Rating: "False", Articles: 2, LatestSlug: "false-1", LatestDate: testDate.AddDate(0, 0, 5)},
+		{Rating: "Mixture", Articles: 2, LatestSlug: "mixture-a", LatestDate: testDate.AddDate(0, 0, 3)},
+	}
+	same := len(counts) == len(want)
+	for i := 0; same && i < len(want); i++ {
+		got := counts[i]
+		same = got.Rating == want[i].Rating && got.Articles == want[i].Articles &&
+			got.LatestSlug == want[i].LatestSlug && got.LatestDate.Equal(want[i].LatestDate)
+	}
+	if !same {
+		return fmt.Errorf("GetRatingCounts returned %+v, want %+v", counts, want)
+	}
+	return nil
+}
+
+func checkSpoofNotFound(ctx context.Context, r repo.Repo) error {
+	// The article exists, but has no spoof.
+	articles, err := saveArticles(ctx, r, "unspoofed")
+	if err != nil {
+		return err
+	}
+
+	for _, slug := range []string{"missing", "unspoofed"} {
+		_, err := r.GetSpoof(ctx, slug)
+		if err := wantErr("GetSpoof("+slug+")", err, repo.ErrNotFound); err != nil {
+			return err
+		}
+	}
+	spoof := testSpoof(articles[0])
+	for call, err := range map[string]error{
+		"UpdateSpoof":       r.UpdateSpoof(ctx, spoof),
+		"MarkSpoofStale":    r.MarkSpoofStale(ctx, spoof.Slug),
+		"EditSpoofContent":  r.EditSpoofContent(ctx, spoof.Slug, spoof.Content),
+		"DeleteSpoof":       r.DeleteSpoof(ctx, spoof.Slug),
+		"UpdateSpoofStatus": r.UpdateSpoofStatus(ctx, spoof.Slug, domain.SpoofDraft, domain.SpoofReview),
+	} {
+		if err := wantErr(call, err, repo.ErrNotFound); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkSpoofUniqueness(ctx context.Context, r repo.Repo) error {
+	articles, err := saveArticles(ctx, r, "spoofed")
+	if err != nil {
+		return err
+	}
+	spoof := testSpoof(articles[0])
+	if err := r.SaveSpoof(ctx, spoof); err != nil {
+		return fmt.Errorf("SaveSpoof: %w", err)
+	}
+	if err := r.SaveSpoof(ctx, spoof); err != nil {
+		return fmt.Errorf("SaveSpoof of the same spoof returned %w, want nil", err)
+	}
+
+	other := spoof
+	other.Content = []string{"The mouse won the election."}
+	if err := wantErr("SaveSpoof of another spoof", r.SaveSpoof(ctx, other), repo.ErrConflict); err != nil {
+		return err
+	}
+
+	got, err := r.GetSpoof(ctx, spoof.Slug)
+	if err != nil {
+		return fmt.Errorf("GetSpoof: %w", err)
+	}
+	if !slices.Equal(got.Content, spoof.Content) || got.Claim.Rating != spoof.Claim.Rating || got.Inversion != spoof.Inversion {
+		return fmt.Errorf("GetSpoof returned %+v, want %+v", got, spoof)
+	}
+	// Spoofs are drafts unless they say otherwise.
+	if got.Status != domain.SpoofDraft {
+		return fmt.Errorf("GetSpoof returned status %q, want %q", got.Status, domain.SpoofDraft)
+	}
+	return nil
+}
+
+func checkSpoofStatus(ctx context.Context, r repo.Repo) error {
+	articles, err := saveArticles(ctx, r, "reviewed")
+	if err != nil {
+		return err
+	}
+	spoof := testSpoof(articles[0])
+	if err := r.SaveSpoof(ctx, spoof); err != nil {
+		return fmt.Errorf("SaveSpoof: %w", err)
+	}
+
+	if err := r.UpdateSpoofStatus(ctx, spoof.Slug, domain.SpoofDraft, domain.SpoofReview); err != nil {
+		return fmt.Errorf("UpdateSpoofStatus: %w", err)
+	}
+	// A second editor acting on the same draft loses.
+	err = r.UpdateSpoofStatus(ctx, spoof.Slug, domain.SpoofDraft, domain.SpoofReview)
+	if err := wantErr("UpdateSpoofStatus from a status the spoof doesn't have", err, repo.ErrConflict); err != nil {
+		return err
+	}
+
+	got, err := r.GetSpoof(ctx, spoof.Slug)
+	if err != nil {
+		return fmt.Errorf("GetSpoof: %w", err)
+	}
+	if got.Status != domain.SpoofReview {
+		return fmt.Errorf("GetSpoof returned status %q, want %q", got.Status, domain.SpoofReview)
+	}
+	return nil
+}
+
+func checkEditSpoof(ctx context.Context, r repo.Repo) error {
+	err := r.EditSpoofContent(ctx, "missing", []string{"Nothing."})
+	if err := wantErr("EditSpoofContent of a missing spoof", err, repo.ErrNotFound); err != nil {
+		return err
+	}
+
+	articles, err := saveArticles(ctx, r, "drafted", "published")
+	if err != nil {
+		return err
+	}
+	// An edited draft stays a draft, but an edited published spoof must be reviewed again before the public sees it.
+	want := map[string]domain.SpoofStatus{"drafted": domain.SpoofDraft, "published": domain.SpoofReview}
+	for _, article := range articles {
+		spoof := testSpoof(article)
+		if article.Slug == "published" {
+			spoof.Status = domain.SpoofPublished
+		}
+		if err := r.SaveSpoof(ctx, spoof); err != nil {
+			return fmt.Errorf("SaveSpoof(%s): %w", spoof.Slug, err)
+		}
+
+		content := []string{"The hamster won the election."}
+		if err := r.EditSpoofContent(ctx, spoof.Slug, content); err != nil {
+			return fmt.Errorf("EditSpoofContent(%s): %w", spoof.Slug, err)
+		}
+		got, err := r.GetSpoof(ctx, spoof.Slug)
+		if err != nil {
+			return fmt.Errorf("GetSpoof(%s): %w", spoof.Slug, err)
+		}
+		if !slices.Equal(got.Content, content) || got.Status != want[spoof.Slug] {
+			return fmt.Errorf("GetSpoof(%s) after EditSpoofContent returned content %q and status %q, want %q and %q",
+				spoof.Slug, got.Content, got.Status, content, want[spoof.Slug])
+		}
+	}
+
+	stubs, err := r.GetLatestSpoofStubs(ctx)
+	if err != nil {
+		return fmt.Errorf("GetLatestSpoofStubs: %w", err)
+	}
+	if len(stubs) != 0 {
+		return fmt.Errorf("GetLatestSpoofStubs after EditSpoofContent returned %+v, want none", stubs)
+	}
+	return nil
+}
+
+func checkLatestStubs(ctx context.Context, r repo.Repo) error {
+	// More published spoofs than fit on the page, and a draft that is newer than all of them.
+	var slugs []string
+	for i := range 23 {
+		slugs = append(slugs, fmt.Sprintf("spoof-%02d", i))
+	}
+	articles, err := saveArticles(ctx, r, append(slugs, "draft")...)
+	if err != nil {
+		return err
+	}
+	// The spoofs are saved out of order, so that the order they were saved in can't pass for the order of their dates.
+	for i := range articles {
+		article := articles[i*7%len(articles)]
+		spoof := testSpoof(article)
+		if article.Slug != "draft" {
+			spoof.Status = domain.SpoofPublished
+		}
+		if err := r.SaveSpoof(ctx, spoof); err != nil {
+			return fmt.Errorf("SaveSpoof(%s): %w", spoof.Slug, err)
+		}
+	}
+
+	stubs, err := r.GetLatestSpoofStubs(ctx)
+	if err != nil {
+		return fmt.Errorf("GetLatestSpoofStubs: %w", err)
+	}
+	var got []string
+	for _, stub := range stubs {
+		got = append(got, stub.Slug)
+	}
+	// The newest first, and only as many as fit on the page.
+	var want []string
+	for i := 22; i > 1; i-- {
+		want = append(want, slugs[i])
+	}
+	if !slices.Equal(got, want) {
+		return fmt.Errorf("GetLatestSpoofStubs returned %q, want %q", got, want)
+	}
+
+	newest := articles[22]
+	if stubs[0].Title != newest.Title || stubs[0].Subtitle != newest.Subtitle {
+		return fmt.Errorf("GetLatestSpoofStubs returned %+v, want the title and subtitle of %s", stubs[0], newest.Slug)
+	}
+	if date, err := time.Parse(time.RFC3339, stubs[0].Date); err != nil || !date.Equal(newest.Date) {
+		return fmt.Errorf("GetLatestSpoofStubs returned date %q, want %s", stubs[0].Date, newest.Date.Format(time.RFC3339))
+	}
+	return nil
+}
+
+func checkDeleteSpoof(ctx context.Context, r repo.Repo) error {
+	articles, err := saveArticles(ctx, r, "deleted")
+	if err != nil {
+		return err
+	}
+	spoof := testSpoof(articles[0])
+	spoof.Status = domain.SpoofPublished
+	if err := r.SaveSpoof(ctx, spoof); err != nil {
+		return fmt.Errorf("SaveSpoof: %w", err)
+	}
+	if err := r.DeleteSpoof(ctx, spoof.Slug); err != nil {
+		return fmt.Errorf("DeleteSpoof: %w", err)
+	}
+
+	_, err = r.GetSpoof(ctx, spoof.Slug)
+	if err := wantErr("GetSpoof after DeleteSpoof", err, repo.ErrNotFound); err != nil {
+		return err
+	}
+	stubs, err := r.GetLatestSpoofStubs(ctx)
+	if err != nil {
+		return fmt.Errorf("GetLatestSpoofStubs: %w", err)
+	}
+	if len(stubs) != 0 {
+		return fmt.Errorf("GetLatestSpoofStubs after DeleteSpoof returned %+v, want none", stubs)
+	}
+
+	// The article stays, so that ingest doesn't spoof it again.
+	if _, err := r.GetArticle(ctx, spoof.Slug); err != nil {
+		return fmt.Errorf("GetArticle after DeleteSpoof: %w", err)
+	}
+	notExisting, err := r.GetAllNotExistingSpoofSlugs(ctx, []string{spoof.Slug})
+	if err != nil {
+		return fmt.Errorf("GetAllNotExistingSpoofSlugs: %w", err)
+	}
+	if len(notExisting) != 0 {
+		return fmt.Errorf("GetAllNotExistingSpoofSlugs after DeleteSpoof returned %q, want none", notExisting)
+	}
+
+	// It can be spoofed again.
+	if err := r.SaveSpoof(ctx, spoof); err != nil {
+		return fmt.Errorf("SaveSpoof after DeleteSpoof: %w", err)
+	}
+	return nil
+}
+
+// searchSlugs returns the slugs of the results of searching for query.
+func searchSlugs(ctx context.Context, r repo.Repo, query string, limit int) ([]string, error) {
+	results, err := r.Search(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("Search(%q): %w", query, err)
+	}
+	var slugs []string
+	for _, result := range results {
+		slugs = append(slugs, result.Slug)
+	}
+	return slugs, nil
+}
+
+func checkSearch(ctx context.Context, r repo.Repo) error {
+	articles, err := saveArticles(ctx, r, "zebra", "yak", "walrus")
+	if err != nil {
+		return err
+	}
+	for _, article := range articles {
+		spoof := testSpoof(article)
+		switch article.Slug {
+		case "yak":
+			// The zebra is only in the content of this one, so it ranks below the one with a zebra in its title.
+			spoof.Content = []string{"The zebra won the election.", "It was opposed by <i>everyone</i>."}
+			spoof.Status = domain.SpoofPublished
+		case "zebra":
+			spoof.Status = domain.SpoofPublished
+		}
+		if err := r.SaveSpoof(ctx, spoof); err != nil {
+			return fmt.Errorf("SaveSpoof(%s): %w", spoof.Slug, err)
+		}
+	}
+
+	tests := []struct {
+		query string
+		limit int
+		want  []string
+		// tie is set when the results match equally well, which each database orders differently.
+		tie bool
+	}{
+		{"zebra", 10, []string{"zebra", "yak"}, false},
+		{"zebra", 1, []string{"zebra"}, false},
+		{"yak", 10, []string{"yak"}, false},
+		{"mayor", 10, []string{"yak", "zebra"}, true},
+		{"zebra -yak", 10, []string{"zebra"}, false},
+		{`"cat yak"`, 10, []string{"yak"}, false},
+		// The yak spoof has both words.
+		{"yak or zebra", 10, []string{"yak", "zebra"}, false},
+		// Only published spoofs are found.
+		{"walrus", 10, nil, false},
+		{"giraffe", 10, nil, false},
+	}
+	for _, tt := range tests {
+		got, err := searchSlugs(ctx, r, tt.query, tt.limit)
+		if err != nil {
+			return err
+		}
+		if tt.tie {
+			slices.Sort(got)
+		}
+		if !slices.Equal(got, tt.want) {
+			return fmt.Errorf("Search(%q, %d) returned %q, want %q", tt.query, tt.limit, got, tt.want)
+		}
+	}
+
+	results, err := r.Search(ctx, "everyone", 10)
+	if err != nil {
+		return fmt.Errorf("Search: %w", err)
+	}
+	if len(results) != 2 {
+		return fmt.Errorf("Search(%q) returned %+v, want both published spoofs", "everyone", results)
+	}
+	for _, result := range results {
+		// The content of the spoof is escaped, except for the marks around the matches.
+		if !strings.Contains(result.Snippet, "<mark>everyone</mark>") || strings.Contains(result.Snippet, "<i>") {
+			return fmt.Errorf("Search(%q) returned snippet %q, want the match marked and the rest escaped", "everyone", result.Snippet)
+		}
+		if result.Title != articles[0].Title && result.Title != articles[1].Title {
+			return fmt.Errorf("Search(%q) returned title %q, want the title of the spoof", "everyone", result.Title)
+		}
+	}
+	return nil
+}
+
+func checkListArticles(ctx context.Context, r repo.Repo) error {
+	// From oldest to newest: a draft, a published spoof, a spoof in review, and an article whose spoof was deleted.
+	articles, err := saveArticles(ctx, r, "drafted", "published", "reviewed", "deleted")
+	if err != nil {
+		return err
+	}
+	statuses := map[string]domain.SpoofStatus{
+		"drafted":   domain.SpoofDraft,
+		"published": domain.SpoofPublished,
+		"reviewed":  domain.SpoofReview,
+		"deleted":   domain.SpoofDraft,
+	}
+	for _, article := range articles {
+		spoof := testSpoof(article)
+		spoof.Status = statuses[article.Slug]
+		if err := r.SaveSpoof(ctx, spoof); err != nil {
+			return fmt.Errorf("SaveSpoof(%s): %w", spoof.Slug, err)
+		}
+	}
+	if err := r.DeleteSpoof(ctx, "deleted"); err != nil {
+		return fmt.Errorf("DeleteSpoof: %w", err)
+	}
+	if err := r.MarkSpoofStale(ctx, "reviewed"); err != nil {
+		return fmt.Errorf("MarkSpoofStale: %w", err)
+	}
+	if err := r.EditSpoofContent(ctx, "drafted", []string{"The hamster won the election."}); err != nil {
+		return fmt.Errorf("EditSpoofContent: %w", err)
+	}
+
+	tests := []struct {
+		filter domain.ArticleFilter
+		want   []string
+	}{
+		// Newest first, with the article without a spoof too.
+		{domain.ArticleFilter{}, []string{"deleted", "reviewed", "published", "drafted"}},
+		{domain.ArticleFilter{SpoofStatus: domain.SpoofPublished}, []string{"published"}},
+		{domain.ArticleFilter{SpoofStatus: domain.SpoofDraft}, []string{"drafted"}},
+		{domain.ArticleFilter{Limit: 2, Offset: 1}, []string{"reviewed", "published"}},
+		{domain.ArticleFilter{Offset: 10}, nil},
+	}
+	for _, tt := range tests {
+		overviews, err := r.ListArticles(ctx, tt.filter)
+		if err != nil {
+			return fmt.Errorf("ListArticles(%+v): %w", tt.filter, err)
+		}
+		var got []string
+		for _, overview := range overviews {
+			got = append(got, overview.Slug)
+		}
+		if !slices.Equal(got, tt.want) {
+			return fmt.Errorf("ListArticles(%+v) returned %q, want %q", tt.filter, got, tt.want)
+		}
+	}
+
+	overviews, err := r.ListArticles(ctx, domain.ArticleFilter{})
+	if err != nil {
+		return fmt.Errorf("ListArticles: %w", err)
+	}
+	bySlug := make(map[string]domain.ArticleOverview)
+	for _, overview := range overviews {
+		bySlug[overview.Slug] = overview
+	}
+	for _, article := range articles {
+		got := bySlug[article.Slug]
+		if got.Title != article.Title || got.Rating != article.Claim.Rating || !got.Date.Equal(article.Date) {
+			return fmt.Errorf("ListArticles returned %+v, want the title, rating and date of %s", got, article.Slug)
+		}
+	}
+	if got := bySlug["deleted"]; got.SpoofStatus != "" || got.SpoofRating != "" {
+		return fmt.Errorf("ListArticles returned %+v for an article without a spoof, want no spoof status or rating", got)
+	}
+	if got := bySlug["reviewed"]; got.SpoofStatus != domain.SpoofReview || got.SpoofRating != "False" || !got.Stale || got.Edited != nil {
+		return fmt.Errorf("ListArticles returned %+v, want a stale spoof in review rated False, never edited", got)
+	}
+	if got := bySlug["drafted"]; got.Stale || got.Edited == nil || got.StatusChanged.IsZero() {
+		return fmt.Errorf("ListArticles returned %+v, want an edited draft that isn't stale", got)
+	}
+	return nil
+}
+
+// sameJob returns an error unless got is want. Times only need to match to the millisecond,
+// because databases don't keep every nanosecond.
+func sameJob(call string, got, want domain.SpoofJob) error {
+	sameTime := func(a, b *time.Time) bool {
+		if a == nil || b == nil {
+			return a == b
+		}
+		return a.Sub(*b).Abs() < time.Millisecond
+	}
+	gotTimes, wantTimes := got, want
+	gotTimes.Created, wantTimes.Created = time.Time{}, time.Time{}
+	gotTimes.Finished, wantTimes.Finished = nil, nil
+	if !reflect.DeepEqual(gotTimes, wantTimes) || !sameTime(&got.Created, &want.Created) || !sameTime(got.Finished, want.Finished) {
+		return fmt.Errorf("%s returned %+v, want %+v", call, got, want)
+	}
+	return nil
+}
+
+func checkJobs(ctx context.Context, r repo.Repo) error {
+	_, err := r.GetJob(ctx, "missing")
+	if err := wantErr("GetJob", err, repo.ErrNotFound); err != nil {
+		return err
+	}
+	if err := wantErr("UpdateJob", r.UpdateJob(ctx, domain.SpoofJob{ID: "missing", Status: domain.JobDone}), repo.ErrNotFound); err != nil {
+		return err
+	}
+
+	created := time.Now().Add(-time.Hour)
+	job := domain.SpoofJob{ID: "job", Slug: "requested", Status: domain.JobQueued, Created: created}
+	if err := r.SaveJob(ctx, job); err != nil {
+		return fmt.Errorf("SaveJob: %w", err)
+	}
+	if err := wantErr("SaveJob of the same ID", r.SaveJob(ctx, job), repo.ErrConflict); err != nil {
+		return err
+	}
+	got, err := r.GetJob(ctx, job.ID)
+	if err != nil {
+		return fmt.Errorf("GetJob: %w", err)
+	}
+	if err := sameJob("GetJob", got, job); err != nil {
+		return err
+	}
+
+	// Only the progress of a job changes.
+	finished := time.Now()
+	failed := job
+	failed.Status = domain.JobFailed
+	failed.Existed = true
+	failed.Error = "it broke"
+	failed.Finished = &finished
+	changed := failed
+	changed.Slug = "other"
+	changed.Regenerate = true
+	changed.Inversion = "mirror"
+	if err := r.UpdateJob(ctx, changed); err != nil {
+		return fmt.Errorf("UpdateJob: %w", err)
+	}
+	got, err = r.GetJob(ctx, job.ID)
+	if err != nil {
+		return fmt.Errorf("GetJob: %w", err)
+	}
+	if err := sameJob("GetJob after UpdateJob", got, failed); err != nil {
+		return err
+	}
+
+	// Jobs are deleted by when they were created, finished or not.
+	recent := domain.SpoofJob{ID: "recent", Slug: "requested", Regenerate: true, Inversion: "mirror", Status: domain.JobRunning, Created: time.Now()}
+	if err := r.SaveJob(ctx, recent); err != nil {
+		return fmt.Errorf("SaveJob: %w", err)
+	}
+	n, err := r.DeleteJobsBefore(ctx, time.Now().Add(-time.Minute))
+	if err != nil {
+		return fmt.Errorf("DeleteJobsBefore: %w", err)
+	}
+	if n != 1 {
+		return fmt.Errorf("DeleteJobsBefore returned %d, want 1", n)
+	}
+	_, err = r.GetJob(ctx, job.ID)
+	if err := wantErr("GetJob after DeleteJobsBefore", err, repo.ErrNotFound); err != nil {
+		return err
+	}
+	got, err = r.GetJob(ctx, recent.ID)
+	if err != nil {
+		return fmt.Errorf("GetJob after DeleteJobsBefore: %w", err)
+	}
+	return sameJob("GetJob after DeleteJobsBefore", got, recent)
+}
+
+func checkTransactions(ctx context.Context, r repo.Repo) error {
+	errRollback := errors.New("rollback")
+	article := testArticle("rolled-back", testDate)
+	err := r.WithTx(ctx, func(tx repo.Repo) error {
+		if err := tx.SaveArticle(ctx, article); err != nil {
+			return err
+		}
+		if err := tx.SaveSpoof(ctx, testSpoof(article)); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		return fmt.Errorf("WithTx returned %v, want the error of fn", err)
+	}
+	_, err = r.GetArticle(ctx, article.Slug)
+	if err := wantErr("GetArticle after a rollback", err, repo.ErrNotFound); err != nil {
+		return err
+	}
+
+	// A transaction joined by a nested WithTx commits once, with everything in it.
+	article = testArticle("committed", testDate)
+	err = r.WithTx(ctx, func(tx repo.Repo) error {
+		if err := tx.SaveArticle(ctx, article); err != nil {
+			return err
+		}
+		return tx.WithTx(ctx, func(tx repo.Repo) error {
+			return tx.SaveSpoof(ctx, testSpoof(article))
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("WithTx: %w", err)
+	}
+	if _, err := r.GetSpoof(ctx, article.Slug); err != nil {
+		return fmt.Errorf("GetSpoof after a commit: %w", err)
+	}
+	return nil
+}
+
+func checkUsers(ctx context.Context, r repo.Repo) error {
+	users, ok := r.(repo.UserRepo)
+	if !ok {
+		return nil
+	}
+
+	user, err := users.SaveUser(ctx, domain.User{Username: "editor", PasswordHash: "hash", Role: domain.RoleEditor})
+	if err != nil {
+		return fmt.Errorf("SaveUser: %w", err)
+	}
+	_, err = users.SaveUser(ctx, domain.User{Username: "editor", PasswordHash: "other", Role: domain.RoleViewer})
+	if err := wantErr("SaveUser of a taken username", err, repo.ErrConflict); err != nil {
+		return err
+	}
+	_, err = users.GetUser(ctx, "missing")
+	if err := wantErr("GetUser", err, repo.ErrNotFound); err != nil {
+		return err
+	}
+	if err := wantErr("SetPassword", users.SetPassword(ctx, "missing", "hash"), repo.ErrNotFound); err != nil {
+		return err
+	}
+	if err := wantErr("DeleteUser", users.DeleteUser(ctx, "missing"), repo.ErrNotFound); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, session := range []domain.Session{
+		{TokenHash: "live", UserID: user.ID, CSRFToken: "csrf", Expires: now.Add(time.Hour)},
+		{TokenHash: "expired", UserID: user.ID, CSRFToken: "csrf", Expires: now.Add(-time.Hour)},
+	} {
+		if err := users.SaveSession(ctx, session); err != nil {
+			return fmt.Errorf("SaveSession: %w", err)
+		}
+	}
+	_, got, err := users.GetSession(ctx, "live")
+	if err != nil {
+		return fmt.Errorf("GetSession: %w", err)
+	}
+	if got.ID != user.ID || got.Username != user.Username {
+		return fmt.Errorf("GetSession returned user %+v, want %+v", got, user)
+	}
+	for _, tokenHash := range []string{"expired", "missing"} {
+		_, _, err := users.GetSession(ctx, tokenHash)
+		if err := wantErr("GetSession("+tokenHash+")", err, repo.ErrNotFound); err != nil {
+			return err
+		}
+	}
+
+	expired := now.Add(-time.Hour)
+	if _, err := users.SaveAPIToken(ctx, domain.APIToken{TokenHash: "expired", UserID: user.ID, Name: "old", Expires: &expired}); err != nil {
+		return fmt.Errorf("SaveAPIToken: %w", err)
+	}
+	for _, tokenHash := range []string{"expired", "missing"} {
+		_, _, err := users.UseAPIToken(ctx, tokenHash)
+		if err := wantErr("UseAPIToken("+tokenHash+")", err, repo.ErrNotFound); err != nil {
+			return err
+		}
+	}
+
+	// Changing the password ends every session.
+	if err := users.SetPassword(ctx, user.Username, "new hash"); err != nil {
+		return fmt.Errorf("SetPassword: %w", err)
+	}
+	_, _, err = users.GetSession(ctx, "live")
+	return wantErr("GetSession after SetPassword", err, repo.ErrNotFound)
+}
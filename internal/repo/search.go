@@ -0,0 +1,71 @@
+package repo
+
+import "strings"
+
+// websearch is a query in the syntax people use in search engines, with "quoted phrases", -excluded words and OR,
+// which is what PostgreSQL's websearch_to_tsquery accepts. Repos that can't use it parse queries with parseWebsearch.
+type websearch struct {
+	// any are the alternatives that match, each a list of phrases that must all match.
+	// Like in a search engine, "a b OR c" means (a AND b) OR c.
+	any [][]string
+	// none are the phrases that must not match.
+	none []string
+}
+
+// parseWebsearch parses a query into its phrases. Like websearch_to_tsquery, it never fails on bad input:
+// a quote that isn't closed runs to the end, and an OR with nothing on one side is dropped.
+// The query matches nothing if the result has no alternatives.
+func parseWebsearch(query string) websearch {
+	var search websearch
+	var all []string
+	or := false
+	for query != "" {
+		query = strings.TrimLeft(query, " \t\r\n")
+		if query == "" {
+			break
+		}
+
+		exclude := false
+		if query[0] == '-' {
+			exclude = true
+			query = query[1:]
+		}
+
+		var phrase string
+		quoted := strings.HasPrefix(query, `"`)
+		if quoted {
+			end := strings.Index(query[1:], `"`)
+			if end < 0 {
+				phrase, query = query[1:], ""
+			} else {
+				phrase, query = query[1:end+1], query[end+2:]
+			}
+		} else {
+			end := strings.IndexAny(query, " \t\r\n")
+			if end < 0 {
+				end = len(query)
+			}
+			phrase, query = query[:end], query[end:]
+		}
+		if strings.TrimSpace(phrase) == "" {
+			continue
+		}
+
+		switch {
+		case !quoted && !exclude && strings.EqualFold(phrase, "or"):
+			or = len(all) > 0
+		case exclude:
+			search.none = append(search.none, phrase)
+		default:
+			if or {
+				search.any = append(search.any, all)
+				all, or = nil, false
+			}
+			all = append(all, phrase)
+		}
+	}
+	if len(all) > 0 {
+		search.any = append(search.any, all)
+	}
+	return search
+}
@@ -0,0 +1,961 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+
+	"github.com/glizzus/trf/internal/domain"
+)
+
+// SQLiteRepo is a SQLite implementation of the Repo interface, for running with no database server.
+// It keeps everything in one file, so it suits a developer's machine or a tiny deployment with a single instance.
+type SQLiteRepo struct {
+	db *sql.DB
+	// q runs the queries. It is db, or the transaction that the repo was made for by WithTx.
+	q querier
+	// tx is the transaction that the repo was made for, if any.
+	tx *sql.Tx
+
+	locks *localLocks
+}
+
+// OpenSQLite opens the SQLite database in the file at path, creating it if it doesn't exist.
+//
+// Foreign keys are enforced, and transactions take the write lock when they begin,
+// so that two of them can't deadlock by both upgrading from a read lock.
+func OpenSQLite(path string) (*sql.DB, error) {
+	params := url.Values{}
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Add("_pragma", "busy_timeout(5000)")
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Set("_txlock", "immediate")
+
+	// SQLite decodes the path of a file: URI, so a path with ?, # or % in it must be escaped to stay the same path.
+	dsn := url.URL{Scheme: "file", Opaque: (&url.URL{Path: path}).EscapedPath(), RawQuery: params.Encode()}
+	db, err := sql.Open("sqlite", dsn.String())
+	if err != nil {
+		return nil, fmt.Errorf("error opening SQLite database %s: %w", path, err)
+	}
+	return db, nil
+}
+
+// NewSQLite creates a new SQLiteRepo with the given database, which must have been opened by OpenSQLite.
+func NewSQLite(db *sql.DB) *SQLiteRepo {
+	return &SQLiteRepo{db: db, q: db, locks: newLocalLocks()}
+}
+
+// WithTx runs fn with a repo whose calls all happen in one transaction,
+// which is committed if fn returns nil and rolled back otherwise.
+// Calling WithTx on the repo that fn gets joins the same transaction.
+func (r *SQLiteRepo) WithTx(ctx context.Context, fn func(Repo) error) error {
+	return r.inTx(ctx, func(tx *SQLiteRepo) error {
+		return fn(tx)
+	})
+}
+
+// inTx runs fn in a transaction, or in the one r was made for if there is one.
+func (r *SQLiteRepo) inTx(ctx context.Context, fn func(*SQLiteRepo) error) error {
+	if r.tx != nil {
+		return fn(r)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %w", err)
+	}
+	// This is a no-op if the transaction was committed.
+	defer tx.Rollback()
+
+	if err := fn(&SQLiteRepo{db: r.db, q: tx, tx: tx, locks: r.locks}); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// Ping checks that the database can be reached.
+func (r *SQLiteRepo) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
+
+// Migrate applies the migrations in fsys that haven't been applied yet, in order, each in a transaction of its own.
+// Only the up migrations, named like "1_init_schema.up.sql", are applied.
+//
+// The version is recorded in schema_migrations like the migrate tool does for PostgreSQL,
+// so that MigrationVersion means the same for both.
+func (r *SQLiteRepo) Migrate(ctx context.Context, fsys fs.FS, version func(name string) (uint, bool)) error {
+	const createQuery = `CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER NOT NULL, dirty INTEGER NOT NULL)`
+	if _, err := r.q.ExecContext(ctx, createQuery); err != nil {
+		return fmt.Errorf("error creating schema_migrations: %w", err)
+	}
+
+	current, _, err := r.MigrationVersion(ctx)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	names, err := fs.Glob(fsys, "*.up.sql")
+	if err != nil {
+		return fmt.Errorf("error listing migrations: %w", err)
+	}
+	type migration struct {
+		version uint
+		name    string
+	}
+	var pending []migration
+	for _, name := range names {
+		if v, ok := version(name); ok && v > current {
+			pending = append(pending, migration{version: v, name: name})
+		}
+	}
+	slices.SortFunc(pending, func(a, b migration) int { return int(a.version) - int(b.version) })
+
+	for _, m := range pending {
+		script, err := fs.ReadFile(fsys, m.name)
+		if err != nil {
+			return fmt.Errorf("error reading migration %s: %w", m.name, err)
+		}
+		if err := r.inTx(ctx, func(r *SQLiteRepo) error {
+			if _, err := r.q.ExecContext(ctx, string(script)); err != nil {
+				return fmt.Errorf("error applying migration %s: %w", m.name, err)
+			}
+			if _, err := r.q.ExecContext(ctx, `DELETE FROM schema_migrations`); err != nil {
+				return fmt.Errorf("error recording migration %s: %w", m.name, err)
+			}
+			if _, err := r.q.ExecContext(ctx, `INSERT INTO schema_migrations (version, dirty) VALUES (?1, 0)`, m.version); err != nil {
+				return fmt.Errorf("error recording migration %s: %w", m.name, err)
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MigrationVersion returns the version of the last migration applied by Migrate.
+// Migrations are applied in transactions, so the schema is never dirty.
+func (r *SQLiteRepo) MigrationVersion(ctx context.Context) (version uint, dirty bool, err error) {
+	err = r.q.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations`).Scan(&version, &dirty)
+	if err != nil {
+		return 0, false, fmt.Errorf("error querying migration version: %w", translateSQLiteError(err))
+	}
+	return version, dirty, nil
+}
+
+// translateSQLiteError converts driver errors into the errors defined by this package.
+// Errors that have no equivalent are returned unchanged.
+func translateSQLiteError(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		switch sqliteErr.Code() {
+		case sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+			return fmt.Errorf("%w: %s", ErrConflict, sqliteErr.Error())
+		}
+	}
+	return err
+}
+
+// sqliteTimeFormat has a fixed width, so that times formatted with it sort like the times themselves.
+// Column defaults write the same format, with strftime('%Y-%m-%d %H:%M:%f000000', 'now').
+const sqliteTimeFormat = "2006-01-02 15:04:05.000000000"
+
+// sqliteTime formats t in UTC for a time column.
+func sqliteTime(t time.Time) string {
+	return t.UTC().Format(sqliteTimeFormat)
+}
+
+// sqliteNow formats the current time for a time column.
+func sqliteNow() string {
+	return sqliteTime(time.Now())
+}
+
+// sqliteDate formats the day of t for a date column. Like a PostgreSQL DATE, the time of day is dropped.
+func sqliteDate(t time.Time) string {
+	return t.Format(time.DateOnly)
+}
+
+// sqliteNullDate formats the day of t for a date column, or NULL if t is nil.
+func sqliteNullDate(t *time.Time) sql.NullString {
+	if t == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: sqliteDate(*t), Valid: true}
+}
+
+// sqliteNullTime formats t for a time column, or NULL if t is nil.
+func sqliteNullTime(t *time.Time) sql.NullString {
+	if t == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: sqliteTime(*t), Valid: true}
+}
+
+// parseSQLiteTime parses a value of a time or date column, which is in UTC.
+func parseSQLiteTime(s string) (time.Time, error) {
+	for _, layout := range []string{sqliteTimeFormat, time.DateOnly} {
+		if t, err := time.ParseInLocation(layout, s, time.UTC); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", s)
+}
+
+// sqliteText returns the text of a column value that the driver returned.
+func sqliteText(src any) (string, bool) {
+	switch v := src.(type) {
+	case string:
+		return v, true
+	case []byte:
+		return string(v), true
+	default:
+		return "", false
+	}
+}
+
+// timeColumn scans a time or date column into t.
+type timeColumn struct {
+	t *time.Time
+}
+
+func (c timeColumn) Scan(src any) error {
+	s, ok := sqliteText(src)
+	if !ok {
+		return fmt.Errorf("cannot scan %T into a time", src)
+	}
+	t, err := parseSQLiteTime(s)
+	if err != nil {
+		return err
+	}
+	*c.t = t
+	return nil
+}
+
+// nullTimeColumn scans a time or date column into t, which is left nil if the column is NULL.
+type nullTimeColumn struct {
+	t **time.Time
+}
+
+func (c nullTimeColumn) Scan(src any) error {
+	if src == nil {
+		*c.t = nil
+		return nil
+	}
+	var t time.Time
+	if err := (timeColumn{&t}).Scan(src); err != nil {
+		return err
+	}
+	*c.t = &t
+	return nil
+}
+
+// stubDateColumn scans a date column into the date of a SpoofStub, formatted like lib/pq formats a PostgreSQL DATE.
+type stubDateColumn struct {
+	s *string
+}
+
+func (c stubDateColumn) Scan(src any) error {
+	var t time.Time
+	if err := (timeColumn{&t}).Scan(src); err != nil {
+		return err
+	}
+	*c.s = t.Format(time.RFC3339Nano)
+	return nil
+}
+
+// jsonColumn scans a column holding JSON, such as a list, into v.
+type jsonColumn struct {
+	v any
+}
+
+func (c jsonColumn) Scan(src any) error {
+	s, ok := sqliteText(src)
+	if !ok {
+		return fmt.Errorf("cannot scan %T into JSON", src)
+	}
+	return json.Unmarshal([]byte(s), c.v)
+}
+
+// jsonArray encodes s for a list column. Like the PostgreSQL array columns, a nil list is empty rather than NULL.
+func jsonArray(s []string) string {
+	if s == nil {
+		s = []string{}
+	}
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+// sqliteArticleArgs returns the parameters of an article, in the order of the columns
+// that SaveArticle and UpdateArticle write.
+func sqliteArticleArgs(article domain.Article) ([]any, error) {
+	metadata, err := metadataArgs(article)
+	if err != nil {
+		return nil, err
+	}
+	// The driver would store the JSON of the sources as a blob, but the column holds text.
+	metadata[3] = string(metadata[3].([]byte))
+
+	return append([]any{
+		article.Slug,
+		article.Title,
+		article.Subtitle,
+		sqliteDate(article.Date),
+		article.Claim.Question,
+		article.Claim.Rating,
+		article.Claim.Context,
+		jsonArray(article.Content),
+		article.Hash(),
+		jsonArray(article.Authors),
+		sqliteNullDate(article.Updated),
+		jsonArray(article.Tags),
+		jsonArray(article.Claim.WhatsTrue),
+		jsonArray(article.Claim.WhatsFalse),
+	}, metadata...), nil
+}
+
+func (r *SQLiteRepo) SaveArticle(ctx context.Context, article domain.Article) error {
+	const query = `
+		INSERT INTO articles (
+			slug, title, subtitle, date, question, rating, context, content, content_hash,
+			authors, updated_date, tags, whats_true, whats_false,
+			category, image_url, image_alt, sources
+		)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9, ?10, ?11, ?12, ?13, ?14, ?15, ?16, ?17, ?18)
+		ON CONFLICT (slug) DO NOTHING
+	`
+
+	args, err := sqliteArticleArgs(article)
+	if err != nil {
+		return err
+	}
+
+	res, err := r.q.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("error saving article %s: %w", article.Slug, translateSQLiteError(err))
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 1 {
+		return nil
+	}
+
+	// The article was saved before. That is fine if it was this version, so that saves can be retried.
+	var same bool
+	err = r.q.QueryRowContext(ctx, `SELECT content_hash IS ?2 FROM articles WHERE slug = ?1`, article.Slug, article.Hash()).Scan(&same)
+	if err != nil {
+		return fmt.Errorf("error saving article %s: %w", article.Slug, translateSQLiteError(err))
+	}
+	if !same {
+		return fmt.Errorf("error saving article %s: %w: another version is saved", article.Slug, ErrConflict)
+	}
+	return nil
+}
+
+func (r *SQLiteRepo) GetArticle(ctx context.Context, slug string) (domain.Article, error) {
+	const query = `
+		SELECT
+			slug, title, subtitle, date, question, rating, context, content,
+			authors, updated_date, tags, whats_true, whats_false,
+			category, image_url, image_alt, sources
+		FROM articles
+		WHERE slug = ?1
+	`
+
+	var article domain.Article
+	var metadata metadataColumns
+	if err := r.q.QueryRowContext(ctx, query, slug).Scan(
+		&article.Slug,
+		&article.Title,
+		&article.Subtitle,
+		timeColumn{&article.Date},
+		&article.Claim.Question,
+		&article.Claim.Rating,
+		&article.Claim.Context,
+		jsonColumn{&article.Content},
+		jsonColumn{&article.Authors},
+		nullTimeColumn{&article.Updated},
+		jsonColumn{&article.Tags},
+		jsonColumn{&article.Claim.WhatsTrue},
+		jsonColumn{&article.Claim.WhatsFalse},
+		&metadata.category,
+		&metadata.imageURL,
+		&metadata.imageAlt,
+		&metadata.sources,
+	); err != nil {
+		return domain.Article{}, fmt.Errorf("error getting article %s: %w", slug, translateSQLiteError(err))
+	}
+	if err := metadata.apply(&article); err != nil {
+		return domain.Article{}, err
+	}
+
+	return article, nil
+}
+
+func (r *SQLiteRepo) UpdateArticle(ctx context.Context, article domain.Article) error {
+	const archiveQuery = `
+		INSERT INTO article_revisions (
+			slug, title, subtitle, date, question, rating, context, content, content_hash,
+			authors, updated_date, tags, whats_true, whats_false,
+			category, image_url, image_alt, sources
+		)
+		SELECT
+			slug, title, subtitle, date, question, rating, context, content, content_hash,
+			authors, updated_date, tags, whats_true, whats_false,
+			category, image_url, image_alt, sources
+		FROM articles
+		WHERE slug = ?1
+	`
+	const updateQuery = `
+		UPDATE articles
+		SET
+			title = ?2, subtitle = ?3, date = ?4, question = ?5, rating = ?6, context = ?7, content = ?8,
+			content_hash = ?9, authors = ?10, updated_date = ?11, tags = ?12, whats_true = ?13, whats_false = ?14,
+			category = ?15, image_url = ?16, image_alt = ?17, sources = ?18,
+			checked_at = ?19, updated_at = ?19
+		WHERE slug = ?1
+	`
+
+	args, err := sqliteArticleArgs(article)
+	if err != nil {
+		return err
+	}
+	args = append(args, sqliteNow())
+
+	// Copying the current version into the history and replacing it must happen together,
+	// otherwise we could lose a version or record one twice.
+	return r.inTx(ctx, func(r *SQLiteRepo) error {
+		res, err := r.q.ExecContext(ctx, archiveQuery, article.Slug)
+		if err != nil {
+			return fmt.Errorf("error archiving revision of article %s: %w", article.Slug, translateSQLiteError(err))
+		}
+		if n, err := res.RowsAffected(); err == nil && n == 0 {
+			return fmt.Errorf("error updating article %s: %w", article.Slug, ErrNotFound)
+		}
+
+		if _, err := r.q.ExecContext(ctx, updateQuery, args...); err != nil {
+			return fmt.Errorf("error updating article %s: %w", article.Slug, translateSQLiteError(err))
+		}
+		return nil
+	})
+}
+
+func (r *SQLiteRepo) MarkArticleChecked(ctx context.Context, slug string) error {
+	res, err := r.q.ExecContext(ctx, `UPDATE articles SET checked_at = ?2 WHERE slug = ?1`, slug, sqliteNow())
+	if err != nil {
+		return fmt.Errorf("error marking article %s as checked: %w", slug, translateSQLiteError(err))
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("error marking article %s as checked: %w", slug, ErrNotFound)
+	}
+	return nil
+}
+
+func (r *SQLiteRepo) GetArticleSlugsToRecheck(ctx context.Context, since time.Time, limit int) ([]string, error) {
+	const query = `
+		SELECT slug
+		FROM articles
+		WHERE date >= ?1
+		ORDER BY checked_at ASC
+		LIMIT ?2
+	`
+
+	rows, err := r.q.QueryContext(ctx, query, sqliteDate(since.UTC()), limit)
+	if err != nil {
+		return nil, fmt.Errorf("error querying for articles to recheck: %w", err)
+	}
+	defer rows.Close()
+
+	var slugs []string
+	for rows.Next() {
+		var slug string
+		if err := rows.Scan(&slug); err != nil {
+			return nil, fmt.Errorf("error scanning articles to recheck: %w", err)
+		}
+		slugs = append(slugs, slug)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating articles to recheck: %w", err)
+	}
+
+	return slugs, nil
+}
+
+func (r *SQLiteRepo) GetRatingCounts(ctx context.Context) ([]domain.RatingCount, error) {
+	// The first row of each rating is its most recent article.
+	const query = `
+		WITH ranked AS (
+			SELECT
+				rating,
+				slug,
+				date,
+				ROW_NUMBER() OVER (PARTITION BY rating ORDER BY date DESC, slug) AS n,
+				COUNT(*) OVER (PARTITION BY rating) AS articles
+			FROM articles
+		)
+		SELECT rating, articles, slug, date
+		FROM ranked
+		WHERE n = 1
+		ORDER BY articles DESC, rating
+	`
+
+	rows, err := r.q.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error querying for rating counts: %w", err)
+	}
+	defer rows.Close()
+
+	var counts []domain.RatingCount
+	for rows.Next() {
+		var count domain.RatingCount
+		if err := rows.Scan(&count.Rating, &count.Articles, &count.LatestSlug, timeColumn{&count.LatestDate}); err != nil {
+			return nil, fmt.Errorf("error scanning rating counts: %w", err)
+		}
+		counts = append(counts, count)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rating counts: %w", err)
+	}
+
+	return counts, nil
+}
+
+func (r *SQLiteRepo) SaveSpoof(ctx context.Context, spoof domain.Spoof) error {
+	const query = `
+		INSERT INTO spoofs (slug, rating, content, whats_true, whats_false, inversion, status)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7)
+		ON CONFLICT (slug) DO NOTHING
+	`
+	// Like articles, a spoof can be saved again as long as it is the same.
+	const sameQuery = `
+		SELECT rating = ?2 AND content = ?3 AND inversion IS ?4
+		FROM spoofs
+		WHERE slug = ?1
+	`
+
+	res, err := r.q.ExecContext(
+		ctx,
+		query,
+		spoof.Slug,
+		spoof.Claim.Rating,
+		jsonArray(spoof.Content),
+		jsonArray(spoof.Claim.WhatsTrue),
+		jsonArray(spoof.Claim.WhatsFalse),
+		nullString(spoof.Inversion),
+		spoofStatus(spoof.Status),
+	)
+	if err != nil {
+		return fmt.Errorf("error saving spoof %s: %w", spoof.Slug, translateSQLiteError(err))
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 1 {
+		return nil
+	}
+
+	var same bool
+	err = r.q.QueryRowContext(ctx, sameQuery, spoof.Slug, spoof.Claim.Rating, jsonArray(spoof.Content), nullString(spoof.Inversion)).Scan(&same)
+	if err != nil {
+		return fmt.Errorf("error saving spoof %s: %w", spoof.Slug, translateSQLiteError(err))
+	}
+	if !same {
+		return fmt.Errorf("error saving spoof %s: %w: another spoof is saved", spoof.Slug, ErrConflict)
+	}
+	return nil
+}
+
+func (r *SQLiteRepo) UpdateSpoof(ctx context.Context, spoof domain.Spoof) error {
+	const query = `
+		UPDATE spoofs
+		SET
+			rating = ?2, content = ?3, whats_true = ?4, whats_false = ?5, inversion = ?6, stale = 0,
+			status = ?7,
+			status_changed_at = CASE WHEN status = ?7 THEN status_changed_at ELSE ?8 END,
+			edited_at = NULL
+		WHERE slug = ?1
+	`
+
+	res, err := r.q.ExecContext(
+		ctx,
+		query,
+		spoof.Slug,
+		spoof.Claim.Rating,
+		jsonArray(spoof.Content),
+		jsonArray(spoof.Claim.WhatsTrue),
+		jsonArray(spoof.Claim.WhatsFalse),
+		nullString(spoof.Inversion),
+		spoofStatus(spoof.Status),
+		sqliteNow(),
+	)
+	if err != nil {
+		return fmt.Errorf("error updating spoof %s: %w", spoof.Slug, translateSQLiteError(err))
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("error updating spoof %s: %w", spoof.Slug, ErrNotFound)
+	}
+	return nil
+}
+
+func (r *SQLiteRepo) MarkSpoofStale(ctx context.Context, slug string) error {
+	res, err := r.q.ExecContext(ctx, `UPDATE spoofs SET stale = 1 WHERE slug = ?1`, slug)
+	if err != nil {
+		return fmt.Errorf("error marking spoof %s as stale: %w", slug, translateSQLiteError(err))
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("error marking spoof %s as stale: %w", slug, ErrNotFound)
+	}
+	return nil
+}
+
+func (r *SQLiteRepo) GetSpoof(ctx context.Context, slug string) (domain.Spoof, error) {
+	const query = `
+		SELECT
+			spoofs.slug,
+			articles.title,
+			articles.subtitle,
+			articles.date,
+			articles.question,
+			spoofs.rating,
+			articles.context,
+			spoofs.content,
+			articles.updated_date,
+			articles.tags,
+			spoofs.whats_true,
+			spoofs.whats_false,
+			articles.category,
+			articles.image_url,
+			articles.image_alt,
+			spoofs.inversion,
+			spoofs.status
+		FROM spoofs
+		JOIN articles ON articles.slug = spoofs.slug
+		WHERE spoofs.slug = ?1
+	`
+
+	// The authors and sources belong to the original article, so a spoof doesn't have them.
+	var spoof domain.Spoof
+	var metadata metadataColumns
+	var inversion sql.NullString
+	if err := r.q.QueryRowContext(ctx, query, slug).Scan(
+		&spoof.Slug,
+		&spoof.Title,
+		&spoof.Subtitle,
+		timeColumn{&spoof.Date},
+		&spoof.Claim.Question,
+		&spoof.Claim.Rating,
+		&spoof.Claim.Context,
+		jsonColumn{&spoof.Content},
+		nullTimeColumn{&spoof.Updated},
+		jsonColumn{&spoof.Tags},
+		jsonColumn{&spoof.Claim.WhatsTrue},
+		jsonColumn{&spoof.Claim.WhatsFalse},
+		&metadata.category,
+		&metadata.imageURL,
+		&metadata.imageAlt,
+		&inversion,
+		&spoof.Status,
+	); err != nil {
+		return domain.Spoof{}, fmt.Errorf("error getting spoof %s: %w", slug, translateSQLiteError(err))
+	}
+	// Sources weren't scanned, so this can't fail.
+	_ = metadata.apply(&spoof.Article)
+	spoof.Inversion = inversion.String
+
+	return spoof, nil
+}
+
+func (r *SQLiteRepo) GetLatestSpoofStubs(ctx context.Context) ([]domain.SpoofStub, error) {
+	const query = `
+		SELECT
+			spoofs.slug,
+			articles.title,
+			articles.subtitle,
+			articles.date
+		FROM spoofs
+		JOIN articles ON articles.slug = spoofs.slug
+		WHERE spoofs.status = 'published'
+		ORDER BY articles.date DESC
+		LIMIT 21
+	`
+
+	rows, err := r.q.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error querying for latest spoof stubs: %w", err)
+	}
+	defer rows.Close()
+
+	var stubs []domain.SpoofStub
+	for rows.Next() {
+		var stub domain.SpoofStub
+		if err := rows.Scan(&stub.Slug, &stub.Title, &stub.Subtitle, stubDateColumn{&stub.Date}); err != nil {
+			return nil, fmt.Errorf("error scanning latest spoof stubs: %w", err)
+		}
+		stubs = append(stubs, stub)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating latest spoof stubs: %w", err)
+	}
+
+	return stubs, nil
+}
+
+// GetAllNotExistingSpoofSlugs returns the slugs that do not exist in the database,
+// in the same order as they were passed in.
+func (r *SQLiteRepo) GetAllNotExistingSpoofSlugs(ctx context.Context, slugs []string) ([]string, error) {
+	if len(slugs) == 0 {
+		return nil, nil
+	}
+
+	rows, err := r.q.QueryContext(ctx, `SELECT slug FROM articles WHERE slug IN (SELECT value FROM json_each(?1))`, jsonArray(slugs))
+	if err != nil {
+		return nil, fmt.Errorf("error querying for non-existing slugs: %w", err)
+	}
+	defer rows.Close()
+
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var slug string
+		if err := rows.Scan(&slug); err != nil {
+			return nil, fmt.Errorf("error scanning non-existing slugs: %w", err)
+		}
+		existing[slug] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating non-existing slugs: %w", err)
+	}
+
+	var notExisting []string
+	for _, slug := range slugs {
+		if !existing[slug] {
+			notExisting = append(notExisting, slug)
+		}
+	}
+	return notExisting, nil
+}
+
+// ftsQuery converts a query in the syntax people use in search engines into an FTS5 query.
+// Every phrase is quoted, so nothing in it is taken as FTS5 syntax.
+// It returns an empty string if there is nothing to search for.
+func ftsQuery(query string) string {
+	quote := func(phrase string) string {
+		return `"` + strings.ReplaceAll(phrase, `"`, `""`) + `"`
+	}
+
+	// FTS5 can't search for what a document doesn't contain, only exclude it from other matches.
+	search := parseWebsearch(query)
+	if len(search.any) == 0 {
+		return ""
+	}
+
+	alternatives := make([]string, len(search.any))
+	for i, all := range search.any {
+		phrases := make([]string, len(all))
+		for j, phrase := range all {
+			phrases[j] = quote(phrase)
+		}
+		alternatives[i] = "(" + strings.Join(phrases, " ") + ")"
+	}
+	fts := "(" + strings.Join(alternatives, " OR ") + ")"
+	for _, phrase := range search.none {
+		fts += " NOT " + quote(phrase)
+	}
+	return fts
+}
+
+func (r *SQLiteRepo) Search(ctx context.Context, query string, limit int) ([]domain.SearchResult, error) {
+	// bm25 is lower for better matches, and weighs the columns of spoofs_search like the PostgreSQL
+	// search vectors do: the title highest, then the subtitle and question, then the content.
+	// The snippet only ever comes from the spoof, because that is what we publish.
+	const q = `
+		SELECT
+			spoofs.slug,
+			articles.title,
+			articles.subtitle,
+			articles.date,
+			-bm25(spoofs_search, 0, 10, 4, 4, 1, 1) AS rank,
+			snippet(spoofs_search, 5, '<mark>', '</mark>', '…', 30) AS snippet
+		FROM
+			spoofs_search
+			JOIN spoofs ON spoofs.slug = spoofs_search.slug
+			JOIN articles ON articles.slug = spoofs.slug
+		WHERE
+			spoofs_search MATCH ?1
+			AND spoofs.status = 'published'
+		ORDER BY rank DESC, articles.date DESC
+		LIMIT ?2
+	`
+
+	fts := ftsQuery(query)
+	if fts == "" {
+		return nil, nil
+	}
+
+	rows, err := r.q.QueryContext(ctx, q, fts, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error querying for search results: %w", err)
+	}
+	defer rows.Close()
+
+	var results []domain.SearchResult
+	for rows.Next() {
+		var result domain.SearchResult
+		if err := rows.Scan(
+			&result.Slug,
+			&result.Title,
+			&result.Subtitle,
+			stubDateColumn{&result.Date},
+			&result.Rank,
+			&result.Snippet,
+		); err != nil {
+			return nil, fmt.Errorf("error scanning search results: %w", err)
+		}
+		results = append(results, result)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating search results: %w", err)
+	}
+
+	return results, nil
+}
+
+func (r *SQLiteRepo) UpdateSpoofStatus(ctx context.Context, slug string, from, to domain.SpoofStatus) error {
+	const query = `
+		UPDATE spoofs
+		SET status = ?3, status_changed_at = ?4
+		WHERE slug = ?1 AND status = ?2
+	`
+
+	res, err := r.q.ExecContext(ctx, query, slug, from, to, sqliteNow())
+	if err != nil {
+		return fmt.Errorf("error updating status of spoof %s: %w", slug, translateSQLiteError(err))
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 1 {
+		return nil
+	}
+
+	// Nothing was updated, so either there is no such spoof, or someone else changed its status first.
+	var current domain.SpoofStatus
+	if err := r.q.QueryRowContext(ctx, `SELECT status FROM spoofs WHERE slug = ?1`, slug).Scan(&current); err != nil {
+		return fmt.Errorf("error updating status of spoof %s: %w", slug, translateSQLiteError(err))
+	}
+	return fmt.Errorf("error updating status of spoof %s: %w: it is %s, not %s", slug, ErrConflict, current, from)
+}
+
+func (r *SQLiteRepo) EditSpoofContent(ctx context.Context, slug string, content []string) error {
+	// A published spoof goes back to review, so that the public never sees an edit that nobody checked.
+	const query = `
+		UPDATE spoofs
+		SET
+			content = ?2, edited_at = ?3,
+			status = CASE WHEN status = ?4 THEN ?5 ELSE status END,
+			status_changed_at = CASE WHEN status = ?4 THEN ?3 ELSE status_changed_at END
+		WHERE slug = ?1
+	`
+
+	res, err := r.q.ExecContext(ctx, query, slug, jsonArray(content), sqliteNow(), domain.SpoofPublished, domain.SpoofReview)
+	if err != nil {
+		return fmt.Errorf("error editing spoof %s: %w", slug, translateSQLiteError(err))
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("error editing spoof %s: %w", slug, ErrNotFound)
+	}
+	return nil
+}
+
+func (r *SQLiteRepo) DeleteSpoof(ctx context.Context, slug string) error {
+	res, err := r.q.ExecContext(ctx, `DELETE FROM spoofs WHERE slug = ?1`, slug)
+	if err != nil {
+		return fmt.Errorf("error deleting spoof %s: %w", slug, translateSQLiteError(err))
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("error deleting spoof %s: %w", slug, ErrNotFound)
+	}
+	return nil
+}
+
+func (r *SQLiteRepo) ListArticles(ctx context.Context, filter domain.ArticleFilter) ([]domain.ArticleOverview, error) {
+	// Articles without a spoof are listed when there is no filter, so that editors can see the ones whose spoof was deleted.
+	const query = `
+		SELECT
+			articles.slug,
+			articles.title,
+			articles.date,
+			articles.rating,
+			spoofs.status,
+			spoofs.rating,
+			spoofs.stale,
+			spoofs.status_changed_at,
+			spoofs.edited_at
+		FROM articles
+		LEFT JOIN spoofs ON spoofs.slug = articles.slug
+		WHERE ?1 = '' OR spoofs.status = ?1
+		ORDER BY articles.date DESC, articles.slug
+		LIMIT ?2 OFFSET ?3
+	`
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+
+	rows, err := r.q.QueryContext(ctx, query, filter.SpoofStatus, limit, filter.Offset)
+	if err != nil {
+		return nil, fmt.Errorf("error querying for articles: %w", err)
+	}
+	defer rows.Close()
+
+	var overviews []domain.ArticleOverview
+	for rows.Next() {
+		var overview domain.ArticleOverview
+		var status, rating sql.NullString
+		var stale sql.NullBool
+		var statusChanged *time.Time
+		if err := rows.Scan(
+			&overview.Slug,
+			&overview.Title,
+			timeColumn{&overview.Date},
+			&overview.Rating,
+			&status,
+			&rating,
+			&stale,
+			nullTimeColumn{&statusChanged},
+			nullTimeColumn{&overview.Edited},
+		); err != nil {
+			return nil, fmt.Errorf("error scanning articles: %w", err)
+		}
+		overview.SpoofStatus = domain.SpoofStatus(status.String)
+		overview.SpoofRating = domain.Rating(rating.String)
+		overview.Stale = stale.Bool
+		if statusChanged != nil {
+			overview.StatusChanged = *statusChanged
+		}
+		overviews = append(overviews, overview)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating articles: %w", err)
+	}
+
+	return overviews, nil
+}
+
+// TryLock takes the lock with the given name, if it isn't held. Only one instance of the server
+// can use a SQLite database, so the lock only needs to keep its own goroutines apart.
+func (r *SQLiteRepo) TryLock(ctx context.Context, name string) (Lock, bool, error) {
+	return r.locks.tryLock(name)
+}
+
+var (
+	_ Repo   = &SQLiteRepo{}
+	_ Locker = &SQLiteRepo{}
+)
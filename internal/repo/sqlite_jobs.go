@@ -0,0 +1,86 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/glizzus/trf/internal/domain"
+)
+
+func (r *SQLiteRepo) SaveJob(ctx context.Context, job domain.SpoofJob) error {
+	const query = `
+		INSERT INTO spoof_jobs (id, slug, regenerate, inversion, status, existed, error, created_at, finished_at)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9)
+	`
+
+	_, err := r.q.ExecContext(
+		ctx,
+		query,
+		job.ID,
+		job.Slug,
+		job.Regenerate,
+		nullString(job.Inversion),
+		job.Status,
+		job.Existed,
+		nullString(job.Error),
+		sqliteTime(job.Created),
+		sqliteNullTime(job.Finished),
+	)
+	if err != nil {
+		return fmt.Errorf("error saving job %s: %w", job.ID, translateSQLiteError(err))
+	}
+	return nil
+}
+
+func (r *SQLiteRepo) UpdateJob(ctx context.Context, job domain.SpoofJob) error {
+	const query = `
+		UPDATE spoof_jobs
+		SET status = ?2, existed = ?3, error = ?4, finished_at = ?5
+		WHERE id = ?1
+	`
+
+	res, err := r.q.ExecContext(ctx, query, job.ID, job.Status, job.Existed, nullString(job.Error), sqliteNullTime(job.Finished))
+	if err != nil {
+		return fmt.Errorf("error updating job %s: %w", job.ID, translateSQLiteError(err))
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("error updating job %s: %w", job.ID, ErrNotFound)
+	}
+	return nil
+}
+
+func (r *SQLiteRepo) GetJob(ctx context.Context, id string) (domain.SpoofJob, error) {
+	const query = `SELECT ` + jobColumns + ` FROM spoof_jobs WHERE id = ?1`
+
+	var job domain.SpoofJob
+	var inversion, jobError sql.NullString
+	if err := r.q.QueryRowContext(ctx, query, id).Scan(
+		&job.ID,
+		&job.Slug,
+		&job.Regenerate,
+		&inversion,
+		&job.Status,
+		&job.Existed,
+		&jobError,
+		timeColumn{&job.Created},
+		nullTimeColumn{&job.Finished},
+	); err != nil {
+		return domain.SpoofJob{}, fmt.Errorf("error getting job %s: %w", id, translateSQLiteError(err))
+	}
+	job.Inversion, job.Error = inversion.String, jobError.String
+	return job, nil
+}
+
+func (r *SQLiteRepo) DeleteJobsBefore(ctx context.Context, before time.Time) (int64, error) {
+	res, err := r.q.ExecContext(ctx, `DELETE FROM spoof_jobs WHERE created_at < ?1`, sqliteTime(before))
+	if err != nil {
+		return 0, fmt.Errorf("error deleting old jobs: %w", translateSQLiteError(err))
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("error counting old jobs: %w", err)
+	}
+	return n, nil
+}
@@ -0,0 +1,61 @@
+package repo_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/glizzus/trf/internal/repo"
+	"github.com/glizzus/trf/internal/repo/repotest"
+	"github.com/glizzus/trf/migrations"
+)
+
+func TestSQLiteRepo(t *testing.T) {
+	repotest.TestRepo(t, func(t *testing.T) repo.Repo {
+		// Every check gets a database of its own.
+		db, err := repo.OpenSQLite(filepath.Join(t.TempDir(), "test.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+
+		sqlite := repo.NewSQLite(db)
+		if err := sqlite.Migrate(context.Background(), migrations.SQLite(), migrations.Version); err != nil {
+			t.Fatal(err)
+		}
+		return sqlite
+	})
+}
+
+func TestOpenSQLitePath(t *testing.T) {
+	ctx := context.Background()
+
+	for _, name := range []string{"plain.db", "what?.db", "number#1.db", "100%.db", "with space.db", "a:b.db"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			db, err := repo.OpenSQLite(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+
+			sqlite := repo.NewSQLite(db)
+			if err := sqlite.Migrate(ctx, migrations.SQLite(), migrations.Version); err != nil {
+				t.Fatal(err)
+			}
+			// The database is in the file we asked for, and not in one whose name was cut at a ? or a #.
+			if _, err := os.Stat(path); err != nil {
+				t.Fatalf("database isn't at %s: %v", path, err)
+			}
+			// The options after the path still apply.
+			var foreignKeys int
+			if err := db.QueryRowContext(ctx, "PRAGMA foreign_keys").Scan(&foreignKeys); err != nil {
+				t.Fatal(err)
+			}
+			if foreignKeys != 1 {
+				t.Errorf("foreign_keys = %d, want 1", foreignKeys)
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/glizzus/trf/internal/domain"
+)
+
+// userColumns are the columns of users that the queries below read, in the order scanUser expects them.
+const userColumns = `users.id, users.username, users.password_hash, users.role, users.created_at`
+
+// scanUser returns the destinations to scan userColumns into.
+func scanUser(user *domain.User) []any {
+	return []any{&user.ID, &user.Username, &user.PasswordHash, &user.Role, timeColumn{&user.Created}}
+}
+
+func (r *SQLiteRepo) SaveUser(ctx context.Context, user domain.User) (domain.User, error) {
+	const query = `
+		INSERT INTO users (username, password_hash, role)
+		VALUES (?1, ?2, ?3)
+		RETURNING id, created_at
+	`
+
+	if err := r.q.QueryRowContext(ctx, query, user.Username, user.PasswordHash, user.Role).Scan(&user.ID, timeColumn{&user.Created}); err != nil {
+		return domain.User{}, fmt.Errorf("error saving user %s: %w", user.Username, translateSQLiteError(err))
+	}
+	return user, nil
+}
+
+func (r *SQLiteRepo) GetUser(ctx context.Context, username string) (domain.User, error) {
+	const query = `SELECT ` + userColumns + ` FROM users WHERE username = ?1`
+
+	var user domain.User
+	if err := r.q.QueryRowContext(ctx, query, username).Scan(scanUser(&user)...); err != nil {
+		return domain.User{}, fmt.Errorf("error getting user %s: %w", username, translateSQLiteError(err))
+	}
+	return user, nil
+}
+
+func (r *SQLiteRepo) SetPassword(ctx context.Context, username, passwordHash string) error {
+	// Whoever knew the old password must not stay signed in, so both happen together.
+	return r.inTx(ctx, func(r *SQLiteRepo) error {
+		var id int64
+		err := r.q.QueryRowContext(ctx, `UPDATE users SET password_hash = ?2 WHERE username = ?1 RETURNING id`, username, passwordHash).Scan(&id)
+		if err != nil {
+			return fmt.Errorf("error setting password of user %s: %w", username, translateSQLiteError(err))
+		}
+		if _, err := r.q.ExecContext(ctx, `DELETE FROM sessions WHERE user_id = ?1`, id); err != nil {
+			return fmt.Errorf("error ending sessions of user %s: %w", username, translateSQLiteError(err))
+		}
+		return nil
+	})
+}
+
+func (r *SQLiteRepo) DeleteUser(ctx context.Context, username string) error {
+	// Sessions and API tokens are deleted by the cascade.
+	res, err := r.q.ExecContext(ctx, `DELETE FROM users WHERE username = ?1`, username)
+	if err != nil {
+		return fmt.Errorf("error deleting user %s: %w", username, translateSQLiteError(err))
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("error deleting user %s: %w", username, ErrNotFound)
+	}
+	return nil
+}
+
+func (r *SQLiteRepo) SaveSession(ctx context.Context, session domain.Session) error {
+	const query = `
+		INSERT INTO sessions (token_hash, user_id, csrf_token, expires_at)
+		VALUES (?1, ?2, ?3, ?4)
+	`
+
+	_, err := r.q.ExecContext(ctx, query, session.TokenHash, session.UserID, session.CSRFToken, sqliteTime(session.Expires))
+	if err != nil {
+		return fmt.Errorf("error saving session of user %d: %w", session.UserID, translateSQLiteError(err))
+	}
+	return nil
+}
+
+func (r *SQLiteRepo) GetSession(ctx context.Context, tokenHash string) (domain.Session, domain.User, error) {
+	const query = `
+		SELECT
+			sessions.token_hash,
+			sessions.user_id,
+			sessions.csrf_token,
+			sessions.created_at,
+			sessions.expires_at,
+			` + userColumns + `
+		FROM sessions
+		JOIN users ON users.id = sessions.user_id
+		WHERE sessions.token_hash = ?1 AND sessions.expires_at > ?2
+	`
+
+	var session domain.Session
+	var user domain.User
+	dest := append([]any{
+		&session.TokenHash,
+		&session.UserID,
+		&session.CSRFToken,
+		timeColumn{&session.Created},
+		timeColumn{&session.Expires},
+	}, scanUser(&user)...)
+	if err := r.q.QueryRowContext(ctx, query, tokenHash, sqliteNow()).Scan(dest...); err != nil {
+		// The token hash is as good as a password, so it stays out of the error.
+		return domain.Session{}, domain.User{}, fmt.Errorf("error getting session: %w", translateSQLiteError(err))
+	}
+	return session, user, nil
+}
+
+func (r *SQLiteRepo) DeleteSession(ctx context.Context, tokenHash string) error {
+	if _, err := r.q.ExecContext(ctx, `DELETE FROM sessions WHERE token_hash = ?1`, tokenHash); err != nil {
+		return fmt.Errorf("error deleting session: %w", translateSQLiteError(err))
+	}
+	return nil
+}
+
+func (r *SQLiteRepo) DeleteExpiredSessions(ctx context.Context) (int64, error) {
+	res, err := r.q.ExecContext(ctx, `DELETE FROM sessions WHERE expires_at <= ?1`, sqliteNow())
+	if err != nil {
+		return 0, fmt.Errorf("error deleting expired sessions: %w", translateSQLiteError(err))
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("error counting expired sessions: %w", err)
+	}
+	return n, nil
+}
+
+func (r *SQLiteRepo) SaveAPIToken(ctx context.Context, token domain.APIToken) (domain.APIToken, error) {
+	const query = `
+		INSERT INTO api_tokens (token_hash, user_id, name, scopes, expires_at)
+		VALUES (?1, ?2, ?3, ?4, ?5)
+		RETURNING id, created_at
+	`
+
+	if err := r.q.QueryRowContext(
+		ctx,
+		query,
+		token.TokenHash,
+		token.UserID,
+		token.Name,
+		jsonArray(scopeStrings(token.Scopes)),
+		sqliteNullTime(token.Expires),
+	).Scan(&token.ID, timeColumn{&token.Created}); err != nil {
+		return domain.APIToken{}, fmt.Errorf("error saving API token %q: %w", token.Name, translateSQLiteError(err))
+	}
+	return token, nil
+}
+
+func (r *SQLiteRepo) UseAPIToken(ctx context.Context, tokenHash string) (domain.APIToken, domain.User, error) {
+	// SQLite doesn't let RETURNING read the tables in FROM, so the user is read separately.
+	const tokenQuery = `
+		UPDATE api_tokens
+		SET last_used_at = ?2
+		WHERE token_hash = ?1 AND (expires_at IS NULL OR expires_at > ?2)
+		RETURNING id, token_hash, user_id, name, scopes, created_at, expires_at
+	`
+	const userQuery = `SELECT ` + userColumns + ` FROM users WHERE id = ?1`
+
+	var token domain.APIToken
+	var user domain.User
+	err := r.inTx(ctx, func(r *SQLiteRepo) error {
+		var scopes []string
+		if err := r.q.QueryRowContext(ctx, tokenQuery, tokenHash, sqliteNow()).Scan(
+			&token.ID,
+			&token.TokenHash,
+			&token.UserID,
+			&token.Name,
+			jsonColumn{&scopes},
+			timeColumn{&token.Created},
+			nullTimeColumn{&token.Expires},
+		); err != nil {
+			return err
+		}
+		for _, scope := range scopes {
+			token.Scopes = append(token.Scopes, domain.Scope(scope))
+		}
+		return r.q.QueryRowContext(ctx, userQuery, token.UserID).Scan(scanUser(&user)...)
+	})
+	if err != nil {
+		return domain.APIToken{}, domain.User{}, fmt.Errorf("error using API token: %w", translateSQLiteError(err))
+	}
+	return token, user, nil
+}
+
+var _ UserRepo = &SQLiteRepo{}
@@ -0,0 +1,126 @@
+package schedule
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/glizzus/trf/internal/metrics"
+	"github.com/glizzus/trf/internal/repo"
+)
+
+// Elector elects one leader among the instances that share a Locker, which leads for as long as it holds a lock.
+// If the leader dies or loses its connection to the database, the lock is released and another instance takes over.
+//
+// A leader that loses the lock in the middle of a run finishes it, so two runs of a job can overlap
+// for a moment when the database connection fails. That is harmless, because saving an article or a spoof
+// that is already saved does nothing.
+type Elector struct {
+	locker   repo.Locker
+	name     string
+	interval time.Duration
+
+	// lock is the lock we hold while we lead. It is only used by Run.
+	lock repo.Lock
+
+	// since is when we became the leader, in Unix nanoseconds, or 0 if we don't lead.
+	since atomic.Int64
+
+	// ready is closed once Run has held its first election.
+	ready     chan struct{}
+	readyOnce sync.Once
+}
+
+// NewElector creates an Elector that competes for the lock with the given name.
+// Followers try to take the lock every interval, and the leader checks that it still holds it as often.
+func NewElector(locker repo.Locker, name string, interval time.Duration) *Elector {
+	return &Elector{
+		locker:   locker,
+		name:     name,
+		interval: interval,
+		ready:    make(chan struct{}),
+	}
+}
+
+// IsLeader returns whether this instance leads.
+func (e *Elector) IsLeader() bool {
+	return e.since.Load() != 0
+}
+
+// Ready returns a channel that is closed once Run has held its first election,
+// after which IsLeader tells whether we won it. Until then, IsLeader returns false even if we are about to lead.
+func (e *Elector) Ready() <-chan struct{} {
+	return e.ready
+}
+
+// LeaderSince returns when this instance became the leader, or the zero time if it doesn't lead.
+func (e *Elector) LeaderSince() time.Time {
+	nanos := e.since.Load()
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos)
+}
+
+// Run takes part in the election until ctx is done, and then gives up the lead if it has it.
+func (e *Elector) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		e.elect(ctx)
+		e.readyOnce.Do(func() { close(e.ready) })
+		select {
+		case <-ctx.Done():
+			e.resign()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// elect checks that the leader still holds the lock, and tries to take it otherwise.
+func (e *Elector) elect(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, e.interval)
+	defer cancel()
+
+	if e.lock != nil {
+		err := e.lock.Check(ctx)
+		if err == nil {
+			return
+		}
+		slog.WarnContext(ctx, "lost the lead", "lock", e.name, "error", err)
+		e.resign()
+	}
+
+	lock, ok, err := e.locker.TryLock(ctx, e.name)
+	switch {
+	case err != nil:
+		slog.WarnContext(ctx, "failed to try to take the lead", "lock", e.name, "error", err)
+		return
+	case !ok:
+		return
+	}
+	e.lock = lock
+	e.since.Store(time.Now().UnixNano())
+	metrics.ScheduleLeader.Set(1)
+	slog.InfoContext(ctx, "took the lead, running scheduled jobs", "lock", e.name)
+}
+
+// resign gives up the lead, if we have it.
+func (e *Elector) resign() {
+	if e.lock == nil {
+		return
+	}
+	e.since.Store(0)
+	metrics.ScheduleLeader.Set(0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), e.interval)
+	defer cancel()
+	if err := e.lock.Unlock(ctx); err != nil {
+		slog.WarnContext(ctx, "failed to release the lead", "lock", e.name, "error", err)
+	}
+	e.lock = nil
+}
@@ -0,0 +1,207 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/glizzus/trf/internal/repo"
+)
+
+// fakeLocker is a repo.Locker whose one lock can be taken by someone else, or lost, at will.
+type fakeLocker struct {
+	mu sync.Mutex
+	// taken is whether someone holds the lock, us or another instance.
+	taken bool
+	// lost makes Check fail, like it does when the connection that holds the lock is gone.
+	lost bool
+	// err makes TryLock fail.
+	err error
+	// unlocks counts how many times a lock was released.
+	unlocks int
+}
+
+type fakeLock struct {
+	locker *fakeLocker
+}
+
+func (l *fakeLocker) TryLock(ctx context.Context, name string) (repo.Lock, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.err != nil {
+		return nil, false, l.err
+	}
+	if l.taken {
+		return nil, false, nil
+	}
+	l.taken = true
+	l.lost = false
+	return fakeLock{l}, true, nil
+}
+
+// set changes the state of the locker while holding its lock.
+func (l *fakeLocker) set(f func(l *fakeLocker)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	f(l)
+}
+
+func (l *fakeLocker) unlocked() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.unlocks
+}
+
+func (l fakeLock) Check(ctx context.Context) error {
+	l.locker.mu.Lock()
+	defer l.locker.mu.Unlock()
+	if l.locker.lost {
+		return errors.New("connection lost")
+	}
+	return nil
+}
+
+func (l fakeLock) Unlock(ctx context.Context) error {
+	l.locker.mu.Lock()
+	defer l.locker.mu.Unlock()
+	// A lost lock was already released with its connection.
+	if !l.locker.lost {
+		l.locker.taken = false
+	}
+	l.locker.unlocks++
+	return nil
+}
+
+// testInterval is how often the electors of the tests check the lock.
+const testInterval = 5 * time.Millisecond
+
+// eventually fails the test unless cond becomes true within a second.
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// runElector runs e until the test ends, and returns a function that stops it and waits for Run to return.
+func runElector(t *testing.T, e *Elector) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		e.Run(ctx)
+	}()
+	stop = func() {
+		cancel()
+		<-done
+	}
+	t.Cleanup(stop)
+	return stop
+}
+
+func waitReady(t *testing.T, e *Elector) {
+	t.Helper()
+	select {
+	case <-e.Ready():
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the first election")
+	}
+}
+
+func TestElectorLeads(t *testing.T) {
+	locker := &fakeLocker{}
+	e := NewElector(locker, "test", testInterval)
+	if e.IsLeader() || !e.LeaderSince().IsZero() {
+		t.Fatal("leads before Run")
+	}
+
+	stop := runElector(t, e)
+	waitReady(t, e)
+	// The first election is over as soon as Ready is, so there is no need to wait any further.
+	if !e.IsLeader() {
+		t.Fatal("doesn't lead after the first election, with no one else around")
+	}
+	if since := e.LeaderSince(); time.Since(since) > time.Second {
+		t.Errorf("LeaderSince() = %v, want about now", since)
+	}
+
+	// Stopping gives up the lock, so that another instance can take over straight away.
+	stop()
+	if e.IsLeader() {
+		t.Error("still leads after Run returned")
+	}
+	if n := locker.unlocked(); n != 1 {
+		t.Errorf("unlocked %d times, want 1", n)
+	}
+	if _, ok, _ := locker.TryLock(context.Background(), "test"); !ok {
+		t.Error("the lock is still held after Run returned")
+	}
+}
+
+func TestElectorFollows(t *testing.T) {
+	locker := &fakeLocker{taken: true}
+	e := NewElector(locker, "test", testInterval)
+
+	stop := runElector(t, e)
+	waitReady(t, e)
+	if e.IsLeader() {
+		t.Fatal("leads while another instance holds the lock")
+	}
+
+	// The leader goes away, and we take over.
+	locker.set(func(l *fakeLocker) { l.taken = false })
+	eventually(t, "the lead", e.IsLeader)
+
+	stop()
+	if n := locker.unlocked(); n != 1 {
+		t.Errorf("unlocked %d times, want 1", n)
+	}
+}
+
+func TestElectorLosesLead(t *testing.T) {
+	locker := &fakeLocker{}
+	e := NewElector(locker, "test", testInterval)
+
+	runElector(t, e)
+	waitReady(t, e)
+	if !e.IsLeader() {
+		t.Fatal("doesn't lead after the first election")
+	}
+
+	// The connection that held the lock is gone, and another instance takes the lock.
+	locker.set(func(l *fakeLocker) {
+		l.lost = true
+		l.taken = true
+	})
+	eventually(t, "the lead to be lost", func() bool { return !e.IsLeader() })
+	if !e.LeaderSince().IsZero() {
+		t.Errorf("LeaderSince() = %v after losing the lead, want zero", e.LeaderSince())
+	}
+	// The lead is given up before the lock is released, so the release may still be on its way.
+	eventually(t, "the lost lock to be released", func() bool { return locker.unlocked() == 1 })
+
+	// Once the other instance goes away, we lead again.
+	locker.set(func(l *fakeLocker) { l.taken = false })
+	eventually(t, "the lead to be taken back", e.IsLeader)
+}
+
+func TestElectorLockError(t *testing.T) {
+	locker := &fakeLocker{err: errors.New("database is down")}
+	e := NewElector(locker, "test", testInterval)
+
+	runElector(t, e)
+	// A failed election is still an election, so that the scheduler doesn't wait for the database forever.
+	waitReady(t, e)
+	if e.IsLeader() {
+		t.Fatal("leads without the lock")
+	}
+
+	locker.set(func(l *fakeLocker) { l.err = nil })
+	eventually(t, "the lead", e.IsLeader)
+}
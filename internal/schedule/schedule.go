@@ -0,0 +1,217 @@
+// Package schedule runs jobs on cron schedules, on only one of the instances that share a database.
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	"github.com/glizzus/trf/internal/metrics"
+)
+
+// Schedule tells when a job runs next.
+type Schedule interface {
+	// Next returns the first time the job runs after t.
+	Next(t time.Time) time.Time
+}
+
+// Parse parses a cron expression with five fields, like "0 * * * *",
+// or a descriptor like "@hourly" or "@every 30m". Times are local,
+// unless the expression starts with a time zone, like "CRON_TZ=Europe/London 0 9 * * *".
+func Parse(spec string) (Schedule, error) {
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing schedule %q: %w", spec, err)
+	}
+	return schedule, nil
+}
+
+// Job is work that runs on a schedule.
+type Job struct {
+	Name     string
+	Schedule Schedule
+
+	// Jitter delays each run by a random duration up to Jitter, so that we don't hit Snopes
+	// at the same second every hour. Keep it well below the time between runs.
+	Jitter time.Duration
+
+	// RunOnStart also runs the job as soon as the scheduler starts, after the jitter.
+	RunOnStart bool
+
+	Run func(ctx context.Context) error
+}
+
+// Leader tells whether this instance is the one that should run jobs.
+type Leader interface {
+	IsLeader() bool
+	// Ready returns a channel that is closed once IsLeader can be trusted, after the first election.
+	Ready() <-chan struct{}
+}
+
+// Scheduler runs jobs on their schedules. Each job runs in a goroutine of its own,
+// and a run that is still going at the job's next time makes it skip that time.
+type Scheduler struct {
+	leader Leader
+	jobs   []Job
+
+	// stopping is closed by Shutdown to ask the scheduler not to start any more runs.
+	stopping chan struct{}
+	stopOnce sync.Once
+
+	// done is closed when Run returns.
+	done chan struct{}
+
+	mu sync.Mutex
+	// cancel aborts the runs in progress. It is nil until Run is called.
+	cancel context.CancelFunc
+}
+
+// New creates a Scheduler with no jobs, which only runs them while leader says that this instance leads.
+// If leader is nil, jobs always run.
+func New(leader Leader) *Scheduler {
+	return &Scheduler{
+		leader:   leader,
+		stopping: make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Add adds a job to the scheduler. It must be called before Run.
+func (s *Scheduler) Add(job Job) {
+	s.jobs = append(s.jobs, job)
+}
+
+// Run runs every job on its schedule. It blocks until Shutdown is called or ctx is done,
+// and the runs in progress have returned. It must only be called once.
+//
+// Cancelling ctx aborts the runs in progress. Use Shutdown to let them finish instead.
+func (s *Scheduler) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	s.mu.Lock()
+	s.cancel = cancel
+	s.mu.Unlock()
+	defer close(s.done)
+
+	var wg sync.WaitGroup
+	for _, job := range s.jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.loop(ctx, job)
+		}()
+	}
+	wg.Wait()
+
+	if s.isStopping() {
+		return nil
+	}
+	return ctx.Err()
+}
+
+// Shutdown asks the scheduler not to start any more runs, and waits for the runs in progress to return.
+//
+// If ctx is done before then, the runs in progress are cancelled
+// and Shutdown returns ctx.Err() without waiting any further.
+func (s *Scheduler) Shutdown(ctx context.Context) error {
+	s.stopOnce.Do(func() { close(s.stopping) })
+
+	s.mu.Lock()
+	cancel := s.cancel
+	s.mu.Unlock()
+	// Run was never called, so there is nothing to wait for.
+	if cancel == nil {
+		return nil
+	}
+
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		cancel()
+		return ctx.Err()
+	}
+}
+
+func (s *Scheduler) isStopping() bool {
+	select {
+	case <-s.stopping:
+		return true
+	default:
+		return false
+	}
+}
+
+// loop runs job every time its schedule says, until the scheduler stops.
+func (s *Scheduler) loop(ctx context.Context, job Job) {
+	// Before the first election, every instance looks like a follower, and a job that runs on start
+	// would be skipped by the instance that is about to lead, until its next time.
+	if s.leader != nil {
+		select {
+		case <-s.leader.Ready():
+		case <-s.stopping:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+
+	next := time.Now()
+	if !job.RunOnStart {
+		next = job.Schedule.Next(next)
+	}
+
+	for {
+		delay := time.Until(next) + jitter(job.Jitter)
+		slog.DebugContext(ctx, "scheduled job", "job", job.Name, "at", time.Now().Add(delay))
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-s.stopping:
+			timer.Stop()
+			return
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		// A timer and a stop can be ready at the same time, and the stop wins.
+		if s.isStopping() {
+			return
+		}
+
+		if s.leader == nil || s.leader.IsLeader() {
+			s.run(ctx, job)
+		} else {
+			slog.DebugContext(ctx, "skipping scheduled job, another instance leads", "job", job.Name)
+		}
+		next = job.Schedule.Next(time.Now())
+	}
+}
+
+// run runs job once, and records how it went.
+func (s *Scheduler) run(ctx context.Context, job Job) {
+	slog.InfoContext(ctx, "running scheduled job", "job", job.Name)
+	start := time.Now()
+	err := job.Run(ctx)
+	metrics.ScheduledRuns.WithLabelValues(job.Name, metrics.Outcome(err)).Inc()
+	if err != nil {
+		slog.ErrorContext(ctx, "scheduled job failed", "job", job.Name, "error", err)
+		return
+	}
+	slog.InfoContext(ctx, "finished scheduled job", "job", job.Name, "duration", time.Since(start).Round(time.Millisecond))
+}
+
+// jitter returns a random duration in [0, max).
+func jitter(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return rand.N(max)
+}
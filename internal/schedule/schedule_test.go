@@ -0,0 +1,174 @@
+package schedule
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// every is a Schedule that runs a job every d, which cron can't do more often than every second.
+type every time.Duration
+
+func (d every) Next(t time.Time) time.Time { return t.Add(time.Duration(d)) }
+
+// fakeLeader is a Leader that is told when the first election is over, and who won it.
+type fakeLeader struct {
+	leader atomic.Bool
+	ready  chan struct{}
+}
+
+func newFakeLeader() *fakeLeader {
+	return &fakeLeader{ready: make(chan struct{})}
+}
+
+func (l *fakeLeader) IsLeader() bool         { return l.leader.Load() }
+func (l *fakeLeader) Ready() <-chan struct{} { return l.ready }
+
+// runScheduler runs s until the test ends, and returns a channel that gets what Run returned.
+func runScheduler(t *testing.T, s *Scheduler) <-chan error {
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() { errc <- s.Run(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		s.Shutdown(context.Background())
+	})
+	return errc
+}
+
+// countingJob returns a job that runs on schedule, and the number of times it ran.
+func countingJob(schedule Schedule, runOnStart bool) (Job, *atomic.Int32) {
+	var runs atomic.Int32
+	return Job{
+		Name:       "test",
+		Schedule:   schedule,
+		RunOnStart: runOnStart,
+		Run: func(ctx context.Context) error {
+			runs.Add(1)
+			return nil
+		},
+	}, &runs
+}
+
+func TestSchedulerWaitsForElection(t *testing.T) {
+	leader := newFakeLeader()
+	// Before the election, the instance that is about to win already says it leads, which it mustn't be asked yet.
+	leader.leader.Store(true)
+	job, runs := countingJob(every(time.Hour), true)
+
+	s := New(leader)
+	s.Add(job)
+	runScheduler(t, s)
+
+	time.Sleep(20 * time.Millisecond)
+	if n := runs.Load(); n != 0 {
+		t.Fatalf("ran %d times before the first election, want 0", n)
+	}
+
+	close(leader.ready)
+	eventually(t, "the job to run on start", func() bool { return runs.Load() == 1 })
+}
+
+func TestSchedulerRunsOnStartAfterLateElection(t *testing.T) {
+	locker := &fakeLocker{}
+	e := NewElector(locker, "test", testInterval)
+	job, runs := countingJob(every(time.Hour), true)
+
+	// The scheduler starts before the elector, like it can when both are started at once.
+	s := New(e)
+	s.Add(job)
+	runScheduler(t, s)
+	time.Sleep(10 * time.Millisecond)
+	runElector(t, e)
+
+	eventually(t, "the job to run on start", func() bool { return runs.Load() == 1 })
+}
+
+func TestSchedulerOnlyRunsWhileLeading(t *testing.T) {
+	leader := newFakeLeader()
+	leader.leader.Store(true)
+	close(leader.ready)
+	job, runs := countingJob(every(2*time.Millisecond), false)
+
+	s := New(leader)
+	s.Add(job)
+	runScheduler(t, s)
+	eventually(t, "the job to run a few times", func() bool { return runs.Load() >= 3 })
+
+	// Another instance takes over. A run that already started may still finish.
+	leader.leader.Store(false)
+	time.Sleep(10 * time.Millisecond)
+	stopped := runs.Load()
+	time.Sleep(20 * time.Millisecond)
+	if n := runs.Load(); n != stopped {
+		t.Fatalf("ran %d more times after losing the lead, want 0", n-stopped)
+	}
+
+	leader.leader.Store(true)
+	eventually(t, "the job to run again", func() bool { return runs.Load() > stopped })
+}
+
+func TestSchedulerShutdown(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var finished atomic.Bool
+	s := New(nil)
+	s.Add(Job{
+		Name:       "slow",
+		Schedule:   every(time.Hour),
+		RunOnStart: true,
+		Run: func(ctx context.Context) error {
+			close(started)
+			<-release
+			finished.Store(true)
+			return nil
+		},
+	})
+	errc := runScheduler(t, s)
+	<-started
+
+	// Shutdown waits for the run in progress.
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- s.Shutdown(context.Background()) }()
+	select {
+	case err := <-shutdown:
+		t.Fatalf("Shutdown returned %v before the run finished", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	close(release)
+	if err := <-shutdown; err != nil {
+		t.Fatalf("Shutdown returned %v, want nil", err)
+	}
+	if !finished.Load() {
+		t.Error("Shutdown returned before the run finished")
+	}
+	if err := <-errc; err != nil {
+		t.Errorf("Run returned %v after Shutdown, want nil", err)
+	}
+}
+
+func TestSchedulerShutdownTimeout(t *testing.T) {
+	started := make(chan struct{})
+	s := New(nil)
+	s.Add(Job{
+		Name:       "stuck",
+		Schedule:   every(time.Hour),
+		RunOnStart: true,
+		Run: func(ctx context.Context) error {
+			close(started)
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	})
+	runScheduler(t, s)
+	<-started
+
+	// A run that doesn't finish in time is cancelled.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Shutdown returned %v, want %v", err, context.DeadlineExceeded)
+	}
+}
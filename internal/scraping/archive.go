@@ -0,0 +1,214 @@
+package scraping
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrNotArchived is returned when there is no archived page for a URL.
+var ErrNotArchived = errors.New("page not archived")
+
+// Page is a raw page fetched from Snopes, along with how and when we fetched it.
+type Page struct {
+	URL string `json:"url"`
+
+	// Hash is the hex-encoded SHA-256 of Body. Pages are stored by their hash,
+	// so fetching the same content twice only stores it once.
+	Hash string `json:"hash"`
+	Body []byte `json:"-"`
+
+	StatusCode   int       `json:"status_code"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	FetchedAt    time.Time `json:"fetched_at"`
+}
+
+// Archive stores the raw pages that we scrape, so that we can make conditional
+// requests when fetching them again, and re-parse them without the network.
+type Archive interface {
+	// Save stores the page as the latest version of its URL.
+	Save(ctx context.Context, page Page) error
+
+	// Latest returns the latest version of the page at url.
+	// It returns ErrNotArchived if the URL was never archived.
+	Latest(ctx context.Context, url string) (Page, error)
+
+	// URLs returns the URL of every archived page.
+	URLs(ctx context.Context) ([]string, error)
+}
+
+func hashBody(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// checkHash returns an error unless hash looks like one that hashBody returns.
+// Blobs are stored under their hash, so a malformed one from a corrupted metadata file
+// must not make it into a path.
+func checkHash(hash string) error {
+	if len(hash) != hex.EncodedLen(sha256.Size) {
+		return fmt.Errorf("invalid hash %q: want %d hex digits", hash, hex.EncodedLen(sha256.Size))
+	}
+	for _, c := range hash {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return fmt.Errorf("invalid hash %q: want lowercase hex digits", hash)
+		}
+	}
+	return nil
+}
+
+// DirArchive is an Archive that stores pages in a directory:
+//
+//	blobs/ab/abcdef....gz  gzip-compressed page bodies, named by their hash
+//	pages/0123....json     metadata for the latest fetch of each URL, named by the hash of the URL
+type DirArchive struct {
+	dir string
+}
+
+// NewDirArchive creates a DirArchive rooted at dir, creating the directory if needed.
+func NewDirArchive(dir string) (*DirArchive, error) {
+	for _, sub := range []string{"blobs", "pages"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			return nil, fmt.Errorf("unable to create archive directory: %w", err)
+		}
+	}
+	return &DirArchive{dir: dir}, nil
+}
+
+// blobPath returns the path of the blob with the given hash, which must have been checked with checkHash.
+func (a *DirArchive) blobPath(hash string) string {
+	return filepath.Join(a.dir, "blobs", hash[:2], hash+".gz")
+}
+
+func (a *DirArchive) pagePath(url string) string {
+	return filepath.Join(a.dir, "pages", hashBody([]byte(url))+".json")
+}
+
+func (a *DirArchive) Save(ctx context.Context, page Page) error {
+	if page.Hash == "" {
+		page.Hash = hashBody(page.Body)
+	}
+	if err := checkHash(page.Hash); err != nil {
+		return fmt.Errorf("unable to archive %s: %w", page.URL, err)
+	}
+
+	blobPath := a.blobPath(page.Hash)
+	if _, err := os.Stat(blobPath); errors.Is(err, fs.ErrNotExist) {
+		if err := writeAtomic(blobPath, func(w io.Writer) error {
+			gz := gzip.NewWriter(w)
+			if _, err := gz.Write(page.Body); err != nil {
+				return err
+			}
+			return gz.Close()
+		}); err != nil {
+			return fmt.Errorf("unable to archive body of %s: %w", page.URL, err)
+		}
+	} else if err != nil {
+		return fmt.Errorf("unable to check archive for body of %s: %w", page.URL, err)
+	}
+
+	if err := writeAtomic(a.pagePath(page.URL), func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(page)
+	}); err != nil {
+		return fmt.Errorf("unable to archive metadata of %s: %w", page.URL, err)
+	}
+
+	return nil
+}
+
+func (a *DirArchive) Latest(ctx context.Context, url string) (Page, error) {
+	var page Page
+
+	meta, err := os.ReadFile(a.pagePath(url))
+	if errors.Is(err, fs.ErrNotExist) {
+		return page, ErrNotArchived
+	}
+	if err != nil {
+		return page, fmt.Errorf("unable to read archived metadata of %s: %w", url, err)
+	}
+	if err := json.Unmarshal(meta, &page); err != nil {
+		return page, fmt.Errorf("unable to decode archived metadata of %s: %w", url, err)
+	}
+	if err := checkHash(page.Hash); err != nil {
+		return page, fmt.Errorf("unable to decode archived metadata of %s: %w", url, err)
+	}
+
+	f, err := os.Open(a.blobPath(page.Hash))
+	if err != nil {
+		return page, fmt.Errorf("unable to open archived body of %s: %w", url, err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return page, fmt.Errorf("unable to decompress archived body of %s: %w", url, err)
+	}
+	page.Body, err = io.ReadAll(gz)
+	if err != nil {
+		return page, fmt.Errorf("unable to read archived body of %s: %w", url, err)
+	}
+
+	return page, nil
+}
+
+func (a *DirArchive) URLs(ctx context.Context) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(a.dir, "pages"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to list archived pages: %w", err)
+	}
+
+	var urls []string
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		meta, err := os.ReadFile(filepath.Join(a.dir, "pages", entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("unable to read archived metadata %s: %w", entry.Name(), err)
+		}
+		var page Page
+		if err := json.Unmarshal(meta, &page); err != nil {
+			return nil, fmt.Errorf("unable to decode archived metadata %s: %w", entry.Name(), err)
+		}
+		urls = append(urls, page.URL)
+	}
+
+	return urls, nil
+}
+
+// writeAtomic writes a file by writing to a temporary file and renaming it,
+// so that readers never see a partially written file.
+func writeAtomic(path string, write func(io.Writer) error) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+var _ Archive = &DirArchive{}
@@ -0,0 +1,308 @@
+package scraping
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/temoto/robotstxt"
+)
+
+// ClientOptions configure how a Client talks to Snopes.
+type ClientOptions struct {
+	// Timeout bounds each HTTP request, including reading the body.
+	Timeout time.Duration
+
+	// MaxRetries is how many times a request is retried after a 429, a 5xx, or a network error.
+	MaxRetries int
+
+	// RetryDelay is the base delay between retries. It doubles after every attempt,
+	// and the actual delay is chosen at random up to that, so that retries don't synchronize.
+	RetryDelay time.Duration
+
+	// UserAgent is sent with every request, and is the agent we look for in robots.txt.
+	UserAgent string
+
+	// Proxy is the URL of a proxy to send requests through.
+	// If it is empty, the usual HTTP_PROXY and HTTPS_PROXY environment variables are used.
+	Proxy string
+
+	// PolitenessDelay is the minimum time between the start of two requests to the same host.
+	PolitenessDelay time.Duration
+
+	// IgnoreRobots disables checking robots.txt before fetching a page.
+	IgnoreRobots bool
+}
+
+// DefaultClientOptions are the options used by a GoqueryScraper that was not given a Client.
+var DefaultClientOptions = ClientOptions{
+	Timeout:         20 * time.Second,
+	MaxRetries:      3,
+	RetryDelay:      1 * time.Second,
+	UserAgent:       "TotallyRealFacts/1.0 (+https://github.com/glizzus/trf)",
+	PolitenessDelay: 2 * time.Second,
+}
+
+// ErrDisallowed is returned when robots.txt does not allow us to fetch a page.
+var ErrDisallowed = errors.New("disallowed by robots.txt")
+
+// StatusError is returned when a page is fetched with a non-2xx status code.
+// Snopes sits behind Cloudflare, so this is usually an error or challenge page
+// that we must not mistake for an article.
+type StatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status %d %s fetching %s", e.StatusCode, http.StatusText(e.StatusCode), e.URL)
+}
+
+// retryable returns whether a request that failed with this status is worth retrying.
+func retryable(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError
+}
+
+// robotsTTL is how long we trust a host's robots.txt before fetching it again.
+const robotsTTL = 24 * time.Hour
+
+type robotsEntry struct {
+	group     *robotstxt.Group
+	fetchedAt time.Time
+}
+
+// Client fetches pages politely: it identifies itself, respects robots.txt,
+// spaces out requests to the same host, and backs off when the host is struggling.
+// It is safe for concurrent use.
+type Client struct {
+	http *http.Client
+	opts ClientOptions
+
+	mu sync.Mutex
+	// next is the earliest time we may send the next request to each host.
+	next   map[string]time.Time
+	robots map[string]robotsEntry
+}
+
+// NewClient creates a Client with the given options.
+func NewClient(opts ClientOptions) (*Client, error) {
+	proxy := http.ProxyFromEnvironment
+	if opts.Proxy != "" {
+		proxyURL, err := url.Parse(opts.Proxy)
+		if err != nil {
+			// The URL may have a password in it, and url.Error quotes it, so only the reason is kept.
+			var urlErr *url.Error
+			if errors.As(err, &urlErr) {
+				err = urlErr.Err
+			}
+			return nil, fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		proxy = http.ProxyURL(proxyURL)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxy
+
+	return &Client{
+		http: &http.Client{
+			Timeout:   opts.Timeout,
+			Transport: transport,
+		},
+		opts:   opts,
+		next:   make(map[string]time.Time),
+		robots: make(map[string]robotsEntry),
+	}, nil
+}
+
+// Get fetches rawURL with the given extra request headers, retrying transient failures.
+// header may be nil.
+//
+// The caller must close the body of the returned response. A response is only
+// returned if its status code is 2xx, or 304 if header made the request conditional.
+// Anything else is a *StatusError.
+func (c *Client) Get(ctx context.Context, rawURL string, header http.Header) (*http.Response, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL %q: %w", rawURL, err)
+	}
+
+	if !c.opts.IgnoreRobots {
+		allowed, err := c.allowed(ctx, u)
+		if err != nil {
+			return nil, fmt.Errorf("unable to check robots.txt: %w", err)
+		}
+		if !allowed {
+			return nil, fmt.Errorf("unable to fetch %s: %w", rawURL, ErrDisallowed)
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		res, err := c.do(ctx, u, header)
+		if err == nil {
+			return res, nil
+		}
+
+		var statusErr *StatusError
+		isStatusErr := errors.As(err, &statusErr)
+		if ctx.Err() != nil || (isStatusErr && !retryable(statusErr.StatusCode)) || attempt >= c.opts.MaxRetries {
+			return nil, err
+		}
+
+		delay := c.backoff(attempt, res)
+		slog.WarnContext(ctx, "retrying request", "url", rawURL, "attempt", attempt+1, "delay", delay, "error", err)
+		if err := sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// do sends a single request. If the status code is not successful, the body is discarded,
+// and the response is returned alongside a *StatusError so that its headers can be inspected.
+func (c *Client) do(ctx context.Context, u *url.URL, header http.Header) (*http.Response, error) {
+	if err := c.wait(ctx, u.Host); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create http request: %w", err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("User-Agent", c.opts.UserAgent)
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute http request: %w", err)
+	}
+
+	conditional := req.Header.Get("If-None-Match") != "" || req.Header.Get("If-Modified-Since") != ""
+	if res.StatusCode == http.StatusNotModified && conditional {
+		return res, nil
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		// Draining the body lets the connection be reused.
+		io.Copy(io.Discard, io.LimitReader(res.Body, 1<<16))
+		res.Body.Close()
+		return res, &StatusError{URL: u.String(), StatusCode: res.StatusCode}
+	}
+
+	return res, nil
+}
+
+// maxRetryAfter caps how long we honor a Retry-After header for.
+// Waiting any longer than this is better left to the next ingest run.
+const maxRetryAfter = 1 * time.Minute
+
+// backoff returns how long to wait before retrying after the given attempt.
+// If the host told us how long to wait with Retry-After, we listen to it.
+func (c *Client) backoff(attempt int, res *http.Response) time.Duration {
+	if res != nil {
+		if seconds, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			return min(time.Duration(seconds)*time.Second, maxRetryAfter)
+		}
+	}
+	ceiling := c.opts.RetryDelay << attempt
+	if ceiling <= 0 {
+		return 0
+	}
+	return rand.N(ceiling) + 1
+}
+
+// wait blocks until we may send a request to host without being impolite.
+func (c *Client) wait(ctx context.Context, host string) error {
+	if c.opts.PolitenessDelay <= 0 {
+		return nil
+	}
+
+	c.mu.Lock()
+	now := time.Now()
+	at := c.next[host]
+	if at.Before(now) {
+		at = now
+	}
+	// Reserve our slot before sleeping, so that concurrent callers queue up behind us.
+	c.next[host] = at.Add(c.opts.PolitenessDelay)
+	c.mu.Unlock()
+
+	return sleep(ctx, time.Until(at))
+}
+
+// allowed returns whether robots.txt allows our user agent to fetch u.
+func (c *Client) allowed(ctx context.Context, u *url.URL) (bool, error) {
+	c.mu.Lock()
+	entry, ok := c.robots[u.Host]
+	c.mu.Unlock()
+
+	if !ok || time.Since(entry.fetchedAt) > robotsTTL {
+		group, err := c.fetchRobots(ctx, u)
+		if err != nil {
+			return false, err
+		}
+		entry = robotsEntry{group: group, fetchedAt: time.Now()}
+
+		c.mu.Lock()
+		c.robots[u.Host] = entry
+		c.mu.Unlock()
+	}
+
+	return entry.group.Test(u.Path), nil
+}
+
+func (c *Client) fetchRobots(ctx context.Context, u *url.URL) (*robotstxt.Group, error) {
+	robotsURL := &url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/robots.txt"}
+
+	if err := c.wait(ctx, u.Host); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, robotsURL.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create http request: %w", err)
+	}
+	req.Header.Set("User-Agent", c.opts.UserAgent)
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute http request: %w", err)
+	}
+	defer res.Body.Close()
+
+	// A 5xx is usually temporary, so we don't want to cache it as "disallow everything" for a day.
+	if res.StatusCode >= http.StatusInternalServerError {
+		return nil, &StatusError{URL: robotsURL.String(), StatusCode: res.StatusCode}
+	}
+
+	// FromResponse treats a 4xx as there being no robots.txt, which allows everything.
+	robots, err := robotstxt.FromResponse(res)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %w", robotsURL, err)
+	}
+
+	return robots.FindGroup(c.opts.UserAgent), nil
+}
+
+// sleep is like time.Sleep, but returns early with an error if ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
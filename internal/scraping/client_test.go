@@ -0,0 +1,313 @@
+package scraping
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testClientOptions retry quickly and don't wait between requests, so that the tests don't take long.
+var testClientOptions = ClientOptions{
+	Timeout:    time.Second,
+	MaxRetries: 3,
+	RetryDelay: time.Millisecond,
+	UserAgent:  "TestBot/1.0",
+}
+
+// hits counts the requests to each path of a test server.
+type hits struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func (h *hits) add(path string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.counts == nil {
+		h.counts = make(map[string]int)
+	}
+	h.counts[path]++
+}
+
+func (h *hits) get(path string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.counts[path]
+}
+
+// newTestSite starts a server that answers each path with the statuses in order, and then with the last one.
+// 200s have the path as their body. robots.txt is served unless it is one of the paths.
+func newTestSite(t *testing.T, statuses map[string][]int, robots string) (*httptest.Server, *hits) {
+	t.Helper()
+	h := &hits{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := h.get(r.URL.Path)
+		h.add(r.URL.Path)
+		if r.Header.Get("User-Agent") != testClientOptions.UserAgent {
+			t.Errorf("request to %s has User-Agent %q, want %q", r.URL.Path, r.Header.Get("User-Agent"), testClientOptions.UserAgent)
+		}
+
+		codes, ok := statuses[r.URL.Path]
+		if !ok && r.URL.Path == "/robots.txt" {
+			io.WriteString(w, robots)
+			return
+		}
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		code := codes[min(n, len(codes)-1)]
+		if code == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "0")
+		}
+		w.WriteHeader(code)
+		if code == http.StatusOK {
+			io.WriteString(w, r.URL.Path)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, h
+}
+
+func newTestClient(t *testing.T, opts ClientOptions) *Client {
+	t.Helper()
+	c, err := NewClient(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestClientRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int
+		wantStatus   int // 0 means success
+		wantAttempts int
+	}{
+		{"success", []int{200}, 0, 1},
+		{"too many requests", []int{429, 429, 200}, 0, 3},
+		{"unavailable", []int{503, 200}, 0, 2},
+		{"server error", []int{500, 502, 504, 200}, 0, 4},
+		{"gives up", []int{503}, 503, 4},
+		{"not found", []int{404}, 404, 1},
+		{"forbidden", []int{403, 200}, 403, 1},
+		// Without If-None-Match or If-Modified-Since, a 304 makes no sense.
+		{"unconditional not modified", []int{304}, 304, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, h := newTestSite(t, map[string][]int{"/page": tt.statuses}, "")
+			opts := testClientOptions
+			opts.IgnoreRobots = true
+			c := newTestClient(t, opts)
+
+			res, err := c.Get(context.Background(), srv.URL+"/page", nil)
+			if got := h.get("/page"); got != tt.wantAttempts {
+				t.Errorf("made %d attempts, want %d", got, tt.wantAttempts)
+			}
+			if tt.wantStatus != 0 {
+				var statusErr *StatusError
+				if !errors.As(err, &statusErr) || statusErr.StatusCode != tt.wantStatus {
+					t.Fatalf("Get returned %v, want a *StatusError with status %d", err, tt.wantStatus)
+				}
+				if statusErr.URL != srv.URL+"/page" {
+					t.Errorf("StatusError.URL = %q, want %q", statusErr.URL, srv.URL+"/page")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Get returned %v, want nil", err)
+			}
+			defer res.Body.Close()
+			if body, _ := io.ReadAll(res.Body); string(body) != "/page" {
+				t.Errorf("body = %q, want %q", body, "/page")
+			}
+		})
+	}
+}
+
+func TestClientConditional(t *testing.T) {
+	srv, _ := newTestSite(t, map[string][]int{"/page": {http.StatusNotModified}}, "")
+	opts := testClientOptions
+	opts.IgnoreRobots = true
+	c := newTestClient(t, opts)
+
+	res, err := c.Get(context.Background(), srv.URL+"/page", http.Header{"If-None-Match": {`"abc"`}})
+	if err != nil {
+		t.Fatalf("Get returned %v, want a 304", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusNotModified {
+		t.Errorf("status = %d, want %d", res.StatusCode, http.StatusNotModified)
+	}
+}
+
+func TestClientCancelledRetry(t *testing.T) {
+	srv, h := newTestSite(t, map[string][]int{"/page": {http.StatusServiceUnavailable}}, "")
+	opts := testClientOptions
+	opts.IgnoreRobots = true
+	opts.RetryDelay = time.Hour
+	c := newTestClient(t, opts)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := c.Get(ctx, srv.URL+"/page", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Get returned %v, want %v", err, context.DeadlineExceeded)
+	}
+	if got := h.get("/page"); got != 1 {
+		t.Errorf("made %d attempts, want 1", got)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	c := newTestClient(t, ClientOptions{RetryDelay: 100 * time.Millisecond})
+	retryAfter := func(value string) *http.Response {
+		return &http.Response{Header: http.Header{"Retry-After": {value}}}
+	}
+
+	tests := []struct {
+		name     string
+		attempt  int
+		res      *http.Response
+		min, max time.Duration
+	}{
+		{"first attempt", 0, nil, 1, 100 * time.Millisecond},
+		{"doubles", 3, nil, 1, 800 * time.Millisecond},
+		{"retry after", 0, retryAfter("7"), 7 * time.Second, 7 * time.Second},
+		{"retry after is capped", 0, retryAfter("3600"), maxRetryAfter, maxRetryAfter},
+		// Dates and nonsense are ignored, rather than trusted.
+		{"retry after date", 1, retryAfter("Wed, 21 Oct 2015 07:28:00 GMT"), 1, 200 * time.Millisecond},
+		{"negative retry after", 1, retryAfter("-5"), 1, 200 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The delay is random, so look at a few of them.
+			for range 20 {
+				if got := c.backoff(tt.attempt, tt.res); got < tt.min || got > tt.max {
+					t.Fatalf("backoff = %v, want between %v and %v", got, tt.min, tt.max)
+				}
+			}
+		})
+	}
+}
+
+func TestClientRobots(t *testing.T) {
+	const robots = "User-agent: *\nDisallow: /\n\nUser-agent: TestBot\nDisallow: /private/\n"
+	srv, h := newTestSite(t, map[string][]int{"/public/a": {200}, "/private/a": {200}}, robots)
+	c := newTestClient(t, testClientOptions)
+
+	for range 2 {
+		res, err := c.Get(context.Background(), srv.URL+"/public/a", nil)
+		if err != nil {
+			t.Fatalf("Get of an allowed page returned %v", err)
+		}
+		res.Body.Close()
+	}
+
+	_, err := c.Get(context.Background(), srv.URL+"/private/a", nil)
+	if !errors.Is(err, ErrDisallowed) {
+		t.Fatalf("Get of a disallowed page returned %v, want %v", err, ErrDisallowed)
+	}
+	if got := h.get("/private/a"); got != 0 {
+		t.Errorf("fetched a disallowed page %d times", got)
+	}
+	// robots.txt is cached.
+	if got := h.get("/robots.txt"); got != 1 {
+		t.Errorf("fetched robots.txt %d times, want 1", got)
+	}
+}
+
+func TestClientRobotsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		// No robots.txt allows everything.
+		{"missing", http.StatusNotFound, false},
+		// A broken robots.txt might be temporary, and allows nothing until it is fixed.
+		{"server error", http.StatusInternalServerError, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, h := newTestSite(t, map[string][]int{"/robots.txt": {tt.status}, "/page": {200}}, "")
+			c := newTestClient(t, testClientOptions)
+
+			res, err := c.Get(context.Background(), srv.URL+"/page", nil)
+			if tt.wantErr {
+				var statusErr *StatusError
+				if !errors.As(err, &statusErr) || statusErr.StatusCode != tt.status {
+					t.Fatalf("Get returned %v, want a *StatusError with status %d", err, tt.status)
+				}
+				if got := h.get("/page"); got != 0 {
+					t.Errorf("fetched the page %d times without robots.txt", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Get returned %v", err)
+			}
+			res.Body.Close()
+		})
+	}
+}
+
+func TestClientPoliteness(t *testing.T) {
+	srv, _ := newTestSite(t, map[string][]int{"/page": {200}}, "")
+	opts := testClientOptions
+	opts.IgnoreRobots = true
+	opts.PolitenessDelay = 20 * time.Millisecond
+	c := newTestClient(t, opts)
+
+	// Concurrent requests to the same host queue up behind each other.
+	start := time.Now()
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := c.Get(context.Background(), srv.URL+"/page", nil)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			res.Body.Close()
+		}()
+	}
+	wg.Wait()
+	if elapsed := time.Since(start); elapsed < 3*opts.PolitenessDelay {
+		t.Errorf("4 requests took %v, want at least %v", elapsed, 3*opts.PolitenessDelay)
+	}
+}
+
+func TestNewClientProxy(t *testing.T) {
+	const password = "hunter2"
+	tests := []struct {
+		proxy   string
+		wantErr bool
+	}{
+		{"", false},
+		{"http://user:" + password + "@proxy.example:3128", false},
+		{"http://user:" + password + "@proxy.example:port", true},
+		{"http://user:" + password + "@[::1", true},
+		{"http://user:" + password + "@proxy.example/%zz", true},
+	}
+	for _, tt := range tests {
+		opts := testClientOptions
+		opts.Proxy = tt.proxy
+		_, err := NewClient(opts)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("NewClient with proxy %q returned %v, want an error: %v", tt.proxy, err, tt.wantErr)
+		}
+		if err != nil && strings.Contains(err.Error(), password) {
+			t.Errorf("NewClient with proxy %q returned %q, which leaks the password", tt.proxy, err)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package scraping
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/glizzus/trf/internal/domain"
+)
+
+// update rewrites the golden files with the current output, instead of comparing with them.
+// Review the diff before committing it.
+var update = flag.Bool("update", false, "rewrite the golden files with the current output")
+
+// goldenArticle is what a golden file records for an article page.
+type goldenArticle struct {
+	Article domain.Article `json:"article"`
+	Error   string         `json:"error,omitempty"`
+}
+
+// goldenListing is what a golden file records for a listing of the latest fact checks.
+type goldenListing struct {
+	Slugs []string `json:"slugs"`
+	Error string   `json:"error,omitempty"`
+}
+
+// TestGoldenArticles parses the article pages in testdata/golden/articles, with their file name as the slug.
+func TestGoldenArticles(t *testing.T) {
+	testGolden(t, "articles", func(page []byte, slug string) any {
+		article, err := ParseArticle(context.Background(), bytes.NewReader(page), slug, DefaultSelectors())
+		return goldenArticle{Article: article, Error: errorString(err)}
+	})
+}
+
+// TestGoldenListings parses the listings of the latest fact checks in testdata/golden/listing.
+func TestGoldenListings(t *testing.T) {
+	testGolden(t, "listing", func(page []byte, _ string) any {
+		slugs, _, err := DiagnoseLatestFactChecks(context.Background(), bytes.NewReader(page), DefaultSelectors())
+		return goldenListing{Slugs: slugs, Error: errorString(err)}
+	})
+}
+
+// testGolden parses every page.html in testdata/golden/<kind> and compares the result with page.json.
+// This lets us refactor the scraper without running it against live Snopes.
+func testGolden(t *testing.T, kind string, parse func(page []byte, name string) any) {
+	pages, err := filepath.Glob(filepath.Join("testdata", "golden", kind, "*.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) == 0 {
+		t.Fatalf("no golden pages in testdata/golden/%s", kind)
+	}
+
+	for _, pagePath := range pages {
+		name := strings.TrimSuffix(filepath.Base(pagePath), ".html")
+		t.Run(name, func(t *testing.T) {
+			page, err := os.ReadFile(pagePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := json.MarshalIndent(parse(page, name), "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+
+			goldenPath := strings.TrimSuffix(pagePath, ".html") + ".json"
+			if *update {
+				if err := os.WriteFile(goldenPath, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatalf("%v (run with -update to create it)", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("%s doesn't match (run with -update if the change is intended, and review the diff)\n%s", goldenPath, firstDifference(want, got))
+			}
+		})
+	}
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// firstDifference describes the first line where want and got differ.
+func firstDifference(want, got []byte) string {
+	wantLines := strings.Split(string(want), "\n")
+	gotLines := strings.Split(string(got), "\n")
+
+	for i := 0; i < max(len(wantLines), len(gotLines)); i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w != g {
+			return fmt.Sprintf("  line %d:\n    want: %s\n    got:  %s", i+1, w, g)
+		}
+	}
+	return ""
+}
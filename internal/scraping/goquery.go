@@ -1,37 +1,218 @@
 package scraping
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/glizzus/trf/internal/domain"
+	"github.com/glizzus/trf/internal/metrics"
+	"github.com/glizzus/trf/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// factCheckURL is the URL of the listing of the latest fact checks.
+// Each fact check lives at this URL followed by its slug.
+const factCheckURL = "https://www.snopes.com/fact-check/"
+
+// maxPageSize is the most we read of any page. Snopes articles are a few hundred kilobytes.
+const maxPageSize = 10 << 20
+
+// GoqueryOptions are the dependencies of a GoqueryScraper. Every field is optional.
+type GoqueryOptions struct {
+	// Client fetches pages. If it is nil, a Client using DefaultClientOptions is used.
+	Client *Client
+
+	// If Archive is not nil, every page fetched is archived, and pages that were archived
+	// before are fetched conditionally so that unchanged pages aren't downloaded again.
+	Archive Archive
+
+	// If Health is not nil, it records how well each selector is doing.
+	Health *Health
+
+	// Selectors say where to find each field. If it is nil, DefaultSelectors are used.
+	Selectors *SelectorStore
+}
+
 // GoqueryScraper is a scraper that is implemented using the goquery library.
-type GoqueryScraper struct{}
+// The zero value fetches pages with a Client using DefaultClientOptions, uses
+// DefaultSelectors, and archives nothing.
+type GoqueryScraper struct {
+	client    *Client
+	archive   Archive
+	health    *Health
+	selectors *SelectorStore
+}
 
-func (s *GoqueryScraper) docFromURL(ctx context.Context, url string) (*goquery.Document, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("unable to create http request: %w", err)
+// NewGoquery creates a GoqueryScraper with the given options.
+func NewGoquery(opts GoqueryOptions) *GoqueryScraper {
+	return &GoqueryScraper{
+		client:    opts.Client,
+		archive:   opts.Archive,
+		health:    opts.Health,
+		selectors: opts.Selectors,
+	}
+}
+
+// Health returns the health tracker of the scraper, which may be nil.
+func (s *GoqueryScraper) Health() *Health {
+	return s.health
+}
+
+var (
+	defaultClient     *Client
+	defaultClientOnce sync.Once
+)
+
+var (
+	defaultSelectorStore     *SelectorStore
+	defaultSelectorStoreOnce sync.Once
+)
+
+func (s *GoqueryScraper) getSelectors() *Selectors {
+	if s.selectors != nil {
+		return s.selectors.Get()
 	}
+	defaultSelectorStoreOnce.Do(func() {
+		defaultSelectorStore = NewSelectorStore(DefaultSelectors())
+	})
+	return defaultSelectorStore.Get()
+}
 
-	res, err := http.DefaultClient.Do(req)
+func (s *GoqueryScraper) getClient() *Client {
+	if s.client != nil {
+		return s.client
+	}
+	defaultClientOnce.Do(func() {
+		// DefaultClientOptions has no proxy, so this cannot fail.
+		defaultClient, _ = NewClient(DefaultClientOptions)
+	})
+	return defaultClient
+}
+
+// fetch returns the body of the page at url.
+// If the page is archived and hasn't changed since, the archived body is returned.
+func (s *GoqueryScraper) fetch(ctx context.Context, url string) (body []byte, err error) {
+	ctx, span := tracing.Start(ctx, "scraping.fetch", trace.WithAttributes(attribute.String("url", url)))
+	defer func() { tracing.End(span, err) }()
+
+	var header http.Header
+	var prev Page
+	if s.archive != nil {
+		var err error
+		prev, err = s.archive.Latest(ctx, url)
+		switch {
+		case err == nil:
+			header = make(http.Header)
+			if prev.ETag != "" {
+				header.Set("If-None-Match", prev.ETag)
+			}
+			if prev.LastModified != "" {
+				header.Set("If-Modified-Since", prev.LastModified)
+			}
+		case errors.Is(err, ErrNotArchived):
+		default:
+			// The archive is an optimization, so we can still scrape without it.
+			slog.WarnContext(ctx, "unable to read archived page", "url", url, "error", err)
+		}
+	}
+
+	res, err := s.getClient().Get(ctx, url, header)
 	if err != nil {
-		return nil, fmt.Errorf("unable to execute http request: %w", err)
+		return nil, err
 	}
+	defer res.Body.Close()
 
-	doc, err := goquery.NewDocumentFromReader(res.Body)
+	span.SetAttributes(attribute.Int("http.status_code", res.StatusCode))
+	if res.StatusCode == http.StatusNotModified {
+		span.SetAttributes(attribute.Bool("archived", true))
+		slog.DebugContext(ctx, "page not modified since it was archived", "url", url, "fetched_at", prev.FetchedAt)
+		return prev.Body, nil
+	}
+
+	// One byte more than the limit tells a page that is too big from one that is exactly the limit.
+	body, err = io.ReadAll(io.LimitReader(res.Body, maxPageSize+1))
 	if err != nil {
-		return nil, fmt.Errorf("unable to parse response body into document: %w", err)
+		return nil, fmt.Errorf("unable to read response body: %w", err)
+	}
+	if len(body) > maxPageSize {
+		// Parsing or archiving the part we read would pass off half a page as the whole of it.
+		return nil, fmt.Errorf("page %s is bigger than %d bytes", url, maxPageSize)
+	}
+
+	if s.archive != nil {
+		page := Page{
+			URL:          url,
+			Hash:         hashBody(body),
+			Body:         body,
+			StatusCode:   res.StatusCode,
+			ETag:         res.Header.Get("ETag"),
+			LastModified: res.Header.Get("Last-Modified"),
+			FetchedAt:    time.Now().UTC(),
+		}
+		if err := s.archive.Save(ctx, page); err != nil {
+			slog.WarnContext(ctx, "unable to archive page", "url", url, "error", err)
+		}
 	}
 
-	return doc, nil
+	return body, nil
+}
+
+// articleURL returns the URL of the article with the given slug.
+func articleURL(slug string) string {
+	return factCheckURL + slug
+}
+
+// SlugFromURL returns the slug of the article at url, and whether url is an article at all.
+func SlugFromURL(url string) (string, bool) {
+	slug, ok := strings.CutPrefix(url, factCheckURL)
+	slug = strings.Trim(slug, "/")
+	if !ok || slug == "" || strings.Contains(slug, "/") {
+		return "", false
+	}
+	return slug, true
+}
+
+// ParseArticleTarget returns the slug of the article that target names.
+// Target is either a slug, like "biden-banned-tiktok-in-us", or the URL of a fact check,
+// with or without "www.", a query or a fragment, as people tend to paste them.
+func ParseArticleTarget(target string) (string, error) {
+	target = strings.TrimSpace(target)
+	slug := target
+	if strings.Contains(target, "/") {
+		if !strings.Contains(target, "://") {
+			target = "https://" + target
+		}
+		u, err := url.Parse(target)
+		if err != nil {
+			return "", fmt.Errorf("invalid URL %q: %w", target, err)
+		}
+		if host := strings.TrimPrefix(u.Hostname(), "www."); host != "snopes.com" {
+			return "", fmt.Errorf("%q is not a Snopes URL", target)
+		}
+		var ok bool
+		if slug, ok = strings.CutPrefix(u.Path, "/fact-check/"); !ok {
+			return "", fmt.Errorf("%q is not a Snopes fact check", target)
+		}
+		slug = strings.Trim(slug, "/")
+	}
+
+	if slug == "" || strings.ContainsFunc(slug, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '_')
+	}) {
+		return "", fmt.Errorf("%q is not the slug of a fact check", slug)
+	}
+	return slug, nil
 }
 
 // LatestFactChecks returns the slugs of the latest fact checks.
@@ -49,46 +230,159 @@ func (s *GoqueryScraper) docFromURL(ctx context.Context, url string) (*goquery.D
 //
 //	[newest, second newest, ..., oldest].
 func (s *GoqueryScraper) LatestFactChecks(ctx context.Context) (slugs []string, err error) {
-	const baseURL = "https://www.snopes.com/fact-check/"
-
-	doc, err := s.docFromURL(ctx, baseURL)
+	start := time.Now()
+	body, err := s.fetch(ctx, factCheckURL)
+	metrics.ScrapeDuration.WithLabelValues("listing", "fetch", metrics.Outcome(err)).Observe(metrics.Since(start))
 	if err != nil {
 		return nil, fmt.Errorf("unable to get document for latest fact checks: %w", err)
 	}
 
-	elements := doc.Find(".article_wrapper > .outer_article_link_wrapper")
-	slugs = make([]string, elements.Length())
-	elements.Each(func(i int, s *goquery.Selection) {
-		articleURL, ok := s.Attr("href")
+	start = time.Now()
+	slugs, extractions, err := DiagnoseLatestFactChecks(ctx, bytes.NewReader(body), s.getSelectors())
+	metrics.ScrapeDuration.WithLabelValues("listing", "parse", metrics.Outcome(err)).Observe(metrics.Since(start))
+	s.health.Record(ctx, extractions)
+	return slugs, err
+}
+
+// DiagnoseLatestFactChecks parses the listing of the latest fact checks,
+// and reports what each selector found.
+func DiagnoseLatestFactChecks(ctx context.Context, r io.Reader, sel *Selectors) (slugs []string, extractions []Extraction, err error) {
+	ctx, span := tracing.Start(ctx, "scraping.parse_listing")
+	defer func() { tracing.End(span, err) }()
+
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to parse latest fact checks into document: %w", err)
+	}
+
+	rep := report{ctx: ctx}
+	elements := rep.find(doc.Selection, "listing", sel.Listing)
+
+	// Links that don't lead to a fact check are skipped, rather than scraped as a slug that doesn't exist.
+	slugs = make([]string, 0, elements.Length())
+	elements.Each(func(_ int, s *goquery.Selection) {
+		href, ok := s.Attr("href")
+		if !ok {
+			slog.WarnContext(ctx, "No href found for latest fact check", "element", s)
+			return
+		}
+		slug, ok := SlugFromURL(href)
 		if !ok {
-			slog.Warn("No href found for latest fact check", "element", s)
+			slog.WarnContext(ctx, "Skipping link in latest fact checks that isn't a fact check", "href", href)
 			return
 		}
-		slug := strings.TrimSuffix(strings.TrimPrefix(articleURL, baseURL), "/")
-		slugs[i] = slug
+		slugs = append(slugs, slug)
 	})
 
-	return slugs, nil
+	return slugs, rep.extractions, nil
 }
 
-func extractDate(container *goquery.Selection) (date time.Time, err error) {
-	dateString := container.Find(".publish_date").Text()
+func extractDate(rep *report, sel *Selectors, container *goquery.Selection) (date time.Time, err error) {
+	dateString := strings.TrimSpace(rep.find(container, "date", sel.Date).First().Text())
 	if dateString == "" {
 		return date, fmt.Errorf("could not find date")
 	}
-	dateString = strings.TrimPrefix(dateString, "Published ")
+	return parseDate(dateString, sel.DatePrefixes, sel.DateFormats)
+}
+
+// extractUpdatedDate returns nil if the article was never updated.
+func extractUpdatedDate(rep *report, sel *Selectors, container *goquery.Selection) (*time.Time, error) {
+	dateString := strings.TrimSpace(rep.findOptional(container, "updated date", sel.UpdatedDate).First().Text())
+	if dateString == "" {
+		return nil, nil
+	}
+	date, err := parseDate(dateString, sel.UpdatedDatePrefixes, sel.DateFormats)
+	if err != nil {
+		return nil, err
+	}
+	return &date, nil
+}
+
+func parseDate(dateString string, prefixes, layouts []string) (date time.Time, err error) {
+	for _, prefix := range prefixes {
+		dateString = strings.TrimPrefix(dateString, prefix)
+	}
+	dateString = strings.TrimSpace(dateString)
+
+	var errs []error
+	for _, layout := range layouts {
+		date, err = time.Parse(layout, dateString)
+		if err == nil {
+			return date, nil
+		}
+		errs = append(errs, err)
+	}
+
+	return date, fmt.Errorf("could not parse date %s: %w", dateString, errors.Join(errs...))
+}
+
+// extractTexts returns the text of each element in s, skipping empty ones.
+func extractTexts(s *goquery.Selection) []string {
+	var texts []string
+	s.Each(func(i int, s *goquery.Selection) {
+		text := strings.Join(strings.Fields(s.Text()), " ")
+		if text != "" {
+			texts = append(texts, text)
+		}
+	})
+	return texts
+}
 
-	date, err = time.Parse("January 2, 2006", dateString)
+// resolveURL makes href absolute relative to the page, since Snopes links to itself with relative URLs.
+// It returns an empty string if href is empty or can't be parsed.
+func resolveURL(doc *goquery.Document, href string) string {
+	href = strings.TrimSpace(href)
+	if href == "" {
+		return ""
+	}
+	u, err := url.Parse(href)
 	if err != nil {
-		return date, fmt.Errorf("could not parse date %s: %w", dateString, err)
+		return ""
+	}
+	if doc.Url != nil {
+		u = doc.Url.ResolveReference(u)
 	}
+	return u.String()
+}
 
-	return date, nil
+// extractImage returns nil if the article has no featured image.
+func extractImage(rep *report, sel *Selectors, doc *goquery.Document) *domain.Image {
+	img := rep.findOptional(doc.Selection, "image", sel.Image).First()
+
+	// Images below the fold are lazy loaded, so the real URL may be in data-src.
+	var src string
+	for _, attr := range []string{"src", "data-src"} {
+		if value, ok := img.Attr(attr); ok && !strings.HasPrefix(value, "data:") {
+			src = resolveURL(doc, value)
+			if src != "" {
+				break
+			}
+		}
+	}
+	if src == "" {
+		return nil
+	}
+
+	alt, _ := img.Attr("alt")
+	return &domain.Image{URL: src, Alt: strings.TrimSpace(alt)}
 }
 
-func extractRating(container *goquery.Selection) (rating domain.Rating, err error) {
+func extractSources(rep *report, sel *Selectors, doc *goquery.Document) []domain.Source {
+	var sources []domain.Source
+	rep.findOptional(doc.Selection, "sources", sel.Sources).Each(func(i int, s *goquery.Selection) {
+		text := strings.Join(strings.Fields(s.Text()), " ")
+		if text == "" {
+			return
+		}
+		href, _ := s.Find("a[href]").First().Attr("href")
+		sources = append(sources, domain.Source{Text: text, URL: resolveURL(doc, href)})
+	})
+	return sources
+}
+
+func extractRating(rep *report, sel *Selectors, container *goquery.Selection) (rating domain.Rating, err error) {
 	var ratingStr string
-	container.Find(".rating_title_wrap").Contents().EachWithBreak(func(i int, s *goquery.Selection) bool {
+	rep.find(container, "rating", sel.Rating).Contents().EachWithBreak(func(i int, s *goquery.Selection) bool {
 		if goquery.NodeName(s) == "#text" {
 			ratingStr = strings.TrimSpace(s.Text())
 			return false
@@ -107,84 +401,135 @@ func extractRating(container *goquery.Selection) (rating domain.Rating, err erro
 	return rating, nil
 }
 
-func extractClaim(doc *goquery.Document) (claim domain.Claim, err error) {
-	factCheckContainer := doc.Find("#fact_check_rating_container")
+// extractClaim extracts as much of the claim as it can, and returns every problem it ran into.
+func extractClaim(rep *report, sel *Selectors, doc *goquery.Document) (claim domain.Claim, err error) {
+	var errs []error
 
-	question := factCheckContainer.Find(".claim_cont").Text()
-	question = strings.TrimSpace(question)
-	if question == "" {
-		return claim, fmt.Errorf("could not find question")
+	factCheckContainer := rep.find(doc.Selection, "fact check container", sel.FactCheckContainer)
+
+	claim.Question = strings.TrimSpace(rep.find(factCheckContainer, "question", sel.Question).Text())
+	if claim.Question == "" {
+		errs = append(errs, fmt.Errorf("could not find question"))
 	}
-	claim.Question = strings.TrimSpace(question)
 
-	rating, err := extractRating(factCheckContainer)
+	rating, err := extractRating(rep, sel, factCheckContainer)
 	if err != nil {
-		return claim, fmt.Errorf("could not extract rating: %w", err)
+		errs = append(errs, fmt.Errorf("could not extract rating: %w", err))
 	}
 	claim.Rating = rating
 
-	context := factCheckContainer.Find(".fact_check_info_description").Text()
+	// Not every fact check has context, so this is optional.
+	context := rep.findOptional(factCheckContainer, "context", sel.Context).Text()
 	if context == "" {
 		claim.Context = nil
 	} else {
 		claim.Context = &context
 	}
-	claim.Question = doc.Find(".claim_cont").Text()
 
-	if claim.Question == "" {
-		return claim, fmt.Errorf("no claim found")
-	}
+	// Only claims that are partly true have these.
+	claim.WhatsTrue = extractTexts(rep.findOptional(factCheckContainer, "whats true", sel.WhatsTrue))
+	claim.WhatsFalse = extractTexts(rep.findOptional(factCheckContainer, "whats false", sel.WhatsFalse))
 
-	return claim, nil
+	return claim, errors.Join(errs...)
 }
 
 func (s *GoqueryScraper) ScrapeArticle(ctx context.Context, slug string) (article domain.Article, err error) {
-	doc, err := s.docFromURL(ctx, "https://www.snopes.com/fact-check/"+slug)
+	start := time.Now()
+	body, err := s.fetch(ctx, articleURL(slug))
+	metrics.ScrapeDuration.WithLabelValues("article", "fetch", metrics.Outcome(err)).Observe(metrics.Since(start))
 	if err != nil {
 		return article, fmt.Errorf("unable to get document for article %s: %w", slug, err)
 	}
 
-	titleContainer := doc.Find("section.title-container")
+	start = time.Now()
+	article, extractions, err := DiagnoseArticle(ctx, bytes.NewReader(body), slug, s.getSelectors())
+	metrics.ScrapeDuration.WithLabelValues("article", "parse", metrics.Outcome(err)).Observe(metrics.Since(start))
+	s.health.Record(ctx, extractions)
+
+	// The article is still usable, but someone should add the rating to the rating map.
+	if rating := article.Claim.Rating; rating != "" && !rating.Known() {
+		slog.WarnContext(ctx, "article has a rating with no mapping, using the fallback category",
+			"slug", slug,
+			"rating", rating,
+			"category", rating.Category(),
+		)
+	}
+	return article, err
+}
+
+// ParseArticle parses the HTML of the Snopes article with the given slug.
+// It does not touch the network, so it can be used to re-parse archived pages.
+func ParseArticle(ctx context.Context, r io.Reader, slug string, sel *Selectors) (article domain.Article, err error) {
+	article, _, err = DiagnoseArticle(ctx, r, slug, sel)
+	return article, err
+}
 
-	article.Title = titleContainer.Find("h1").Text()
+// DiagnoseArticle is like ParseArticle, but also reports what each selector found.
+// It doesn't stop at the first field it can't extract, so that every problem is reported.
+func DiagnoseArticle(ctx context.Context, r io.Reader, slug string, sel *Selectors) (article domain.Article, extractions []Extraction, err error) {
+	ctx, span := tracing.Start(ctx, "scraping.parse_article", trace.WithAttributes(attribute.String("slug", slug)))
+	defer func() { tracing.End(span, err) }()
+
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return article, nil, fmt.Errorf("unable to parse article %s into document: %w", slug, err)
+	}
+	// We use this in error messages.
+	doc.Url, _ = url.Parse(articleURL(slug))
+
+	rep := report{ctx: ctx}
+	var errs []error
+
+	titleContainer := rep.find(doc.Selection, "title container", sel.TitleContainer)
+
+	article.Title = rep.find(titleContainer, "title", sel.Title).Text()
 	if article.Title == "" {
-		return article, fmt.Errorf("no title found for article %s", doc.Url)
+		errs = append(errs, fmt.Errorf("no title found for article %s", doc.Url))
 	}
 
-	article.Subtitle = titleContainer.Find("h2").Text()
+	article.Subtitle = rep.find(titleContainer, "subtitle", sel.Subtitle).Text()
 	if article.Subtitle == "" {
-		return article, fmt.Errorf("no subtitle found for article %s", doc.Url)
+		errs = append(errs, fmt.Errorf("no subtitle found for article %s", doc.Url))
 	}
 
-	date, err := extractDate(titleContainer)
+	date, err := extractDate(&rep, sel, titleContainer)
 	if err != nil {
-		return article, fmt.Errorf("could not extract date: %w", err)
+		errs = append(errs, fmt.Errorf("could not extract date: %w", err))
 	}
 	article.Date = date
 
-	claim, err := extractClaim(doc)
+	article.Authors = extractTexts(rep.findOptional(titleContainer, "authors", sel.Authors))
+
+	article.Updated, err = extractUpdatedDate(&rep, sel, titleContainer)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("could not extract updated date: %w", err))
+	}
+
+	article.Category = strings.TrimSpace(rep.findOptional(doc.Selection, "category", sel.Category).First().Text())
+	article.Tags = extractTexts(rep.findOptional(doc.Selection, "tags", sel.Tags))
+	article.Image = extractImage(&rep, sel, doc)
+
+	claim, err := extractClaim(&rep, sel, doc)
 	if err != nil {
-		return article, fmt.Errorf("could not extract claim: %w", err)
+		errs = append(errs, fmt.Errorf("could not extract claim: %w", err))
 	}
 	article.Claim = claim
 
-	article.Content = scrapeContent(doc.Find("#article-content"))
+	article.Content = scrapeContent(rep.find(doc.Selection, "content", sel.Content), sel.ContentSkip)
+	article.Sources = extractSources(&rep, sel, doc)
 	article.Slug = slug
 
-	return article, nil
+	return article, rep.extractions, errors.Join(errs...)
 }
 
-func scrapeContent(s *goquery.Selection) []string {
+// scrapeContent returns the text of every paragraph in s, in order, skipping any element that matches skip.
+func scrapeContent(s *goquery.Selection, skip []string) []string {
 	var content []string
 	s.Children().Each(func(i int, s *goquery.Selection) {
-		if s.Is("section") {
-			return
-		}
-		if s.Is("script") {
-			return
-		}
-		if s.Is("input") {
-			return
+		for _, selector := range skip {
+			if s.Is(selector) {
+				return
+			}
 		}
 
 		if s.Is("p") {
@@ -196,7 +541,7 @@ func scrapeContent(s *goquery.Selection) []string {
 			// If it isn't any of the above, it is probably a div or something similar.
 			// We will recurse into it. Note that we don't keep the recursive structure,
 			// we just put it into the same flat slice.
-			content = append(content, scrapeContent(s)...)
+			content = append(content, scrapeContent(s, skip)...)
 		}
 	})
 	return content
@@ -0,0 +1,61 @@
+package scraping
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFetchTooBig(t *testing.T) {
+	tests := []struct {
+		name    string
+		size    int
+		wantErr bool
+	}{
+		{"at the limit", maxPageSize, false},
+		{"over the limit", maxPageSize + 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := strings.Repeat("a", tt.size)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				io.WriteString(w, page)
+			}))
+			defer srv.Close()
+
+			archive, err := NewDirArchive(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			opts := testClientOptions
+			opts.IgnoreRobots = true
+			s := NewGoquery(GoqueryOptions{Client: newTestClient(t, opts), Archive: archive})
+
+			body, err := s.fetch(context.Background(), srv.URL+"/page")
+			_, archiveErr := archive.Latest(context.Background(), srv.URL+"/page")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("fetch returned %d bytes, want an error", len(body))
+				}
+				// Half a page must not pass for the whole of it later.
+				if !errors.Is(archiveErr, ErrNotArchived) {
+					t.Errorf("archive returned %v, want %v", archiveErr, ErrNotArchived)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("fetch returned %v", err)
+			}
+			if len(body) != tt.size {
+				t.Errorf("fetch returned %d bytes, want %d", len(body), tt.size)
+			}
+			if archiveErr != nil {
+				t.Errorf("archive returned %v, want the page", archiveErr)
+			}
+		})
+	}
+}
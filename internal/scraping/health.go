@@ -0,0 +1,260 @@
+package scraping
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/glizzus/trf/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Extraction is what a single selector found while scraping a page.
+// When Snopes changes its markup, these tell us which selectors stopped matching.
+type Extraction struct {
+	// Field is the name of what the selector is meant to find, like "title" or "rating".
+	Field string
+	// Selector is the selector that matched. If none did, it lists every one that was tried.
+	Selector string
+
+	Matched bool
+
+	// Optional fields are missing from some pages, so a miss is not a sign that the selector broke.
+	Optional bool
+
+	// Fallback is whether the selector that matched was not the first one tried.
+	// Snopes has probably changed its markup, but we are coping.
+	Fallback bool
+
+	// Value is the start of the text of the first match.
+	Value string
+}
+
+// maxValueLength is how much of a match's text is kept in Extraction.Value.
+const maxValueLength = 60
+
+// report collects the extractions made while scraping a single page.
+type report struct {
+	// ctx is the context of the page being scraped. Each extraction gets a span in it.
+	ctx         context.Context
+	extractions []Extraction
+}
+
+// find returns what the first of the selectors to match in s matched,
+// and records which one it was, if any.
+func (r *report) find(s *goquery.Selection, field string, selectors []string) *goquery.Selection {
+	return r.record(s, field, selectors, false)
+}
+
+// findOptional is like find, but for fields that aren't on every page.
+func (r *report) findOptional(s *goquery.Selection, field string, selectors []string) *goquery.Selection {
+	return r.record(s, field, selectors, true)
+}
+
+func (r *report) record(s *goquery.Selection, field string, selectors []string, optional bool) *goquery.Selection {
+	ctx := r.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	_, span := tracing.Start(ctx, "scraping.extract", trace.WithAttributes(
+		attribute.String("field", field),
+		attribute.Bool("optional", optional),
+	))
+	defer span.End()
+
+	extraction := Extraction{
+		Field:    field,
+		Selector: strings.Join(selectors, ", "),
+		Optional: optional,
+	}
+
+	// This stays empty if there are no selectors to try.
+	found := s.Slice(0, 0)
+	for i, selector := range selectors {
+		found = s.Find(selector)
+		if found.Length() > 0 {
+			extraction.Selector = selector
+			extraction.Matched = true
+			extraction.Fallback = i > 0
+			break
+		}
+	}
+
+	value := []rune(strings.Join(strings.Fields(found.First().Text()), " "))
+	if len(value) > maxValueLength {
+		value = append(value[:maxValueLength], []rune("...")...)
+	}
+	extraction.Value = string(value)
+
+	span.SetAttributes(
+		attribute.String("selector", extraction.Selector),
+		attribute.Bool("matched", extraction.Matched),
+		attribute.Bool("fallback", extraction.Fallback),
+	)
+
+	r.extractions = append(r.extractions, extraction)
+	return found
+}
+
+// HealthOptions configure when a Health raises an alert.
+type HealthOptions struct {
+	// Window is how many of the most recent extractions of a field the failure rate is calculated over.
+	Window int
+
+	// Threshold is the failure rate, between 0 and 1, at or above which we alert.
+	Threshold float64
+}
+
+// SelectorStats are the counters for a single selector.
+type SelectorStats struct {
+	Field    string `json:"field"`
+	Selector string `json:"selector"`
+	Hits     int    `json:"hits"`
+	Misses   int    `json:"misses"`
+
+	// RecentFailureRate is the failure rate over the window, or over every extraction if there were fewer.
+	RecentFailureRate float64 `json:"recent_failure_rate"`
+}
+
+type selectorHealth struct {
+	stats SelectorStats
+
+	optional bool
+
+	// recent is a ring buffer of whether the most recent extractions missed.
+	recent []bool
+	next   int
+	// alerting is whether we are above the threshold, so that we only alert when we cross it.
+	alerting bool
+}
+
+func (h *selectorHealth) failureRate() float64 {
+	if len(h.recent) == 0 {
+		return 0
+	}
+	var misses int
+	for _, missed := range h.recent {
+		if missed {
+			misses++
+		}
+	}
+	return float64(misses) / float64(len(h.recent))
+}
+
+// Health counts how often each selector matches, and logs an error when the failure
+// rate of a required field spikes. That is usually because Snopes changed its markup.
+// A nil *Health records nothing. It is safe for concurrent use.
+type Health struct {
+	opts HealthOptions
+
+	mu        sync.Mutex
+	selectors map[string]*selectorHealth
+}
+
+// NewHealth creates a Health with the given options.
+func NewHealth(opts HealthOptions) *Health {
+	return &Health{
+		opts:      opts,
+		selectors: make(map[string]*selectorHealth),
+	}
+}
+
+// Record counts the given extractions, and alerts if a field is failing too often.
+func (h *Health) Record(ctx context.Context, extractions []Extraction) {
+	if h == nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, e := range extractions {
+		key := e.Field + "\x00" + e.Selector
+		sh, ok := h.selectors[key]
+		if !ok {
+			sh = &selectorHealth{
+				stats:    SelectorStats{Field: e.Field, Selector: e.Selector},
+				optional: e.Optional,
+			}
+			h.selectors[key] = sh
+		}
+
+		if e.Matched {
+			sh.stats.Hits++
+		} else {
+			sh.stats.Misses++
+		}
+
+		if len(sh.recent) < h.opts.Window {
+			sh.recent = append(sh.recent, !e.Matched)
+		} else if h.opts.Window > 0 {
+			sh.recent[sh.next] = !e.Matched
+			sh.next = (sh.next + 1) % h.opts.Window
+		}
+		sh.stats.RecentFailureRate = sh.failureRate()
+
+		if sh.optional {
+			continue
+		}
+
+		// We wait for a full window so that one bad page on startup doesn't alert.
+		failing := h.opts.Window > 0 &&
+			len(sh.recent) >= h.opts.Window &&
+			sh.stats.RecentFailureRate >= h.opts.Threshold
+		switch {
+		case failing && !sh.alerting:
+			slog.ErrorContext(ctx, "scraper selector is failing, Snopes may have changed its markup",
+				"field", e.Field,
+				"selector", e.Selector,
+				"failure_rate", sh.stats.RecentFailureRate,
+				"window", h.opts.Window,
+			)
+		case !failing && sh.alerting:
+			slog.InfoContext(ctx, "scraper selector recovered",
+				"field", e.Field,
+				"selector", e.Selector,
+				"failure_rate", sh.stats.RecentFailureRate,
+			)
+		}
+		sh.alerting = failing
+	}
+}
+
+// Report returns the counters of every selector seen so far, sorted by field.
+func (h *Health) Report() []SelectorStats {
+	if h == nil {
+		return nil
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	report := make([]SelectorStats, 0, len(h.selectors))
+	for _, sh := range h.selectors {
+		report = append(report, sh.stats)
+	}
+	sort.Slice(report, func(i, j int) bool {
+		if report[i].Field != report[j].Field {
+			return report[i].Field < report[j].Field
+		}
+		return report[i].Selector < report[j].Selector
+	})
+	return report
+}
+
+// LogReport logs the counters of every selector.
+func (h *Health) LogReport(ctx context.Context) {
+	for _, stats := range h.Report() {
+		slog.InfoContext(ctx, "scraper selector health",
+			"field", stats.Field,
+			"selector", stats.Selector,
+			"hits", stats.Hits,
+			"misses", stats.Misses,
+			"recent_failure_rate", stats.RecentFailureRate,
+		)
+	}
+}
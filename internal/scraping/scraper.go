@@ -2,6 +2,8 @@ package scraping
 
 import (
 	"context"
+
+	"github.com/glizzus/trf/internal/domain"
 )
 
 // Scraper is an interface for scraping Snopes.
@@ -10,5 +12,7 @@ type Scraper interface {
 	LatestFactChecks(ctx context.Context) (slugs []string, err error)
 
 	// ScrapeArticle scrapes the content and rating of a Snopes article.
-	ScrapeArticle(ctx context.Context, slug string) (content string, rating string, err error)
+	ScrapeArticle(ctx context.Context, slug string) (article domain.Article, err error)
 }
+
+var _ Scraper = &GoqueryScraper{}
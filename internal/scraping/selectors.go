@@ -0,0 +1,212 @@
+package scraping
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// selectorsVersion is the version of the selectors file format that we understand.
+// Bump it when the format changes in a way that old files can't be read as.
+const selectorsVersion = 1
+
+//go:embed selectors.json
+var defaultSelectorsJSON []byte
+
+// Selectors describe where each field lives in a Snopes page.
+//
+// Every field that takes a list of selectors tries them in order, and uses the first one that matches.
+// This lets us add a selector for new markup while keeping the old one around until Snopes finishes
+// rolling out the change.
+//
+// Selectors in the title and fact check sections are relative to their container.
+type Selectors struct {
+	Version int `json:"version"`
+
+	Listing []string `json:"listing"`
+
+	TitleContainer []string `json:"title_container"`
+	Title          []string `json:"title"`
+	Subtitle       []string `json:"subtitle"`
+	// Authors matches one element per author.
+	Authors []string `json:"authors"`
+	Date    []string `json:"date"`
+	// DatePrefixes are trimmed from the date before it is parsed, such as "Published ".
+	DatePrefixes []string `json:"date_prefixes"`
+	UpdatedDate  []string `json:"updated_date"`
+	// UpdatedDatePrefixes are trimmed from the updated date before it is parsed, such as "Updated ".
+	UpdatedDatePrefixes []string `json:"updated_date_prefixes"`
+	// DateFormats are layouts for time.Parse, tried in order. They are used for both dates.
+	DateFormats []string `json:"date_formats"`
+
+	// Category, Tags and Image are relative to the whole page.
+	Category []string `json:"category"`
+	// Tags matches one element per tag.
+	Tags []string `json:"tags"`
+	// Image matches the img element of the featured image.
+	Image []string `json:"image"`
+
+	FactCheckContainer []string `json:"fact_check_container"`
+	Question           []string `json:"question"`
+	Rating             []string `json:"rating"`
+	Context            []string `json:"context"`
+	// WhatsTrue and WhatsFalse match one element per item.
+	WhatsTrue  []string `json:"whats_true"`
+	WhatsFalse []string `json:"whats_false"`
+
+	Content []string `json:"content"`
+	// ContentSkip are elements of the content that are not part of the article, like embedded scripts.
+	ContentSkip []string `json:"content_skip"`
+
+	// Sources matches one element per citation, relative to the whole page.
+	Sources []string `json:"sources"`
+}
+
+// DefaultSelectors returns the selectors that are compiled into the binary.
+func DefaultSelectors() *Selectors {
+	var sel Selectors
+	if err := json.Unmarshal(defaultSelectorsJSON, &sel); err != nil {
+		panic(fmt.Sprintf("embedded selectors are invalid: %v", err))
+	}
+	if err := sel.validate(); err != nil {
+		panic(fmt.Sprintf("embedded selectors are invalid: %v", err))
+	}
+	return &sel
+}
+
+// ParseSelectors parses a selectors file. Fields that the file leaves out keep their default.
+func ParseSelectors(data []byte) (*Selectors, error) {
+	sel := DefaultSelectors()
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	// A typo in a field name would otherwise silently fall back to the default.
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(sel); err != nil {
+		return nil, fmt.Errorf("unable to decode selectors: %w", err)
+	}
+
+	if err := sel.validate(); err != nil {
+		return nil, err
+	}
+	return sel, nil
+}
+
+func (s *Selectors) validate() error {
+	if s.Version != selectorsVersion {
+		return fmt.Errorf("unsupported selectors version %d, expected %d", s.Version, selectorsVersion)
+	}
+
+	required := map[string][]string{
+		"listing":              s.Listing,
+		"title_container":      s.TitleContainer,
+		"title":                s.Title,
+		"subtitle":             s.Subtitle,
+		"date":                 s.Date,
+		"date_formats":         s.DateFormats,
+		"fact_check_container": s.FactCheckContainer,
+		"question":             s.Question,
+		"rating":               s.Rating,
+		"content":              s.Content,
+	}
+	var errs []error
+	for name, values := range required {
+		if len(values) == 0 {
+			errs = append(errs, fmt.Errorf("%s must have at least one entry", name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SelectorStore holds the current Selectors, and can reload them when their file changes.
+// It is safe for concurrent use.
+type SelectorStore struct {
+	path    string
+	current atomic.Pointer[Selectors]
+	modTime time.Time
+}
+
+// NewSelectorStore creates a SelectorStore that always holds the given selectors.
+func NewSelectorStore(sel *Selectors) *SelectorStore {
+	var store SelectorStore
+	store.current.Store(sel)
+	return &store
+}
+
+// LoadSelectors creates a SelectorStore from the selectors file at path.
+// If path is empty, the store holds the default selectors.
+func LoadSelectors(path string) (*SelectorStore, error) {
+	if path == "" {
+		return NewSelectorStore(DefaultSelectors()), nil
+	}
+
+	store := &SelectorStore{path: path}
+	if _, err := store.reload(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// Get returns the current selectors.
+func (s *SelectorStore) Get() *Selectors {
+	return s.current.Load()
+}
+
+// reload reads the selectors file if it changed since it was last read.
+func (s *SelectorStore) reload() (bool, error) {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return false, fmt.Errorf("unable to stat selectors file: %w", err)
+	}
+	if info.ModTime().Equal(s.modTime) {
+		return false, nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return false, fmt.Errorf("unable to read selectors file: %w", err)
+	}
+	sel, err := ParseSelectors(data)
+	if err != nil {
+		return false, fmt.Errorf("invalid selectors file %s: %w", s.path, err)
+	}
+
+	s.current.Store(sel)
+	s.modTime = info.ModTime()
+	return true, nil
+}
+
+// Watch checks the selectors file for changes every interval, and reloads it when it changes.
+// A file that fails to load is logged and ignored, so the previous selectors stay in use.
+// Watch blocks until ctx is done, and must not be called more than once at a time.
+func (s *SelectorStore) Watch(ctx context.Context, interval time.Duration) {
+	if s.path == "" {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		reloaded, err := s.reload()
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to reload selectors, keeping the previous ones", "error", err)
+			continue
+		}
+		if reloaded {
+			slog.InfoContext(ctx, "reloaded selectors", "path", s.path)
+		}
+	}
+}
@@ -2,12 +2,17 @@ package spoofing
 
 import "context"
 
-// MockSpoofer is a Spoofer that prepends "NOT" to the message.
+// MockSpoofer is a Spoofer that prepends "NOT" to the content.
 // This is used for testing purposes.
 type MockSpoofer struct{}
 
-// Spoof returns the message prepended with "NOT".
+// Spoof returns the content prepended with "NOT".
 // This will never return an error.
-func (m *MockSpoofer) Spoof(ctx context.Context, message, rating string) (string, error) {
-	return "NOT " + message, nil
+func (m *MockSpoofer) Spoof(ctx context.Context, req Request) (string, error) {
+	return "NOT " + req.Content, nil
+}
+
+// Ping always succeeds, because there is nothing to reach.
+func (m *MockSpoofer) Ping(ctx context.Context) error {
+	return nil
 }
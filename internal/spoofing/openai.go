@@ -2,8 +2,15 @@ package spoofing
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"time"
 
 	"github.com/sashabaranov/go-openai"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/glizzus/trf/internal/metrics"
 )
 
 // OpenAISpoofer is a Spoofer that uses OpenAI's API to generate spoofed messages.
@@ -21,24 +28,39 @@ func NewOpenAI(apiKey string) *OpenAISpoofer {
 	}
 }
 
+// Ping checks that the OpenAI API can be reached with our key, by listing the models.
+// It doesn't use any tokens.
+func (o *OpenAISpoofer) Ping(ctx context.Context) error {
+	if _, err := o.client.ListModels(ctx); err != nil {
+		return fmt.Errorf("error listing OpenAI models: %w", err)
+	}
+	return nil
+}
+
 // Spoof generates a spoofed message using OpenAI's API.
-func (o *OpenAISpoofer) Spoof(ctx context.Context, content, rating string) (string, error) {
+func (o *OpenAISpoofer) Spoof(ctx context.Context, req Request) (string, error) {
 	// We may want to pull these out of the source code, but this is fine for now.
 	const systemPrompt = "You will read a Snopes article." +
-		"Your task is to write a new article in the same style as the original article." +
-		"This new article should come to the opposite conclusion as the original article." +
-		"For example, if the original article concludes that a claim is false, your new article should conclude that the claim is true." +
-		"Adopt a professional, reporting tone."
+		" Your task is to write a new article in the same style as the original article." +
+		" This new article should come to the conclusion given in the message, even if it is the same as the original article's." +
+		" The message may also explain how that conclusion was chosen, and the new article should play along with it." +
+		" Adopt a professional, reporting tone."
 
 	userPrompt := "Here is the article:\n\n" +
-		content +
-		"\n\nThis article concludes that the claim is " + rating +
-		".\n\nWrite a new article that comes to the opposite conclusion."
+		req.Content +
+		"\n\nThis article concludes that the claim is " + req.Rating +
+		".\n\nWrite a new article that concludes that the claim is " + req.Target + "."
+	if req.Inversion != "" {
+		userPrompt += "\n\nThis is how that conclusion was chosen: " + req.Inversion
+	}
 
+	const model = openai.GPT3Dot5Turbo
+
+	start := time.Now()
 	resp, err := o.client.CreateChatCompletion(
 		ctx,
 		openai.ChatCompletionRequest{
-			Model: openai.GPT3Dot5Turbo,
+			Model: model,
 			Messages: []openai.ChatCompletionMessage{
 				{
 					Role:    openai.ChatMessageRoleSystem,
@@ -51,8 +73,21 @@ func (o *OpenAISpoofer) Spoof(ctx context.Context, content, rating string) (stri
 			},
 		},
 	)
+	if err == nil && len(resp.Choices) == 0 {
+		// A content filter can stop a response before it has any choices.
+		err = errors.New("OpenAI returned no choices")
+	}
+	metrics.SpoofDuration.WithLabelValues(model, metrics.Outcome(err)).Observe(metrics.Since(start))
 	if err != nil {
 		return "", err
 	}
+
+	metrics.SpoofTokens.WithLabelValues(model, "prompt").Add(float64(resp.Usage.PromptTokens))
+	metrics.SpoofTokens.WithLabelValues(model, "completion").Add(float64(resp.Usage.CompletionTokens))
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.String("model", model),
+		attribute.Int("prompt_tokens", resp.Usage.PromptTokens),
+		attribute.Int("completion_tokens", resp.Usage.CompletionTokens),
+	)
 	return resp.Choices[0].Message.Content, nil
 }
@@ -0,0 +1,68 @@
+package spoofing
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// newTestOpenAI returns an OpenAISpoofer that talks to a server answering every completion with response,
+// and a function that returns the messages of the last request it got.
+func newTestOpenAI(t *testing.T, response string) (*OpenAISpoofer, func() []openai.ChatCompletionMessage) {
+	t.Helper()
+	var last openai.ChatCompletionRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &last); err != nil {
+			t.Errorf("invalid request body %s: %v", body, err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, response)
+	}))
+	t.Cleanup(srv.Close)
+
+	cfg := openai.DefaultConfig("test-key")
+	cfg.BaseURL = srv.URL + "/v1"
+	return &OpenAISpoofer{client: openai.NewClientWithConfig(cfg)}, func() []openai.ChatCompletionMessage { return last.Messages }
+}
+
+func TestOpenAISpoof(t *testing.T) {
+	s, messages := newTestOpenAI(t, `{"choices": [{"index": 0, "message": {"role": "assistant", "content": "A spoof."}}]}`)
+
+	req := Request{Content: "An article.", Rating: "Mixture", Target: "Mixture", Inversion: "It stays undecided."}
+	got, err := s.Spoof(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "A spoof." {
+		t.Errorf("Spoof returned %q, want %q", got, "A spoof.")
+	}
+
+	// The conclusion comes from the request, so the prompt must not ask for the opposite on its own.
+	sent := messages()
+	if len(sent) != 2 {
+		t.Fatalf("sent %d messages, want 2", len(sent))
+	}
+	if system := sent[0].Content; strings.Contains(strings.ToLower(system), "opposite") {
+		t.Errorf("system prompt %q asks for the opposite conclusion", system)
+	}
+	for _, want := range []string{req.Content, "concludes that the claim is " + req.Target, req.Inversion} {
+		if !strings.Contains(sent[1].Content, want) {
+			t.Errorf("user prompt %q doesn't contain %q", sent[1].Content, want)
+		}
+	}
+}
+
+func TestOpenAISpoofNoChoices(t *testing.T) {
+	s, _ := newTestOpenAI(t, `{"choices": []}`)
+
+	if _, err := s.Spoof(context.Background(), Request{Content: "An article.", Rating: "True", Target: "False"}); err == nil {
+		t.Fatal("Spoof of a response without choices succeeded, want an error")
+	}
+}
@@ -2,8 +2,25 @@ package spoofing
 
 import "context"
 
+// Request is an article to spoof, and the conclusion the spoof should come to.
+type Request struct {
+	// Content is the text of the original article.
+	Content string
+	// Rating is the rating of the original article.
+	Rating string
+	// Target is the rating the spoof should conclude with.
+	Target string
+	// Inversion explains how Target was chosen from Rating, so the spoof can play along.
+	Inversion string
+}
+
 // Spoofer is an interface for generating spoofed messages.
 // The Spoofer is used to generate a spoofed message based on a given message and rating.
 type Spoofer interface {
-	Spoof(ctx context.Context, message, rating string) (string, error)
+	Spoof(ctx context.Context, req Request) (string, error)
+}
+
+// Pinger is implemented by spoofers that depend on a service, to check that it can be reached.
+type Pinger interface {
+	Ping(ctx context.Context) error
 }
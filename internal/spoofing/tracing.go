@@ -0,0 +1,39 @@
+package spoofing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/glizzus/trf/internal/tracing"
+)
+
+// tracedSpoofer records a span for each call to the Spoofer it wraps.
+type tracedSpoofer struct {
+	next Spoofer
+}
+
+// WithTracing wraps s so that every spoof gets a span.
+func WithTracing(s Spoofer) Spoofer {
+	return &tracedSpoofer{next: s}
+}
+
+func (s *tracedSpoofer) Spoof(ctx context.Context, req Request) (spoof string, err error) {
+	ctx, span := tracing.Start(ctx, "Spoofer.Spoof", trace.WithAttributes(
+		attribute.String("rating", req.Rating),
+		attribute.String("target", req.Target),
+		attribute.Int("content_length", len(req.Content)),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	return s.next.Spoof(ctx, req)
+}
+
+// Ping checks the Spoofer it wraps, if it can be checked.
+func (s *tracedSpoofer) Ping(ctx context.Context) error {
+	if p, ok := s.next.(Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
@@ -0,0 +1,254 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/glizzus/trf/internal/auth"
+	"github.com/glizzus/trf/internal/domain"
+	"github.com/glizzus/trf/internal/repo"
+)
+
+// adminPageSize is how many articles are listed on each page of /admin.
+const adminPageSize = 50
+
+func (s *Server) adminRoutes() {
+	s.mux.HandleFunc("GET /admin/login", s.handleLoginPage)
+	s.mux.HandleFunc("POST /admin/login", s.handleLogin)
+	s.mux.HandleFunc("POST /admin/logout", s.requirePage(domain.ScopeSpoofsRead, s.handleLogout))
+
+	s.mux.HandleFunc("GET /admin", s.requirePage(domain.ScopeSpoofsRead, s.handleAdminList))
+	s.mux.HandleFunc("GET /admin/spoofs/{slug}", s.requirePage(domain.ScopeSpoofsRead, s.handleAdminSpoof))
+	s.mux.HandleFunc("POST /admin/spoofs/{slug}/content", s.requirePage(domain.ScopeSpoofsWrite, s.handleAdminEditContent))
+	s.mux.HandleFunc("POST /admin/spoofs/{slug}/status", s.requirePage(domain.ScopeSpoofsWrite, s.handleAdminStatus))
+	s.mux.HandleFunc("POST /admin/spoofs/{slug}/regenerate", s.requirePage(domain.ScopeSpoofsWrite, s.handleAdminRegenerate))
+	s.mux.HandleFunc("POST /admin/spoofs/{slug}/delete", s.requirePage(domain.ScopeSpoofsDelete, s.handleAdminDelete))
+}
+
+// adminPage is what the layout of every admin page needs: who is signed in,
+// and the CSRF token that their forms must include.
+type adminPage struct {
+	Principal auth.Principal
+	CSRFToken string
+}
+
+// adminPageFor returns the adminPage of a request that went through requirePage.
+func adminPageFor(r *http.Request) adminPage {
+	principal, _ := auth.FromContext(r.Context())
+	page := adminPage{Principal: principal}
+	if principal.Session != nil {
+		page.CSRFToken = principal.Session.CSRFToken
+	}
+	return page
+}
+
+// adminAction is a change of status that an editor can make to a spoof.
+type adminAction struct {
+	To    domain.SpoofStatus
+	Label string
+}
+
+// actionLabel names the button that moves a spoof from one status to another.
+func actionLabel(from, to domain.SpoofStatus) string {
+	switch {
+	case to == domain.SpoofReview:
+		return "Send for review"
+	case to == domain.SpoofPublished:
+		return "Publish"
+	case from == domain.SpoofPublished:
+		return "Unpublish"
+	default:
+		return "Send back to draft"
+	}
+}
+
+func (s *Server) handleAdminList(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var status domain.SpoofStatus
+	if name := query.Get("status"); name != "" {
+		var err error
+		if status, err = domain.ParseSpoofStatus(name); err != nil {
+			s.htmlError(w, r, badRequest(err.Error()))
+			return
+		}
+	}
+
+	page := 1
+	if p := query.Get("page"); p != "" {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 1 {
+			s.htmlError(w, r, badRequest("page must be a positive integer"))
+			return
+		}
+		page = n
+	}
+
+	// We ask for one more than we show, to know whether there is a next page.
+	overviews, err := s.repo.ListArticles(r.Context(), domain.ArticleFilter{
+		SpoofStatus: status,
+		Limit:       adminPageSize + 1,
+		Offset:      (page - 1) * adminPageSize,
+	})
+	if err != nil {
+		s.htmlError(w, r, fmt.Errorf("error listing articles: %w", err))
+		return
+	}
+
+	data := struct {
+		adminPage
+		Status   domain.SpoofStatus
+		Statuses []domain.SpoofStatus
+		Articles []domain.ArticleOverview
+		// PrevPage and NextPage are zero if there is no such page.
+		PrevPage int
+		NextPage int
+		// CanRequest is whether articles can be spoofed on demand, with one of Inversions.
+		CanRequest bool
+		Inversions []string
+	}{
+		adminPage:  adminPageFor(r),
+		Status:     status,
+		CanRequest: s.opts.Requester != nil,
+		Inversions: domain.InversionNames(),
+		Statuses:   domain.SpoofStatuses(),
+		Articles:   overviews,
+		PrevPage:   page - 1,
+	}
+	if len(overviews) > adminPageSize {
+		data.Articles = overviews[:adminPageSize]
+		data.NextPage = page + 1
+	}
+
+	s.render(w, r, s.adminListTmpl, data)
+}
+
+func (s *Server) handleAdminSpoof(w http.ResponseWriter, r *http.Request) {
+	slug := r.PathValue("slug")
+
+	article, err := s.repo.GetArticle(r.Context(), slug)
+	if err != nil {
+		s.htmlError(w, r, err)
+		return
+	}
+
+	data := struct {
+		adminPage
+		Article       domain.Article
+		Spoof         *domain.Spoof
+		Actions       []adminAction
+		CanRegenerate bool
+	}{
+		adminPage:     adminPageFor(r),
+		Article:       article,
+		CanRegenerate: s.opts.Regenerator != nil,
+	}
+
+	// An article without a spoof is shown anyway, so that it can be regenerated.
+	spoof, err := s.repo.GetSpoof(r.Context(), slug)
+	switch {
+	case err == nil:
+		data.Spoof = &spoof
+		for _, to := range spoof.Status.Transitions() {
+			data.Actions = append(data.Actions, adminAction{To: to, Label: actionLabel(spoof.Status, to)})
+		}
+	case !errors.Is(err, repo.ErrNotFound):
+		s.htmlError(w, r, fmt.Errorf("error getting spoof: %w", err))
+		return
+	}
+
+	s.render(w, r, s.adminSpoofTmpl, data)
+}
+
+func (s *Server) handleAdminEditContent(w http.ResponseWriter, r *http.Request) {
+	slug := r.PathValue("slug")
+	if err := r.ParseForm(); err != nil {
+		s.htmlError(w, r, badRequest("invalid form"))
+		return
+	}
+
+	// Emptying a paragraph removes it.
+	var content []string
+	for _, paragraph := range r.PostForm["paragraph"] {
+		if paragraph = strings.TrimSpace(paragraph); paragraph != "" {
+			content = append(content, paragraph)
+		}
+	}
+	if len(content) == 0 {
+		s.htmlError(w, r, badRequest("a spoof needs at least one paragraph"))
+		return
+	}
+
+	if err := s.repo.EditSpoofContent(r.Context(), slug, content); err != nil {
+		s.htmlError(w, r, err)
+		return
+	}
+	s.redirectToAdminSpoof(w, r, slug)
+}
+
+func (s *Server) handleAdminStatus(w http.ResponseWriter, r *http.Request) {
+	slug := r.PathValue("slug")
+	if err := r.ParseForm(); err != nil {
+		s.htmlError(w, r, badRequest("invalid form"))
+		return
+	}
+
+	from, err := domain.ParseSpoofStatus(r.PostForm.Get("from"))
+	if err != nil {
+		s.htmlError(w, r, badRequest(err.Error()))
+		return
+	}
+	to, err := domain.ParseSpoofStatus(r.PostForm.Get("to"))
+	if err != nil {
+		s.htmlError(w, r, badRequest(err.Error()))
+		return
+	}
+	if !from.CanBecome(to) {
+		s.htmlError(w, r, badRequest(fmt.Sprintf("a %s spoof can't become %s", from, to)))
+		return
+	}
+
+	// The status we showed the editor must still be the current one, or someone else got there first.
+	if err := s.repo.UpdateSpoofStatus(r.Context(), slug, from, to); err != nil {
+		s.htmlError(w, r, err)
+		return
+	}
+	s.redirectToAdminSpoof(w, r, slug)
+}
+
+func (s *Server) handleAdminRegenerate(w http.ResponseWriter, r *http.Request) {
+	slug := r.PathValue("slug")
+	if s.opts.Regenerator == nil {
+		s.htmlError(w, r, &statusError{status: http.StatusNotImplemented, msg: "regenerating spoofs is not enabled"})
+		return
+	}
+
+	// Spoofing takes a while, so the editor waits on the page of the job rather than on this request.
+	job, err := s.opts.Regenerator.Regenerate(r.Context(), slug)
+	if err != nil {
+		s.htmlError(w, r, queueError(fmt.Errorf("error regenerating spoof %s: %w", slug, err)))
+		return
+	}
+	http.Redirect(w, r, "/admin/jobs/"+url.PathEscape(job.ID), http.StatusSeeOther)
+}
+
+func (s *Server) handleAdminDelete(w http.ResponseWriter, r *http.Request) {
+	slug := r.PathValue("slug")
+
+	if err := s.repo.DeleteSpoof(r.Context(), slug); err != nil {
+		s.htmlError(w, r, err)
+		return
+	}
+	// The article is still there, so the editor can regenerate it.
+	s.redirectToAdminSpoof(w, r, slug)
+}
+
+// redirectToAdminSpoof sends the editor back to the spoof they acted on,
+// so that reloading the page doesn't repeat the action.
+func (s *Server) redirectToAdminSpoof(w http.ResponseWriter, r *http.Request, slug string) {
+	http.Redirect(w, r, "/admin/spoofs/"+url.PathEscape(slug), http.StatusSeeOther)
+}
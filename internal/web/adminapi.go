@@ -0,0 +1,121 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/glizzus/trf/internal/domain"
+)
+
+// maxAdminAPILimit is the most articles /api/v1/admin/articles returns at once.
+const maxAdminAPILimit = 200
+
+// adminAPIRoutes serve what the admin area does as JSON, to programs with an API token.
+func (s *Server) adminAPIRoutes() {
+	s.mux.HandleFunc("GET /api/v1/admin/articles", s.requireToken(domain.ScopeSpoofsRead, s.handleAPIListArticles))
+	s.mux.HandleFunc("GET /api/v1/admin/spoofs/{slug}", s.requireToken(domain.ScopeSpoofsRead, s.handleAPIGetSpoof))
+	s.mux.HandleFunc("POST /api/v1/admin/spoofs/{slug}/status", s.requireToken(domain.ScopeSpoofsWrite, s.handleAPISpoofStatus))
+	s.mux.HandleFunc("DELETE /api/v1/admin/spoofs/{slug}", s.requireToken(domain.ScopeSpoofsDelete, s.handleAPIDeleteSpoof))
+}
+
+// queryInt returns the integer in the query parameter name, or def if it isn't set.
+func queryInt(r *http.Request, name string, def, min, max int) (int, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < min || n > max {
+		return 0, badRequest(fmt.Sprintf("%s must be between %d and %d", name, min, max))
+	}
+	return n, nil
+}
+
+func (s *Server) handleAPIListArticles(w http.ResponseWriter, r *http.Request) {
+	var filter domain.ArticleFilter
+	if name := r.URL.Query().Get("status"); name != "" {
+		status, err := domain.ParseSpoofStatus(name)
+		if err != nil {
+			s.jsonError(w, r, badRequest(err.Error()))
+			return
+		}
+		filter.SpoofStatus = status
+	}
+
+	var err error
+	if filter.Limit, err = queryInt(r, "limit", adminPageSize, 1, maxAdminAPILimit); err != nil {
+		s.jsonError(w, r, err)
+		return
+	}
+	if filter.Offset, err = queryInt(r, "offset", 0, 0, math.MaxInt32); err != nil {
+		s.jsonError(w, r, err)
+		return
+	}
+
+	overviews, err := s.repo.ListArticles(r.Context(), filter)
+	if err != nil {
+		s.jsonError(w, r, fmt.Errorf("error listing articles: %w", err))
+		return
+	}
+	// Always encode an array, even when nothing matched.
+	if overviews == nil {
+		overviews = []domain.ArticleOverview{}
+	}
+
+	s.writeJSON(w, overviews)
+}
+
+func (s *Server) handleAPIGetSpoof(w http.ResponseWriter, r *http.Request) {
+	spoof, err := s.repo.GetSpoof(r.Context(), r.PathValue("slug"))
+	if err != nil {
+		s.jsonError(w, r, err)
+		return
+	}
+	s.writeJSON(w, spoof)
+}
+
+func (s *Server) handleAPISpoofStatus(w http.ResponseWriter, r *http.Request) {
+	slug := r.PathValue("slug")
+
+	var body struct {
+		From domain.SpoofStatus `json:"from"`
+		To   domain.SpoofStatus `json:"to"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&body); err != nil {
+		s.jsonError(w, r, badRequest("invalid JSON body"))
+		return
+	}
+	for _, status := range []domain.SpoofStatus{body.From, body.To} {
+		if _, err := domain.ParseSpoofStatus(string(status)); err != nil {
+			s.jsonError(w, r, badRequest(err.Error()))
+			return
+		}
+	}
+	if !body.From.CanBecome(body.To) {
+		s.jsonError(w, r, badRequest(fmt.Sprintf("a %s spoof can't become %s", body.From, body.To)))
+		return
+	}
+
+	if err := s.repo.UpdateSpoofStatus(r.Context(), slug, body.From, body.To); err != nil {
+		s.jsonError(w, r, err)
+		return
+	}
+
+	spoof, err := s.repo.GetSpoof(r.Context(), slug)
+	if err != nil {
+		s.jsonError(w, r, err)
+		return
+	}
+	s.writeJSON(w, spoof)
+}
+
+func (s *Server) handleAPIDeleteSpoof(w http.ResponseWriter, r *http.Request) {
+	if err := s.repo.DeleteSpoof(r.Context(), r.PathValue("slug")); err != nil {
+		s.jsonError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,193 @@
+package web
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/glizzus/trf/internal/auth"
+	"github.com/glizzus/trf/internal/domain"
+)
+
+const (
+	// sessionCookie holds the session token of a signed in user.
+	sessionCookie = "ministry_session"
+	// csrfField is the form field that every form posted to /admin must include.
+	csrfField = "csrf_token"
+)
+
+var (
+	errUnauthorized = &statusError{status: http.StatusUnauthorized, msg: "unauthorized"}
+	errForbidden    = &statusError{status: http.StatusForbidden, msg: "forbidden"}
+	errCSRF         = &statusError{status: http.StatusForbidden, msg: "invalid or missing CSRF token, reload the page and try again"}
+)
+
+// bearerToken returns the token in the Authorization header, if there is one.
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return token, true
+}
+
+// crossSite returns whether the browser says that the request came from another site.
+// Browsers that don't send Sec-Fetch-Site are given the benefit of the doubt.
+func crossSite(r *http.Request) bool {
+	site := r.Header.Get("Sec-Fetch-Site")
+	return site != "" && site != "same-origin" && site != "none"
+}
+
+// requirePage only lets users signed in with scope through to next, which serves the admin area.
+// Users who aren't signed in are sent to the login page. Forms they post must include their CSRF token.
+func (s *Server) requirePage(scope domain.Scope, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var principal auth.Principal
+		var err error
+		if cookie, cookieErr := r.Cookie(sessionCookie); cookieErr == nil {
+			principal, err = s.opts.Auth.Session(r.Context(), cookie.Value)
+		} else {
+			err = auth.ErrInvalidCredentials
+		}
+
+		switch {
+		case errors.Is(err, auth.ErrInvalidCredentials) && r.Method == http.MethodGet:
+			http.Redirect(w, r, "/admin/login?next="+url.QueryEscape(r.URL.RequestURI()), http.StatusSeeOther)
+			return
+		case errors.Is(err, auth.ErrInvalidCredentials):
+			s.htmlError(w, r, errUnauthorized)
+			return
+		case err != nil:
+			s.htmlError(w, r, err)
+			return
+		}
+
+		if r.Method == http.MethodPost {
+			// The session cookie is sent with requests from any site,
+			// so a form on another site could otherwise post to us as the user.
+			token := r.PostFormValue(csrfField)
+			if subtle.ConstantTimeCompare([]byte(token), []byte(principal.Session.CSRFToken)) != 1 {
+				s.htmlError(w, r, errCSRF)
+				return
+			}
+		}
+
+		if !principal.Can(scope) {
+			s.htmlError(w, r, errForbidden)
+			return
+		}
+		next(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+	}
+}
+
+// requireToken only lets requests with an API token that has scope through to next, which serves the JSON API.
+// Browsers never send the token by themselves, so these requests need no CSRF protection.
+func (s *Server) requireToken(scope domain.Scope, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := bearerToken(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="Ministry"`)
+			s.jsonError(w, r, errUnauthorized)
+			return
+		}
+
+		principal, err := s.opts.Auth.APIToken(r.Context(), token)
+		switch {
+		case errors.Is(err, auth.ErrInvalidCredentials):
+			w.Header().Set("WWW-Authenticate", `Bearer realm="Ministry", error="invalid_token"`)
+			s.jsonError(w, r, errUnauthorized)
+			return
+		case err != nil:
+			s.jsonError(w, r, err)
+			return
+		}
+
+		if !principal.Can(scope) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="Ministry", error="insufficient_scope", scope="`+string(scope)+`"`)
+			s.jsonError(w, r, errForbidden)
+			return
+		}
+		next(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+	}
+}
+
+// safeRedirect returns next if it is a path on this site, and /admin otherwise,
+// so that the login page can't be used to send users elsewhere.
+func safeRedirect(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return "/admin"
+	}
+	return next
+}
+
+func (s *Server) handleLoginPage(w http.ResponseWriter, r *http.Request) {
+	s.renderLogin(w, r, http.StatusOK, "")
+}
+
+// renderLogin renders the login form with status, and a message saying what went wrong, if anything did.
+func (s *Server) renderLogin(w http.ResponseWriter, r *http.Request, status int, message string) {
+	data := struct {
+		adminPage
+		Next    string
+		Message string
+	}{
+		Next:    safeRedirect(r.FormValue("next")),
+		Message: message,
+	}
+	s.renderStatus(w, r, status, s.adminLoginTmpl, data)
+}
+
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	// There is no session yet, so there is no CSRF token either.
+	// Without this, another site could sign the user in as someone else.
+	if crossSite(r) {
+		s.htmlError(w, r, &statusError{status: http.StatusForbidden, msg: "cross-site request"})
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		s.htmlError(w, r, badRequest("invalid form"))
+		return
+	}
+
+	token, principal, err := s.opts.Auth.Login(r.Context(), r.PostForm.Get("username"), r.PostForm.Get("password"))
+	switch {
+	case errors.Is(err, auth.ErrInvalidCredentials):
+		s.renderLogin(w, r, http.StatusUnauthorized, "Wrong username or password.")
+		return
+	case err != nil:
+		s.htmlError(w, r, err)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    token,
+		Path:     "/admin",
+		Expires:  principal.Session.Expires,
+		Secure:   s.opts.SecureCookies,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, safeRedirect(r.PostForm.Get("next")), http.StatusSeeOther)
+}
+
+func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	// requirePage made sure the cookie is there.
+	cookie, _ := r.Cookie(sessionCookie)
+	if err := s.opts.Auth.Logout(r.Context(), cookie.Value); err != nil {
+		s.htmlError(w, r, err)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Path:     "/admin",
+		MaxAge:   -1,
+		Secure:   s.opts.SecureCookies,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, "/admin/login", http.StatusSeeOther)
+}
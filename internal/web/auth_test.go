@@ -0,0 +1,203 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glizzus/trf/internal/auth"
+	"github.com/glizzus/trf/internal/domain"
+)
+
+func TestLogin(t *testing.T) {
+	s := newTestServer(t, Options{})
+
+	tests := []struct {
+		name         string
+		site         string
+		password     string
+		next         string
+		wantStatus   int
+		wantLocation string
+	}{
+		{"signs in", "same-origin", testPassword, "/admin/spoofs/some-slug", http.StatusSeeOther, "/admin/spoofs/some-slug"},
+		{"without Sec-Fetch-Site", "", testPassword, "", http.StatusSeeOther, "/admin"},
+		{"wrong password", "same-origin", "wrong horse battery staple", "", http.StatusUnauthorized, ""},
+		{"cross-site", "cross-site", testPassword, "", http.StatusForbidden, ""},
+		{"same-site", "same-site", testPassword, "", http.StatusForbidden, ""},
+		{"protocol-relative next", "same-origin", testPassword, "//evil.example/admin", http.StatusSeeOther, "/admin"},
+		{"backslash next", "same-origin", testPassword, "/\\evil.example", http.StatusSeeOther, "/admin"},
+		{"absolute next", "same-origin", testPassword, "https://evil.example/admin", http.StatusSeeOther, "/admin"},
+		{"relative next", "same-origin", testPassword, "evil.example", http.StatusSeeOther, "/admin"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := postForm("/admin/login", url.Values{
+				"username": {string(domain.RoleEditor)},
+				"password": {tt.password},
+				"next":     {tt.next},
+			})
+			if tt.site != "" {
+				req.Header.Set("Sec-Fetch-Site", tt.site)
+			}
+			res := s.do(req)
+
+			if res.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", res.StatusCode, tt.wantStatus)
+			}
+			if got := res.Header.Get("Location"); got != tt.wantLocation {
+				t.Errorf("Location = %q, want %q", got, tt.wantLocation)
+			}
+
+			var cookie *http.Cookie
+			for _, c := range res.Cookies() {
+				if c.Name == sessionCookie {
+					cookie = c
+				}
+			}
+			if signedIn := tt.wantStatus == http.StatusSeeOther; signedIn != (cookie != nil) {
+				t.Fatalf("got session cookie %v, want one: %v", cookie, signedIn)
+			}
+			if cookie != nil && (!cookie.HttpOnly || cookie.SameSite != http.SameSiteLaxMode || cookie.Path != "/admin") {
+				t.Errorf("session cookie %v is missing its protections", cookie)
+			}
+		})
+	}
+}
+
+func TestSafeRedirect(t *testing.T) {
+	tests := map[string]string{
+		"/admin/spoofs/a?status=draft": "/admin/spoofs/a?status=draft",
+		"":                             "/admin",
+		"//evil.example":               "/admin",
+		"/\\evil.example":              "/admin",
+		"https://evil.example":         "/admin",
+		"javascript:alert(1)":          "/admin",
+	}
+	for next, want := range tests {
+		if got := safeRedirect(next); got != want {
+			t.Errorf("safeRedirect(%q) = %q, want %q", next, got, want)
+		}
+	}
+}
+
+func TestRequirePage(t *testing.T) {
+	s := newTestServer(t, Options{})
+	cookie, csrfToken := s.login(t, domain.RoleEditor)
+	viewerCookie, viewerCSRFToken := s.login(t, domain.RoleViewer)
+
+	// The spoof doesn't exist, so getting through ends in a 404.
+	status := url.Values{"from": {string(domain.SpoofDraft)}, "to": {string(domain.SpoofReview)}}
+	withToken := func(form url.Values, token string) url.Values {
+		with := url.Values{csrfField: {token}}
+		for k, v := range form {
+			with[k] = v
+		}
+		return with
+	}
+
+	tests := []struct {
+		name       string
+		cookie     *http.Cookie
+		form       url.Values
+		wantStatus int
+	}{
+		{"valid CSRF token", cookie, withToken(status, csrfToken), http.StatusNotFound},
+		{"missing CSRF token", cookie, status, http.StatusForbidden},
+		{"empty CSRF token", cookie, withToken(status, ""), http.StatusForbidden},
+		{"wrong CSRF token", cookie, withToken(status, csrfToken+"x"), http.StatusForbidden},
+		{"another user's CSRF token", cookie, withToken(status, viewerCSRFToken), http.StatusForbidden},
+		{"missing scope", viewerCookie, withToken(status, viewerCSRFToken), http.StatusForbidden},
+		{"no session", nil, withToken(status, csrfToken), http.StatusUnauthorized},
+		{"unknown session", &http.Cookie{Name: sessionCookie, Value: "nope"}, withToken(status, csrfToken), http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := postForm("/admin/spoofs/missing/status", tt.form)
+			if tt.cookie != nil {
+				req.AddCookie(tt.cookie)
+			}
+			if res := s.do(req); res.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", res.StatusCode, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestExpiredSession(t *testing.T) {
+	s := newTestServer(t, Options{})
+	// Sessions of this service have expired by the time they are saved.
+	expired := &testServer{Server: s.Server, repo: s.repo, auth: auth.NewService(s.repo, auth.Options{SessionTTL: -time.Minute})}
+	cookie, csrfToken := expired.login(t, domain.RoleEditor)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin?status=draft", nil)
+	req.AddCookie(cookie)
+	res := s.do(req)
+	if res.StatusCode != http.StatusSeeOther {
+		t.Fatalf("GET status = %d, want %d", res.StatusCode, http.StatusSeeOther)
+	}
+	if got, want := res.Header.Get("Location"), "/admin/login?next="+url.QueryEscape("/admin?status=draft"); got != want {
+		t.Errorf("Location = %q, want %q", got, want)
+	}
+
+	req = postForm("/admin/logout", url.Values{csrfField: {csrfToken}})
+	req.AddCookie(cookie)
+	if res := s.do(req); res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("POST status = %d, want %d", res.StatusCode, http.StatusUnauthorized)
+	}
+}
+
+func TestRequireToken(t *testing.T) {
+	s := newTestServer(t, Options{})
+	ctx := context.Background()
+
+	readToken, err := s.auth.CreateAPIToken(ctx, string(domain.RoleEditor), "read", []domain.Scope{domain.ScopeSpoofsRead}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeToken, err := s.auth.CreateAPIToken(ctx, string(domain.RoleEditor), "write", []domain.Scope{domain.ScopeSpoofsWrite}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expiredToken, err := s.auth.CreateAPIToken(ctx, string(domain.RoleEditor), "expired", []domain.Scope{domain.ScopeSpoofsRead}, time.Nanosecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond)
+
+	tests := []struct {
+		name          string
+		method, path  string
+		authorization string
+		wantStatus    int
+		wantChallenge string
+	}{
+		{"read with read scope", http.MethodGet, "/api/v1/admin/articles", "Bearer " + readToken, http.StatusOK, ""},
+		{"scheme is case insensitive", http.MethodGet, "/api/v1/admin/articles", "bearer " + readToken, http.StatusOK, ""},
+		{"read without read scope", http.MethodGet, "/api/v1/admin/articles", "Bearer " + writeToken, http.StatusForbidden, `error="insufficient_scope"`},
+		{"delete without delete scope", http.MethodDelete, "/api/v1/admin/spoofs/missing", "Bearer " + readToken, http.StatusForbidden, `scope="spoofs:delete"`},
+		{"no token", http.MethodGet, "/api/v1/admin/articles", "", http.StatusUnauthorized, `Bearer realm="Ministry"`},
+		{"basic auth", http.MethodGet, "/api/v1/admin/articles", "Basic " + readToken, http.StatusUnauthorized, `Bearer realm="Ministry"`},
+		{"unknown token", http.MethodGet, "/api/v1/admin/articles", "Bearer nope", http.StatusUnauthorized, `error="invalid_token"`},
+		{"expired token", http.MethodGet, "/api/v1/admin/articles", "Bearer " + expiredToken, http.StatusUnauthorized, `error="invalid_token"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			res := s.do(req)
+			if res.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", res.StatusCode, tt.wantStatus)
+			}
+			if got := res.Header.Get("WWW-Authenticate"); !strings.Contains(got, tt.wantChallenge) {
+				t.Errorf("WWW-Authenticate = %q, want it to contain %q", got, tt.wantChallenge)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/glizzus/trf/internal/repo"
+)
+
+// statusError is an error that should be reported to the client with a specific status code.
+// Its message is safe to show to the client.
+type statusError struct {
+	status int
+	msg    string
+}
+
+func (e *statusError) Error() string {
+	return e.msg
+}
+
+// badRequest returns an error that is reported to the client as a 400 with the given message.
+func badRequest(msg string) error {
+	return &statusError{status: http.StatusBadRequest, msg: msg}
+}
+
+var errNotFound = &statusError{status: http.StatusNotFound, msg: "not found"}
+
+// statusFor maps an error to the HTTP status code it should be reported as.
+// This is the single place where errors from the rest of the application get a status code.
+func statusFor(err error) int {
+	var statusErr *statusError
+	switch {
+	case errors.As(err, &statusErr):
+		return statusErr.status
+	case errors.Is(err, repo.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, repo.ErrConflict):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// messageFor returns a message describing err that is safe to show to the client.
+// Internal errors are not described, because they might leak details about the server.
+func messageFor(err error, status int) string {
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		return statusErr.msg
+	}
+	return http.StatusText(status)
+}
+
+// logError logs err if it is a server error. Client errors are expected and not worth logging.
+func logError(r *http.Request, err error, status int) {
+	if status >= http.StatusInternalServerError {
+		slog.ErrorContext(r.Context(), "failed to handle request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"error", err,
+		)
+	}
+}
+
+// htmlError responds to the request with an error page appropriate for err.
+func (s *Server) htmlError(w http.ResponseWriter, r *http.Request, err error) {
+	status := statusFor(err)
+	logError(r, err, status)
+
+	tmpl := s.errorTmpl
+	if status == http.StatusNotFound {
+		tmpl = s.notFoundTmpl
+	}
+
+	data := struct {
+		Status    int
+		Message   string
+		RequestID string
+	}{
+		Status:    status,
+		Message:   messageFor(err, status),
+		RequestID: RequestID(r.Context()),
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := tmpl.Execute(w, data); err != nil {
+		slog.Error("failed to execute error template", "status", status, "error", err)
+	}
+}
+
+// jsonError responds to the request with a JSON error body appropriate for err.
+func (s *Server) jsonError(w http.ResponseWriter, r *http.Request, err error) {
+	status := statusFor(err)
+	logError(r, err, status)
+
+	body := struct {
+		Error     string `json:"error"`
+		RequestID string `json:"request_id"`
+	}{
+		Error:     messageFor(err, status),
+		RequestID: RequestID(r.Context()),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		slog.Error("failed to encode error response", "status", status, "error", err)
+	}
+}
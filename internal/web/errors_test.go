@@ -0,0 +1,150 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glizzus/trf/internal/domain"
+	"github.com/glizzus/trf/internal/repo"
+)
+
+// failingRepo is a repo that fails to list the latest spoofs and to search them, like a repo whose database is down.
+type failingRepo struct {
+	repo.Repo
+}
+
+// errDatabaseDown has details in it that must not reach the client.
+var errDatabaseDown = errors.New("dial tcp db.internal:5432: connection refused")
+
+func (failingRepo) GetLatestSpoofStubs(ctx context.Context) ([]domain.SpoofStub, error) {
+	return nil, errDatabaseDown
+}
+
+func (failingRepo) Search(ctx context.Context, query string, limit int) ([]domain.SearchResult, error) {
+	return nil, errDatabaseDown
+}
+
+func TestErrorStatus(t *testing.T) {
+	s := newTestServer(t, Options{})
+	ctx := context.Background()
+
+	article := domain.Article{
+		Slug:    "drafted",
+		Title:   "Did a cat become mayor?",
+		Date:    time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		Claim:   domain.Claim{Question: "Did a cat become mayor?", Rating: "True"},
+		Content: []string{"The cat won."},
+	}
+	if err := s.repo.SaveArticle(ctx, article); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.repo.SaveSpoof(ctx, domain.Spoof{Article: article, Status: domain.SpoofDraft}); err != nil {
+		t.Fatal(err)
+	}
+	token, err := s.auth.CreateAPIToken(ctx, string(domain.RoleEditor), "test", []domain.Scope{domain.ScopeSpoofsRead, domain.ScopeSpoofsWrite}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	failing := New(failingRepo{repo.NewMemory()}, Options{})
+
+	tests := []struct {
+		name         string
+		handler      http.Handler
+		method, path string
+		body         string
+		wantStatus   int
+		wantMessage  string
+	}{
+		{"missing page", s, http.MethodGet, "/no-such-spoof", "", http.StatusNotFound, "Not Found"},
+		// A draft isn't public, so it isn't there as far as readers know.
+		{"draft", s, http.MethodGet, "/drafted", "", http.StatusNotFound, "Not Found"},
+		{"missing spoof", s, http.MethodGet, "/api/v1/admin/spoofs/no-such-spoof", "", http.StatusNotFound, "Not Found"},
+		{"missing query", s, http.MethodGet, "/api/v1/search", "", http.StatusBadRequest, "missing query"},
+		{"bad limit", s, http.MethodGet, "/api/v1/search?q=cat&limit=0", "", http.StatusBadRequest, "limit must be between"},
+		{"bad JSON", s, http.MethodPost, "/api/v1/admin/spoofs/drafted/status", "{", http.StatusBadRequest, ""},
+		{"status conflict", s, http.MethodPost, "/api/v1/admin/spoofs/drafted/status", `{"from": "review", "to": "published"}`, http.StatusConflict, "Conflict"},
+		{"status of missing spoof", s, http.MethodPost, "/api/v1/admin/spoofs/no-such-spoof/status", `{"from": "draft", "to": "review"}`, http.StatusNotFound, "Not Found"},
+		{"internal error page", failing, http.MethodGet, "/latest", "", http.StatusInternalServerError, "Internal Server Error"},
+		{"internal error JSON", failing, http.MethodGet, "/api/v1/search?q=cat", "", http.StatusInternalServerError, "Internal Server Error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set(requestIDHeader, "test-request-id")
+			if strings.HasPrefix(tt.path, "/api/v1/admin/") {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+			rec := httptest.NewRecorder()
+			tt.handler.ServeHTTP(rec, req)
+			res := rec.Result()
+			body, _ := io.ReadAll(res.Body)
+
+			if res.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", res.StatusCode, tt.wantStatus, body)
+			}
+			if strings.Contains(string(body), "db.internal") {
+				t.Errorf("body %s leaks the error", body)
+			}
+
+			if !strings.HasPrefix(tt.path, "/api/") {
+				if got := res.Header.Get("Content-Type"); !strings.HasPrefix(got, "text/html") {
+					t.Errorf("Content-Type = %q, want HTML", got)
+				}
+				if !strings.Contains(string(body), tt.wantMessage) {
+					t.Errorf("body %s doesn't contain %q", body, tt.wantMessage)
+				}
+				// The request id lets whoever reports a server error point us at its logs.
+				if tt.wantStatus >= http.StatusInternalServerError && !strings.Contains(string(body), "test-request-id") {
+					t.Errorf("body %s doesn't contain the request id", body)
+				}
+				return
+			}
+
+			if got := res.Header.Get("Content-Type"); got != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", got)
+			}
+			var got struct {
+				Error     string `json:"error"`
+				RequestID string `json:"request_id"`
+			}
+			if err := json.Unmarshal(body, &got); err != nil {
+				t.Fatalf("invalid JSON error %s: %v", body, err)
+			}
+			if !strings.Contains(got.Error, tt.wantMessage) {
+				t.Errorf("error = %q, want it to contain %q", got.Error, tt.wantMessage)
+			}
+			if got.RequestID != "test-request-id" {
+				t.Errorf("request_id = %q, want %q", got.RequestID, "test-request-id")
+			}
+		})
+	}
+}
+
+func TestStatusFor(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{errNotFound, http.StatusNotFound},
+		{badRequest("bad"), http.StatusBadRequest},
+		{errForbidden, http.StatusForbidden},
+		{repo.ErrNotFound, http.StatusNotFound},
+		{repo.ErrConflict, http.StatusConflict},
+		// Wrapping doesn't change the status.
+		{errors.Join(errors.New("context"), repo.ErrConflict), http.StatusConflict},
+		{queueError(errors.New("other")), http.StatusInternalServerError},
+		{errDatabaseDown, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		if got := statusFor(tt.err); got != tt.want {
+			t.Errorf("statusFor(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
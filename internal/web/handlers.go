@@ -0,0 +1,133 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/glizzus/trf/internal/domain"
+)
+
+const (
+	searchLimit    = 20
+	maxSearchLimit = 100
+)
+
+// render executes tmpl into a buffer before writing it, so that a failing template
+// results in an error page instead of a half-written response.
+func (s *Server) render(w http.ResponseWriter, r *http.Request, tmpl *template.Template, data any) {
+	s.renderStatus(w, r, http.StatusOK, tmpl, data)
+}
+
+// renderStatus is like render, but responds with status instead of 200.
+func (s *Server) renderStatus(w http.ResponseWriter, r *http.Request, status int, tmpl *template.Template, data any) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		s.htmlError(w, r, fmt.Errorf("error executing template %s: %w", tmpl.Name(), err))
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
+
+// writeJSON responds to the request with v encoded as JSON.
+func (s *Server) writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
+
+func (s *Server) handleLatest(w http.ResponseWriter, r *http.Request) {
+	stubs, err := s.repo.GetLatestSpoofStubs(r.Context())
+	if err != nil {
+		s.htmlError(w, r, fmt.Errorf("error retrieving latest spoof stubs: %w", err))
+		return
+	}
+	slog.DebugContext(r.Context(), "found stubs", "count", len(stubs))
+
+	s.render(w, r, s.latestTmpl, stubs)
+}
+
+func (s *Server) handleSpoof(w http.ResponseWriter, r *http.Request) {
+	slug := r.PathValue("slug")
+	if slug == "" {
+		s.htmlError(w, r, badRequest("missing slug"))
+		return
+	}
+
+	spoof, err := s.repo.GetSpoof(r.Context(), slug)
+	if err != nil {
+		s.htmlError(w, r, err)
+		return
+	}
+	// Spoofs that haven't been published yet don't exist as far as the public knows.
+	if spoof.Status != domain.SpoofPublished {
+		s.htmlError(w, r, errNotFound)
+		return
+	}
+
+	s.render(w, r, s.spoofTmpl, spoof)
+}
+
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+
+	data := struct {
+		Query   string
+		Results []domain.SearchResult
+	}{Query: query}
+
+	// An empty query just renders the search form.
+	if query != "" {
+		results, err := s.repo.Search(r.Context(), query, searchLimit)
+		if err != nil {
+			s.htmlError(w, r, fmt.Errorf("error searching for %q: %w", query, err))
+			return
+		}
+		data.Results = results
+	}
+
+	s.render(w, r, s.searchTmpl, data)
+}
+
+func (s *Server) handleAPISearch(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		s.jsonError(w, r, badRequest("missing query"))
+		return
+	}
+
+	limit := searchLimit
+	if l := r.URL.Query().Get("limit"); l != "" {
+		parsed, err := strconv.Atoi(l)
+		if err != nil || parsed < 1 || parsed > maxSearchLimit {
+			s.jsonError(w, r, badRequest(fmt.Sprintf("limit must be between 1 and %d", maxSearchLimit)))
+			return
+		}
+		limit = parsed
+	}
+
+	results, err := s.repo.Search(r.Context(), query, limit)
+	if err != nil {
+		s.jsonError(w, r, fmt.Errorf("error searching for %q: %w", query, err))
+		return
+	}
+	// Always encode an array, even when nothing matched.
+	if results == nil {
+		results = []domain.SearchResult{}
+	}
+
+	s.writeJSON(w, results)
+}
+
+// handleAPIRatings lists every rating we know, with what it means and how it is displayed.
+func (s *Server) handleAPIRatings(w http.ResponseWriter, r *http.Request) {
+	s.writeJSON(w, domain.RatingInfos())
+}
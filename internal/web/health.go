@@ -0,0 +1,39 @@
+package web
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/glizzus/trf/internal/health"
+)
+
+// handleLivez reports that the process is up. It never touches a dependency,
+// so an outage of, say, Postgres doesn't get us restarted in a loop.
+func (s *Server) handleLivez(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte("OK"))
+}
+
+// handleReadyz reports whether our dependencies are usable, with the outcome of each check.
+// It responds with 503 if any of them isn't.
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	report := health.Report{Status: health.StatusOK, Checks: []health.Result{}}
+	if s.opts.Readiness != nil {
+		report = s.opts.Readiness.Check(r.Context())
+	}
+
+	status := http.StatusOK
+	if !report.OK() {
+		status = http.StatusServiceUnavailable
+		slog.DebugContext(r.Context(), "not ready", "report", report)
+	}
+
+	// Probes shouldn't see a cached answer.
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		slog.Error("failed to encode readiness report", "error", err)
+	}
+}
@@ -0,0 +1,41 @@
+package web
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// probeRoutes are hit every few seconds by health checks, so they are only logged at debug level.
+var probeRoutes = map[string]bool{
+	"GET /livez":   true,
+	"GET /readyz":  true,
+	"GET /healthz": true,
+}
+
+// withLogging logs every request once it has been handled.
+// The request id, and the trace id if there is one, come from the request's context.
+func (s *Server) withLogging(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+
+		route := s.route(r)
+		level := slog.LevelInfo
+		switch {
+		case rec.Status() >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case probeRoutes[route]:
+			level = slog.LevelDebug
+		}
+
+		slog.LogAttrs(r.Context(), level, "handled request",
+			slog.String("method", r.Method),
+			slog.String("route", route),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", rec.Status()),
+			slog.Duration("latency", time.Since(start)),
+		)
+	})
+}
@@ -0,0 +1,132 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/glizzus/trf/internal/domain"
+	"github.com/glizzus/trf/internal/ingest"
+	"github.com/glizzus/trf/internal/scraping"
+)
+
+var errRequestsDisabled = &statusError{status: http.StatusNotImplemented, msg: "spoofing articles on demand is not enabled"}
+
+// requestRoutes let editors spoof articles that ingest never picked up, by URL or slug.
+func (s *Server) requestRoutes() {
+	s.mux.HandleFunc("POST /admin/spoofs", s.requirePage(domain.ScopeSpoofsWrite, s.handleAdminRequest))
+	s.mux.HandleFunc("GET /admin/jobs/{id}", s.requirePage(domain.ScopeSpoofsRead, s.handleAdminJob))
+
+	s.mux.HandleFunc("POST /api/v1/admin/spoofs", s.requireToken(domain.ScopeSpoofsWrite, s.handleAPIRequest))
+	s.mux.HandleFunc("GET /api/v1/admin/jobs/{id}", s.requireToken(domain.ScopeSpoofsRead, s.handleAPIJob))
+}
+
+// request queues the article named by target to be spoofed with the named inversion.
+// An empty inversion name means the usual one.
+func (s *Server) request(r *http.Request, target, inversionName string) (domain.SpoofJob, error) {
+	if s.opts.Requester == nil {
+		return domain.SpoofJob{}, errRequestsDisabled
+	}
+
+	slug, err := scraping.ParseArticleTarget(target)
+	if err != nil {
+		return domain.SpoofJob{}, badRequest(err.Error())
+	}
+	var inversion domain.Inversion
+	if inversionName != "" {
+		if inversion, err = domain.ParseInversion(inversionName); err != nil {
+			return domain.SpoofJob{}, badRequest(err.Error())
+		}
+	}
+
+	job, err := s.opts.Requester.Request(r.Context(), slug, inversion)
+	if err != nil {
+		return domain.SpoofJob{}, queueError(fmt.Errorf("error requesting spoof of %s: %w", slug, err))
+	}
+	return job, nil
+}
+
+// queueError reports a full queue of jobs as a 429, because it passes and the client can try again later.
+// Other errors are left alone.
+func queueError(err error) error {
+	if errors.Is(err, ingest.ErrQueueFull) {
+		return &statusError{status: http.StatusTooManyRequests, msg: ingest.ErrQueueFull.Error()}
+	}
+	return err
+}
+
+// job returns the job with the id in the request's path.
+func (s *Server) job(r *http.Request) (domain.SpoofJob, error) {
+	if s.opts.Requester == nil {
+		return domain.SpoofJob{}, errRequestsDisabled
+	}
+	return s.opts.Requester.Job(r.Context(), r.PathValue("id"))
+}
+
+func (s *Server) handleAdminRequest(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		s.htmlError(w, r, badRequest("invalid form"))
+		return
+	}
+
+	job, err := s.request(r, r.PostForm.Get("target"), r.PostForm.Get("inversion"))
+	if err != nil {
+		s.htmlError(w, r, err)
+		return
+	}
+	http.Redirect(w, r, "/admin/jobs/"+url.PathEscape(job.ID), http.StatusSeeOther)
+}
+
+func (s *Server) handleAdminJob(w http.ResponseWriter, r *http.Request) {
+	job, err := s.job(r)
+	if err != nil {
+		s.htmlError(w, r, err)
+		return
+	}
+
+	data := struct {
+		adminPage
+		Job domain.SpoofJob
+	}{
+		adminPage: adminPageFor(r),
+		Job:       job,
+	}
+	s.render(w, r, s.adminJobTmpl, data)
+}
+
+func (s *Server) handleAPIRequest(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		// Target is the URL or the slug of the article.
+		Target    string `json:"target"`
+		Inversion string `json:"inversion"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&body); err != nil {
+		s.jsonError(w, r, badRequest("invalid JSON body"))
+		return
+	}
+
+	job, err := s.request(r, body.Target, body.Inversion)
+	if err != nil {
+		s.jsonError(w, r, err)
+		return
+	}
+
+	// Articles that already have a spoof are done straight away. The others are polled for.
+	w.Header().Set("Location", "/api/v1/admin/jobs/"+url.PathEscape(job.ID))
+	if !job.Status.Finished() {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+	}
+	s.writeJSON(w, job)
+}
+
+func (s *Server) handleAPIJob(w http.ResponseWriter, r *http.Request) {
+	job, err := s.job(r)
+	if err != nil {
+		s.jsonError(w, r, err)
+		return
+	}
+	s.writeJSON(w, job)
+}
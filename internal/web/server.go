@@ -0,0 +1,121 @@
+// Package web serves the public Totally Real Facts site and its JSON API.
+package web
+
+import (
+	"context"
+	"html/template"
+	"net/http"
+
+	"github.com/glizzus/trf/internal/auth"
+	"github.com/glizzus/trf/internal/domain"
+	"github.com/glizzus/trf/internal/health"
+	"github.com/glizzus/trf/internal/repo"
+)
+
+// Regenerator spoofs articles again in the background, replacing their spoofs.
+type Regenerator interface {
+	// Regenerate queues an article to be spoofed again, and returns the job that tracks it.
+	// It fails when there is no such article, or when too many articles are waiting.
+	Regenerate(ctx context.Context, slug string) (domain.SpoofJob, error)
+}
+
+// Requester spoofs articles on demand, in the background.
+type Requester interface {
+	// Request queues an article to be spoofed, and returns the job that tracks it.
+	// If inversion is nil, the usual one is used. It fails when too many articles are waiting.
+	Request(ctx context.Context, slug string, inversion domain.Inversion) (domain.SpoofJob, error)
+	// Job returns the job with the given id, or an error wrapping repo.ErrNotFound if there is none.
+	Job(ctx context.Context, id string) (domain.SpoofJob, error)
+}
+
+// Options are the optional dependencies of a Server.
+type Options struct {
+	// Readiness checks our dependencies for /readyz. If it is nil, we are always ready.
+	Readiness *health.Checker
+
+	// Regenerator lets editors regenerate spoofs from /admin. If it is nil, they can't.
+	Regenerator Regenerator
+	// Requester lets editors spoof articles by URL or slug. If it is nil, they can't.
+	Requester Requester
+
+	// Auth signs users in to /admin, and checks the tokens of the JSON API that editors use.
+	// If it is nil, neither is served at all.
+	Auth *auth.Service
+	// SecureCookies only sends the session cookie over HTTPS.
+	SecureCookies bool
+}
+
+// Server is the HTTP handler for the site.
+type Server struct {
+	repo repo.Repo
+	mux  *http.ServeMux
+	opts Options
+
+	latestTmpl   *template.Template
+	spoofTmpl    *template.Template
+	searchTmpl   *template.Template
+	notFoundTmpl *template.Template
+	errorTmpl    *template.Template
+
+	adminLoginTmpl *template.Template
+	adminListTmpl  *template.Template
+	adminSpoofTmpl *template.Template
+	adminJobTmpl   *template.Template
+}
+
+// New creates a Server backed by the given repo.
+// Templates are loaded from the "templates" directory relative to the working directory.
+func New(repo repo.Repo, opts Options) *Server {
+	s := &Server{
+		repo: repo,
+		mux:  http.NewServeMux(),
+		opts: opts,
+
+		latestTmpl: template.Must(template.ParseFiles("templates/latest.html")),
+		spoofTmpl:  template.Must(template.ParseFiles("templates/spoof.html")),
+		searchTmpl: template.Must(template.New("search.html").Funcs(template.FuncMap{
+			// Snippets are escaped by the repo, except for the <mark> tags around matches.
+			"snippet": func(s string) template.HTML { return template.HTML(s) },
+		}).ParseFiles("templates/search.html")),
+		notFoundTmpl: template.Must(template.ParseFiles("templates/404.html")),
+		errorTmpl:    template.Must(template.ParseFiles("templates/500.html")),
+
+		adminLoginTmpl: template.Must(template.ParseFiles("templates/admin/layout.html", "templates/admin/login.html")),
+		adminListTmpl:  template.Must(template.ParseFiles("templates/admin/layout.html", "templates/admin/list.html")),
+		adminSpoofTmpl: template.Must(template.ParseFiles("templates/admin/layout.html", "templates/admin/spoof.html")),
+		adminJobTmpl:   template.Must(template.ParseFiles("templates/admin/layout.html", "templates/admin/job.html")),
+	}
+	s.routes()
+	return s
+}
+
+func (s *Server) routes() {
+	s.mux.HandleFunc("GET /livez", s.handleLivez)
+	s.mux.HandleFunc("GET /readyz", s.handleReadyz)
+	// This predates /livez, and is kept for the probes that still use it.
+	s.mux.HandleFunc("GET /healthz", s.handleLivez)
+
+	// These handlers are more specific than "/{slug}", so the mux prefers them.
+	s.mux.HandleFunc("GET /latest", s.handleLatest)
+	s.mux.HandleFunc("GET /search", s.handleSearch)
+	s.mux.HandleFunc("GET /api/v1/search", s.handleAPISearch)
+	s.mux.HandleFunc("GET /api/v1/ratings", s.handleAPIRatings)
+
+	if s.opts.Auth != nil {
+		s.adminRoutes()
+		s.adminAPIRoutes()
+		s.requestRoutes()
+	}
+
+	s.mux.HandleFunc("GET /{slug}", s.handleSpoof)
+
+	// Anything else that isn't matched above gets our 404 page instead of the mux's plain text.
+	s.mux.HandleFunc("GET /", func(w http.ResponseWriter, r *http.Request) {
+		s.htmlError(w, r, errNotFound)
+	})
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	withRequestID(s.withTracing(s.withLogging(s.withMetrics(s.mux)))).ServeHTTP(w, r)
+}
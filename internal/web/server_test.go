@@ -0,0 +1,74 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glizzus/trf/internal/auth"
+	"github.com/glizzus/trf/internal/domain"
+	"github.com/glizzus/trf/internal/repo"
+)
+
+// testPassword is the password of every user that newTestServer creates.
+const testPassword = "correct horse battery staple"
+
+func TestMain(m *testing.M) {
+	// Templates are loaded relative to the working directory, which is the root of the repo when the site runs.
+	if err := os.Chdir("../.."); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// testServer is a Server backed by a memory repo, with a user for each role, named after it.
+type testServer struct {
+	*Server
+	repo *repo.MemoryRepo
+	auth *auth.Service
+}
+
+func newTestServer(t *testing.T, opts Options) *testServer {
+	t.Helper()
+
+	r := repo.NewMemory()
+	if opts.Auth == nil {
+		opts.Auth = auth.NewService(r, auth.Options{SessionTTL: time.Hour})
+	}
+	for _, role := range domain.Roles() {
+		if _, err := opts.Auth.CreateUser(context.Background(), string(role), testPassword, role); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &testServer{Server: New(r, opts), repo: r, auth: opts.Auth}
+}
+
+// login signs in as the user with the given role, and returns the session cookie and the CSRF token.
+func (s *testServer) login(t *testing.T, role domain.Role) (*http.Cookie, string) {
+	t.Helper()
+
+	token, principal, err := s.auth.Login(context.Background(), string(role), testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &http.Cookie{Name: sessionCookie, Value: token}, principal.Session.CSRFToken
+}
+
+// do serves req, and returns the response.
+func (s *testServer) do(req *http.Request) *http.Response {
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	return rec.Result()
+}
+
+// postForm returns a request that posts form to path.
+func postForm(path string, form url.Values) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req
+}
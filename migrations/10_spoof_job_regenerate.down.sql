@@ -0,0 +1 @@
+ALTER TABLE spoof_jobs DROP COLUMN IF EXISTS regenerate;
@@ -0,0 +1,3 @@
+ALTER TABLE spoof_jobs ADD COLUMN regenerate BOOLEAN NOT NULL DEFAULT FALSE;
+
+COMMENT ON COLUMN spoof_jobs.regenerate IS 'Whether the job replaces the spoof of an article we have, rather than spoofing a new one';
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS spoofs_search_idx;
+DROP TRIGGER IF EXISTS spoofs_search_update ON spoofs;
+DROP FUNCTION IF EXISTS spoofs_search_update;
+ALTER TABLE spoofs DROP COLUMN IF EXISTS search;
+
+DROP INDEX IF EXISTS articles_search_idx;
+DROP TRIGGER IF EXISTS articles_search_update ON articles;
+DROP FUNCTION IF EXISTS articles_search_update;
+ALTER TABLE articles DROP COLUMN IF EXISTS search;
@@ -0,0 +1,49 @@
+-- Full-text search over articles and spoofs.
+--
+-- We maintain the search vectors with triggers instead of generated columns
+-- because array_to_string is not immutable, and generated columns require it.
+
+ALTER TABLE articles ADD COLUMN search TSVECTOR;
+
+COMMENT ON COLUMN articles.search IS 'The full-text search vector of the article.
+The title is weighted highest, followed by the subtitle and question, then the content';
+
+CREATE FUNCTION articles_search_update() RETURNS TRIGGER AS $$
+BEGIN
+    NEW.search :=
+        setweight(to_tsvector('english', coalesce(NEW.title, '')), 'A') ||
+        setweight(to_tsvector('english', coalesce(NEW.subtitle, '')), 'B') ||
+        setweight(to_tsvector('english', coalesce(NEW.question, '')), 'B') ||
+        setweight(to_tsvector('english', coalesce(array_to_string(NEW.content, ' '), '')), 'C');
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER articles_search_update
+    BEFORE INSERT OR UPDATE OF title, subtitle, question, content ON articles
+    FOR EACH ROW EXECUTE FUNCTION articles_search_update();
+
+-- Touch every row so the trigger backfills the existing articles
+UPDATE articles SET title = title;
+
+CREATE INDEX articles_search_idx ON articles USING GIN (search);
+
+ALTER TABLE spoofs ADD COLUMN search TSVECTOR;
+
+COMMENT ON COLUMN spoofs.search IS 'The full-text search vector of the spoofed content';
+
+-- spoofs.content is TEXT holding an array literal, so we cast it before building the vector
+CREATE FUNCTION spoofs_search_update() RETURNS TRIGGER AS $$
+BEGIN
+    NEW.search := setweight(to_tsvector('english', coalesce(array_to_string(NEW.content::TEXT[], ' '), '')), 'C');
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER spoofs_search_update
+    BEFORE INSERT OR UPDATE OF content ON spoofs
+    FOR EACH ROW EXECUTE FUNCTION spoofs_search_update();
+
+UPDATE spoofs SET content = content;
+
+CREATE INDEX spoofs_search_idx ON spoofs USING GIN (search);
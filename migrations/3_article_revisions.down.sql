@@ -0,0 +1,8 @@
+ALTER TABLE spoofs DROP COLUMN IF EXISTS stale;
+
+DROP TABLE IF EXISTS article_revisions;
+
+ALTER TABLE articles
+    DROP COLUMN IF EXISTS updated_at,
+    DROP COLUMN IF EXISTS checked_at,
+    DROP COLUMN IF EXISTS content_hash;
@@ -0,0 +1,37 @@
+ALTER TABLE articles
+    ADD COLUMN content_hash TEXT,
+    ADD COLUMN checked_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    ADD COLUMN updated_at TIMESTAMP;
+
+COMMENT ON COLUMN articles.content_hash IS 'A hash of the scraped fields of the article, used to detect when Snopes revises it.
+This is NULL for articles scraped before we started hashing them';
+COMMENT ON COLUMN articles.checked_at IS 'When we last scraped the article to check for revisions';
+COMMENT ON COLUMN articles.updated_at IS 'When we last saw Snopes revise the article, if ever';
+
+-- Each row is a version of an article that has since been replaced by a newer one.
+-- The current version always lives in articles.
+CREATE TABLE article_revisions (
+    id SERIAL PRIMARY KEY,
+    slug TEXT NOT NULL REFERENCES articles (slug) ON DELETE CASCADE,
+    title TEXT NOT NULL,
+    subtitle TEXT NOT NULL,
+    date DATE NOT NULL,
+
+    question TEXT NOT NULL,
+    rating RATING NOT NULL,
+    context TEXT,
+
+    content TEXT[] NOT NULL,
+    content_hash TEXT,
+
+    superseded_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX article_revisions_slug_idx ON article_revisions (slug);
+
+COMMENT ON TABLE article_revisions IS 'Previous versions of articles that Snopes has since revised';
+
+ALTER TABLE spoofs ADD COLUMN stale BOOLEAN NOT NULL DEFAULT FALSE;
+
+COMMENT ON COLUMN spoofs.stale IS 'Whether the original article changed its rating after we spoofed it,
+so the spoof may contradict a rating that Snopes no longer holds';
@@ -0,0 +1,28 @@
+ALTER TABLE spoofs
+    DROP COLUMN IF EXISTS whats_true,
+    DROP COLUMN IF EXISTS whats_false;
+
+ALTER TABLE article_revisions
+    DROP COLUMN IF EXISTS authors,
+    DROP COLUMN IF EXISTS updated_date,
+    DROP COLUMN IF EXISTS category,
+    DROP COLUMN IF EXISTS tags,
+    DROP COLUMN IF EXISTS image_url,
+    DROP COLUMN IF EXISTS image_alt,
+    DROP COLUMN IF EXISTS sources,
+    DROP COLUMN IF EXISTS whats_true,
+    DROP COLUMN IF EXISTS whats_false;
+
+DROP INDEX IF EXISTS articles_category_idx;
+DROP INDEX IF EXISTS articles_tags_idx;
+
+ALTER TABLE articles
+    DROP COLUMN IF EXISTS authors,
+    DROP COLUMN IF EXISTS updated_date,
+    DROP COLUMN IF EXISTS category,
+    DROP COLUMN IF EXISTS tags,
+    DROP COLUMN IF EXISTS image_url,
+    DROP COLUMN IF EXISTS image_alt,
+    DROP COLUMN IF EXISTS sources,
+    DROP COLUMN IF EXISTS whats_true,
+    DROP COLUMN IF EXISTS whats_false;
@@ -0,0 +1,37 @@
+-- Columns that are lists default to empty, so that articles scraped before we
+-- collected them don't need special handling.
+ALTER TABLE articles
+    ADD COLUMN authors TEXT[] NOT NULL DEFAULT '{}',
+    ADD COLUMN updated_date DATE,
+    ADD COLUMN category TEXT,
+    ADD COLUMN tags TEXT[] NOT NULL DEFAULT '{}',
+    ADD COLUMN image_url TEXT,
+    ADD COLUMN image_alt TEXT,
+    ADD COLUMN sources JSONB NOT NULL DEFAULT '[]',
+    ADD COLUMN whats_true TEXT[] NOT NULL DEFAULT '{}',
+    ADD COLUMN whats_false TEXT[] NOT NULL DEFAULT '{}';
+
+COMMENT ON COLUMN articles.updated_date IS 'The date Snopes says it last updated the article, if it did.
+This is not the same as updated_at, which is when we noticed a revision';
+COMMENT ON COLUMN articles.sources IS 'The citations of the article, as a JSON array of {"text", "url"} objects';
+COMMENT ON COLUMN articles.whats_true IS 'The "What''s True" breakdown of a claim that is partly true';
+COMMENT ON COLUMN articles.whats_false IS 'The "What''s False" breakdown of a claim that is partly true';
+
+CREATE INDEX articles_tags_idx ON articles USING GIN (tags);
+CREATE INDEX articles_category_idx ON articles (category);
+
+ALTER TABLE article_revisions
+    ADD COLUMN authors TEXT[] NOT NULL DEFAULT '{}',
+    ADD COLUMN updated_date DATE,
+    ADD COLUMN category TEXT,
+    ADD COLUMN tags TEXT[] NOT NULL DEFAULT '{}',
+    ADD COLUMN image_url TEXT,
+    ADD COLUMN image_alt TEXT,
+    ADD COLUMN sources JSONB NOT NULL DEFAULT '[]',
+    ADD COLUMN whats_true TEXT[] NOT NULL DEFAULT '{}',
+    ADD COLUMN whats_false TEXT[] NOT NULL DEFAULT '{}';
+
+-- A spoof swaps what's true and what's false, so it keeps its own copy.
+ALTER TABLE spoofs
+    ADD COLUMN whats_true TEXT[] NOT NULL DEFAULT '{}',
+    ADD COLUMN whats_false TEXT[] NOT NULL DEFAULT '{}';
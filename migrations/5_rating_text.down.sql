@@ -0,0 +1,17 @@
+-- This fails if any article has a rating that isn't in the enum.
+-- Map those to a known rating before migrating down.
+ALTER TABLE articles DROP CONSTRAINT IF EXISTS rating_not_empty;
+
+CREATE TYPE rating AS ENUM (
+    'True', 'Mostly True', 'Mostly False',
+    'False', 'Legit', 'Fake', 'Correct Attribution',
+    'Misattributed', 'Unproven', 'Unfounded',
+    'Outdated', 'Miscaptioned', 'Legend',
+    'Scam', 'Labeled Satire', 'Originated as Satire',
+    'Research in Progress', 'Mixture',
+    'Lost Legend', 'Recall'
+);
+
+ALTER TABLE articles ALTER COLUMN rating TYPE RATING USING rating::RATING;
+ALTER TABLE article_revisions ALTER COLUMN rating TYPE RATING USING rating::RATING;
+ALTER TABLE spoofs ALTER COLUMN rating TYPE RATING USING rating::RATING;
@@ -0,0 +1,14 @@
+-- Snopes adds ratings from time to time, and a closed enum meant a new one
+-- stopped us from saving the article at all. Ratings are now stored as Snopes
+-- wrote them, and the application decides what each one means.
+ALTER TABLE articles ALTER COLUMN rating TYPE TEXT USING rating::TEXT;
+ALTER TABLE article_revisions ALTER COLUMN rating TYPE TEXT USING rating::TEXT;
+ALTER TABLE spoofs ALTER COLUMN rating TYPE TEXT USING rating::TEXT;
+
+DROP TYPE rating;
+
+ALTER TABLE articles ADD CONSTRAINT rating_not_empty CHECK (rating <> '');
+
+COMMENT ON COLUMN articles.rating IS 'The rating of the article, exactly as Snopes wrote it.
+It may be one we have no mapping for yet.
+A list of the usual ones can be found here: https://www.snopes.com/fact-check-ratings/';
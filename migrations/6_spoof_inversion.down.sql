@@ -0,0 +1 @@
+ALTER TABLE spoofs DROP COLUMN IF EXISTS inversion;
@@ -0,0 +1,4 @@
+ALTER TABLE spoofs ADD COLUMN inversion TEXT;
+
+COMMENT ON COLUMN spoofs.inversion IS 'The name of the strategy that chose the rating of the spoof, such as "mirror".
+This is NULL for spoofs made before we recorded it';
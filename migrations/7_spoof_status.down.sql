@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS spoofs_status_idx;
+
+ALTER TABLE spoofs
+    DROP COLUMN IF EXISTS status,
+    DROP COLUMN IF EXISTS status_changed_at,
+    DROP COLUMN IF EXISTS edited_at;
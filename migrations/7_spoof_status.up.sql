@@ -0,0 +1,17 @@
+-- Spoofs are reviewed by an editor before the public can see them.
+-- Spoofs that were already live stay published.
+ALTER TABLE spoofs
+    ADD COLUMN status TEXT NOT NULL DEFAULT 'published'
+        CONSTRAINT spoof_status_valid CHECK (status IN ('draft', 'review', 'published')),
+    ADD COLUMN status_changed_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    ADD COLUMN edited_at TIMESTAMP;
+
+-- New spoofs get their status from the application, so there is no default to fall back on.
+ALTER TABLE spoofs ALTER COLUMN status DROP DEFAULT;
+
+CREATE INDEX spoofs_status_idx ON spoofs (status);
+
+COMMENT ON COLUMN spoofs.status IS 'Where the spoof is in the review workflow: draft, then review, then published.
+Only published spoofs are shown to the public';
+COMMENT ON COLUMN spoofs.status_changed_at IS 'When the status of the spoof last changed';
+COMMENT ON COLUMN spoofs.edited_at IS 'When an editor last changed the content of the spoof by hand, if ever';
@@ -0,0 +1,46 @@
+-- Users sign in to the admin area with a password, or use the JSON API with a token.
+CREATE TABLE users (
+    id SERIAL PRIMARY KEY,
+    username TEXT NOT NULL UNIQUE CONSTRAINT username_not_empty CHECK (username <> ''),
+    password_hash TEXT NOT NULL,
+    role TEXT NOT NULL CONSTRAINT user_role_valid CHECK (role IN ('viewer', 'editor', 'admin')),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+COMMENT ON TABLE users IS 'People who can review spoofs, and what they are allowed to do';
+COMMENT ON COLUMN users.password_hash IS 'The bcrypt hash of the password of the user';
+COMMENT ON COLUMN users.role IS 'viewer can read, editor can also edit and publish, admin can also delete';
+
+CREATE TABLE sessions (
+    token_hash TEXT PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    csrf_token TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX sessions_user_id_idx ON sessions (user_id);
+CREATE INDEX sessions_expires_at_idx ON sessions (expires_at);
+
+COMMENT ON TABLE sessions IS 'Users signed in to the admin area';
+COMMENT ON COLUMN sessions.token_hash IS 'The SHA-256 hash of the session cookie.
+The cookie itself is never stored, so a leaked database can not be used to sign in';
+COMMENT ON COLUMN sessions.csrf_token IS 'The token that forms posted during the session must include';
+
+CREATE TABLE api_tokens (
+    id SERIAL PRIMARY KEY,
+    token_hash TEXT NOT NULL UNIQUE,
+    user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    scopes TEXT[] NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP
+);
+
+CREATE INDEX api_tokens_user_id_idx ON api_tokens (user_id);
+
+COMMENT ON TABLE api_tokens IS 'Tokens that let programs use the JSON API on behalf of a user';
+COMMENT ON COLUMN api_tokens.token_hash IS 'The SHA-256 hash of the token, which is only shown once when it is created';
+COMMENT ON COLUMN api_tokens.scopes IS 'What the token may do. It never gets more than the role of its user allows';
+COMMENT ON COLUMN api_tokens.expires_at IS 'When the token stops working, or NULL if it never does';
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS spoof_jobs;
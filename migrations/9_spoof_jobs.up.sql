@@ -0,0 +1,18 @@
+CREATE TABLE spoof_jobs (
+    id TEXT PRIMARY KEY,
+    slug TEXT NOT NULL,
+    inversion TEXT,
+    status TEXT NOT NULL CONSTRAINT spoof_job_status_valid CHECK (status IN ('queued', 'running', 'done', 'failed')),
+    existed BOOLEAN NOT NULL DEFAULT FALSE,
+    error TEXT,
+    created_at TIMESTAMP NOT NULL,
+    finished_at TIMESTAMP
+);
+
+CREATE INDEX spoof_jobs_created_at_idx ON spoof_jobs (created_at);
+
+COMMENT ON TABLE spoof_jobs IS 'Spoofs requested on demand. They are kept here rather than in memory,
+so that every instance can tell how a job is going, whichever one it was requested from';
+COMMENT ON COLUMN spoof_jobs.inversion IS 'The name of the inversion that was asked for, or NULL if the usual one applies';
+COMMENT ON COLUMN spoof_jobs.existed IS 'Whether the article already had a spoof, in which case nothing was done';
+COMMENT ON COLUMN spoof_jobs.error IS 'Why the job failed, if it did';
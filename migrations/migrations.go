@@ -0,0 +1,65 @@
+// Package migrations embeds the SQL migrations, so the binary knows which schema it expects.
+//
+// The PostgreSQL migrations are applied by the migrate tool, which ignores this file and the sqlite directory.
+// The SQLite migrations are applied by the application itself when it opens the database.
+package migrations
+
+import (
+	"embed"
+	"io/fs"
+	"strconv"
+	"strings"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+//go:embed sqlite/*.sql
+var sqliteFiles embed.FS
+
+// FS returns the migration files, named like "1_init_schema.up.sql".
+func FS() fs.FS {
+	return files
+}
+
+// Latest returns the version of the newest migration, which is the schema version the code expects.
+func Latest() uint {
+	return latest(files)
+}
+
+// SQLite returns the migration files of the SQLite schema, named like the PostgreSQL ones.
+// There are no down migrations, because the application only ever migrates up.
+func SQLite() fs.FS {
+	sub, _ := fs.Sub(sqliteFiles, "sqlite")
+	return sub
+}
+
+// LatestSQLite returns the version of the newest SQLite migration.
+func LatestSQLite() uint {
+	return latest(SQLite())
+}
+
+// Version returns the version of the migration file with the given name, and whether it is a migration at all.
+func Version(name string) (uint, bool) {
+	prefix, _, ok := strings.Cut(name, "_")
+	if !ok {
+		return 0, false
+	}
+	version, err := strconv.ParseUint(prefix, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return uint(version), true
+}
+
+func latest(fsys fs.FS) uint {
+	entries, _ := fs.ReadDir(fsys, ".")
+
+	var latest uint
+	for _, entry := range entries {
+		if version, ok := Version(entry.Name()); ok && version > latest {
+			latest = version
+		}
+	}
+	return latest
+}
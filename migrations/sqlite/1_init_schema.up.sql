@@ -0,0 +1,184 @@
+-- The schema of the PostgreSQL migrations up to 8_users, for running without a database server.
+--
+-- SQLite has no arrays, so lists are JSON arrays. It has no time types either, so times are
+-- text in UTC, formatted so that they sort like the times they hold, and dates are YYYY-MM-DD.
+
+CREATE TABLE articles (
+    id INTEGER PRIMARY KEY,
+    -- The slug of the article, like "biden-banned-tiktok-in-us". It is used as the primary identifier of an article.
+    slug TEXT NOT NULL UNIQUE CHECK (slug <> ''),
+    title TEXT NOT NULL,
+    subtitle TEXT NOT NULL,
+    date TEXT NOT NULL,
+
+    question TEXT NOT NULL,
+    rating TEXT NOT NULL CHECK (rating <> ''),
+    context TEXT,
+
+    content TEXT NOT NULL,
+    -- A hash of the scraped fields of the article, used to detect when Snopes revises it.
+    content_hash TEXT,
+
+    authors TEXT NOT NULL DEFAULT '[]',
+    -- When Snopes says it last updated the article. This is not the same as updated_at.
+    updated_date TEXT,
+    category TEXT,
+    tags TEXT NOT NULL DEFAULT '[]',
+    image_url TEXT,
+    image_alt TEXT,
+    sources TEXT NOT NULL DEFAULT '[]',
+    whats_true TEXT NOT NULL DEFAULT '[]',
+    whats_false TEXT NOT NULL DEFAULT '[]',
+
+    created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f000000', 'now')),
+    -- When we last scraped the article to check for revisions.
+    checked_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f000000', 'now')),
+    -- When we last saw Snopes revise the article, if ever.
+    updated_at TEXT
+);
+
+CREATE INDEX articles_date_idx ON articles (date);
+
+-- Each row is a version of an article that has since been replaced by a newer one.
+-- The current version always lives in articles.
+CREATE TABLE article_revisions (
+    id INTEGER PRIMARY KEY,
+    slug TEXT NOT NULL REFERENCES articles (slug) ON DELETE CASCADE,
+    title TEXT NOT NULL,
+    subtitle TEXT NOT NULL,
+    date TEXT NOT NULL,
+
+    question TEXT NOT NULL,
+    rating TEXT NOT NULL,
+    context TEXT,
+
+    content TEXT NOT NULL,
+    content_hash TEXT,
+
+    authors TEXT NOT NULL DEFAULT '[]',
+    updated_date TEXT,
+    category TEXT,
+    tags TEXT NOT NULL DEFAULT '[]',
+    image_url TEXT,
+    image_alt TEXT,
+    sources TEXT NOT NULL DEFAULT '[]',
+    whats_true TEXT NOT NULL DEFAULT '[]',
+    whats_false TEXT NOT NULL DEFAULT '[]',
+
+    superseded_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f000000', 'now'))
+);
+
+CREATE INDEX article_revisions_slug_idx ON article_revisions (slug);
+
+CREATE TABLE spoofs (
+    id INTEGER PRIMARY KEY,
+    -- The slug of the article that this spoof is based on.
+    slug TEXT NOT NULL UNIQUE REFERENCES articles (slug),
+    rating TEXT NOT NULL,
+    content TEXT NOT NULL,
+    -- A spoof swaps what's true and what's false, so it keeps its own copy.
+    whats_true TEXT NOT NULL DEFAULT '[]',
+    whats_false TEXT NOT NULL DEFAULT '[]',
+    -- The name of the strategy that chose the rating of the spoof, such as "mirror".
+    inversion TEXT,
+
+    -- Whether the original article changed its rating after we spoofed it.
+    stale INTEGER NOT NULL DEFAULT 0,
+
+    -- Where the spoof is in the review workflow. Only published spoofs are shown to the public.
+    status TEXT NOT NULL CHECK (status IN ('draft', 'review', 'published')),
+    status_changed_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f000000', 'now')),
+    -- When an editor last changed the content of the spoof by hand, if ever.
+    edited_at TEXT
+);
+
+CREATE INDEX spoofs_status_idx ON spoofs (status);
+
+-- Full-text search over published spoofs and their articles, kept up to date by the triggers below.
+-- spoof_content is escaped for HTML, so that the only markup in a snippet is the <mark> tags around matches.
+CREATE VIRTUAL TABLE spoofs_search USING fts5 (
+    slug UNINDEXED,
+    title,
+    subtitle,
+    question,
+    article_content,
+    spoof_content,
+    tokenize = 'porter unicode61'
+);
+
+CREATE TRIGGER spoofs_search_insert AFTER INSERT ON spoofs
+BEGIN
+    INSERT INTO spoofs_search (slug, title, subtitle, question, article_content, spoof_content)
+    SELECT
+        articles.slug,
+        articles.title,
+        articles.subtitle,
+        articles.question,
+        (SELECT group_concat(value, ' ') FROM json_each(articles.content)),
+        replace(replace(replace(
+            (SELECT group_concat(value, ' ') FROM json_each(NEW.content)),
+            '&', '&amp;'), '<', '&lt;'), '>', '&gt;')
+    FROM articles
+    WHERE articles.slug = NEW.slug;
+END;
+
+CREATE TRIGGER spoofs_search_update AFTER UPDATE OF content ON spoofs
+BEGIN
+    UPDATE spoofs_search
+    SET spoof_content = replace(replace(replace(
+        (SELECT group_concat(value, ' ') FROM json_each(NEW.content)),
+        '&', '&amp;'), '<', '&lt;'), '>', '&gt;')
+    WHERE slug = NEW.slug;
+END;
+
+CREATE TRIGGER spoofs_search_delete AFTER DELETE ON spoofs
+BEGIN
+    DELETE FROM spoofs_search WHERE slug = OLD.slug;
+END;
+
+CREATE TRIGGER articles_search_update AFTER UPDATE OF title, subtitle, question, content ON articles
+BEGIN
+    UPDATE spoofs_search
+    SET
+        title = NEW.title,
+        subtitle = NEW.subtitle,
+        question = NEW.question,
+        article_content = (SELECT group_concat(value, ' ') FROM json_each(NEW.content))
+    WHERE slug = NEW.slug;
+END;
+
+CREATE TABLE users (
+    id INTEGER PRIMARY KEY,
+    username TEXT NOT NULL UNIQUE CHECK (username <> ''),
+    -- The bcrypt hash of the user's password.
+    password_hash TEXT NOT NULL,
+    role TEXT NOT NULL CHECK (role IN ('viewer', 'editor', 'admin')),
+    created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f000000', 'now'))
+);
+
+-- Users signed in to the admin area. Only the hash of each session token is kept.
+CREATE TABLE sessions (
+    token_hash TEXT PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    csrf_token TEXT NOT NULL,
+    created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f000000', 'now')),
+    expires_at TEXT NOT NULL
+);
+
+CREATE INDEX sessions_user_id_idx ON sessions (user_id);
+CREATE INDEX sessions_expires_at_idx ON sessions (expires_at);
+
+-- Tokens that let programs use the JSON API on behalf of a user. Only the hash of each token is kept.
+CREATE TABLE api_tokens (
+    id INTEGER PRIMARY KEY,
+    token_hash TEXT NOT NULL UNIQUE,
+    user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    scopes TEXT NOT NULL DEFAULT '[]',
+    created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f000000', 'now')),
+    -- When the token stops working, or NULL if it never does.
+    expires_at TEXT,
+    last_used_at TEXT
+);
+
+CREATE INDEX api_tokens_user_id_idx ON api_tokens (user_id);
@@ -0,0 +1,15 @@
+-- Spoofs requested on demand, and how they are going.
+CREATE TABLE spoof_jobs (
+    id TEXT PRIMARY KEY,
+    slug TEXT NOT NULL,
+    -- The name of the inversion that was asked for, or NULL if the usual one applies.
+    inversion TEXT,
+    status TEXT NOT NULL CHECK (status IN ('queued', 'running', 'done', 'failed')),
+    -- Whether the article already had a spoof, in which case nothing was done.
+    existed INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    created_at TEXT NOT NULL,
+    finished_at TEXT
+);
+
+CREATE INDEX spoof_jobs_created_at_idx ON spoof_jobs (created_at);
@@ -0,0 +1,2 @@
+-- Whether the job replaces the spoof of an article we have, rather than spoofing a new one.
+ALTER TABLE spoof_jobs ADD COLUMN regenerate INTEGER NOT NULL DEFAULT 0;